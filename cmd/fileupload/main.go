@@ -0,0 +1,161 @@
+// Command fileupload is a command-line client of the file upload API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pavva91/file-upload/pkg/client"
+)
+
+const usage = `Usage: fileupload [-config path] [-profile name] <command> [flags] [args]
+
+Commands:
+  upload    [-bucket b] [-prefix p] [-concurrency n] [-resume] path...   upload files or whole directories
+  download  [-bucket b] [-o path] name                                  download an object, resuming a partial file
+  list      [-bucket b] [-prefix p] [-json]                             list objects
+  delete    [-bucket b] name...                                         delete objects
+  presign   [-bucket b] [-expires 1h] name                              print a presigned download link
+  share     [-bucket b] [-expires 24h] [-password p] [-max-downloads n] name   print a share link served by the API
+`
+
+func main() {
+	flags := flag.NewFlagSet("fileupload", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configPath := flags.String("config", defaultConfigPath(), "CLI config file with the profiles")
+	profileName := flags.String("profile", "", "profile of the config file to use")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	profile, err := loadProfile(*configPath, *profileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c := client.New(profile.Endpoint, profile.ApiKey)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "upload":
+		err = runUpload(ctx, c, profile, args)
+	case "download":
+		err = runDownload(ctx, c, profile, args)
+	case "list":
+		err = runList(ctx, c, profile, args)
+	case "delete":
+		err = runDelete(ctx, c, profile, args)
+	case "presign":
+		err = runPresign(ctx, c, profile, args)
+	case "share":
+		err = runShare(ctx, c, profile, args)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runList(ctx context.Context, c *client.Client, profile Profile, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	bucket := flags.String("bucket", profile.Bucket, "bucket name")
+	prefix := flags.String("prefix", "", "only list objects starting with prefix")
+	asJSON := flags.Bool("json", false, "print the objects as JSON")
+	flags.Parse(args)
+
+	files, err := c.ListFiles(ctx, *bucket, *prefix)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(files)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, file := range files {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", file.LastModified.Local().Format(time.DateTime), file.Size, file.Name)
+	}
+	return w.Flush()
+}
+
+func runDelete(ctx context.Context, c *client.Client, profile Profile, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	bucket := flags.String("bucket", profile.Bucket, "bucket name")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("delete: missing object name")
+	}
+
+	for _, name := range flags.Args() {
+		err := c.DeleteFile(ctx, *bucket, name)
+		if err != nil {
+			return fmt.Errorf("delete %s: %w", name, err)
+		}
+		fmt.Println("deleted", name)
+	}
+	return nil
+}
+
+func runPresign(ctx context.Context, c *client.Client, profile Profile, args []string) error {
+	flags := flag.NewFlagSet("presign", flag.ExitOnError)
+	bucket := flags.String("bucket", profile.Bucket, "bucket name")
+	expires := flags.Duration("expires", time.Hour, "validity of the link (max 168h)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("presign: expected one object name")
+	}
+
+	presigned, err := c.PresignFile(ctx, *bucket, flags.Arg(0), *expires)
+	if err != nil {
+		return err
+	}
+	fmt.Println(presigned.URL)
+	fmt.Fprintln(os.Stderr, "expires at", presigned.ExpiresAt.Local().Format(time.DateTime))
+	return nil
+}
+
+func runShare(ctx context.Context, c *client.Client, profile Profile, args []string) error {
+	flags := flag.NewFlagSet("share", flag.ExitOnError)
+	bucket := flags.String("bucket", profile.Bucket, "bucket name")
+	expires := flags.Duration("expires", 24*time.Hour, "validity of the link (max shares.max-expires of the server)")
+	password := flags.String("password", "", "password asked by the link")
+	maxDownloads := flags.Int("max-downloads", 0, "downloads allowed, 0 for no limit")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("share: expected one object name")
+	}
+
+	share, err := c.CreateShare(ctx, *bucket, flags.Arg(0), client.CreateShareRequest{
+		ExpiresIn:    int(expires.Seconds()),
+		Password:     *password,
+		MaxDownloads: *maxDownloads,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(share.URL)
+	fmt.Fprintln(os.Stderr, "share", share.ID, "expires at", share.ExpiresAt.Local().Format(time.DateTime))
+	return nil
+}
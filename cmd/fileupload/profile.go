@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Profile    Server the CLI talks to, selected with -profile
+type Profile struct {
+	Endpoint string `yaml:"endpoint"`
+	ApiKey   string `yaml:"api-key"`
+	Bucket   string `yaml:"bucket"`
+}
+
+// Model that links to the CLI config file, e.g.:
+//
+//	default-profile: local
+//	profiles:
+//	  local:
+//	    endpoint: "http://localhost:8080/api/v1"
+//	    api-key: "your-api-key"
+//	    bucket: "devbucket"
+type CliConfig struct {
+	DefaultProfile string             `yaml:"default-profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// defaultConfigPath function    $FILEUPLOAD_CONFIG or ~/.config/fileupload/config.yml
+func defaultConfigPath() string {
+	if path := os.Getenv("FILEUPLOAD_CONFIG"); path != "" {
+		return path
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "fileupload.yml"
+	}
+	return filepath.Join(configDir, "fileupload", "config.yml")
+}
+
+// loadProfile function    Read the profile from the config file, FILEUPLOAD_ENDPOINT and FILEUPLOAD_API_KEY override its values
+func loadProfile(configPath string, name string) (Profile, error) {
+	var cliConfig CliConfig
+	var profile Profile
+
+	f, err := os.Open(configPath)
+	switch {
+	case err == nil:
+		defer f.Close()
+		err = yaml.NewDecoder(f).Decode(&cliConfig)
+		if err != nil {
+			return profile, fmt.Errorf("invalid config file %s: %w", configPath, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return profile, err
+	}
+
+	if name == "" {
+		name = cliConfig.DefaultProfile
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	profile, ok := cliConfig.Profiles[name]
+	if !ok && name != "default" {
+		return profile, fmt.Errorf("profile %s not found in %s", name, configPath)
+	}
+
+	if endpoint := os.Getenv("FILEUPLOAD_ENDPOINT"); endpoint != "" {
+		profile.Endpoint = endpoint
+	}
+	if apiKey := os.Getenv("FILEUPLOAD_API_KEY"); apiKey != "" {
+		profile.ApiKey = apiKey
+	}
+	if profile.Endpoint == "" {
+		profile.Endpoint = "http://localhost:8080/api/v1"
+	}
+	return profile, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	err := os.WriteFile(configPath, []byte(`default-profile: local
+profiles:
+  local:
+    endpoint: "http://localhost:8080/api/v1"
+    api-key: "local-key"
+    bucket: "devbucket"
+  prod:
+    endpoint: "https://files.example.com/api/v1"
+    api-key: "prod-key"
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		configPath string
+		name       string
+		env        map[string]string
+		want       Profile
+		wantErr    bool
+	}{
+		"default profile": {
+			configPath: configPath,
+			want:       Profile{Endpoint: "http://localhost:8080/api/v1", ApiKey: "local-key", Bucket: "devbucket"},
+		},
+		"named profile": {
+			configPath: configPath,
+			name:       "prod",
+			want:       Profile{Endpoint: "https://files.example.com/api/v1", ApiKey: "prod-key"},
+		},
+		"unknown profile": {
+			configPath: configPath,
+			name:       "staging",
+			wantErr:    true,
+		},
+		"environment overrides": {
+			configPath: configPath,
+			env:        map[string]string{"FILEUPLOAD_ENDPOINT": "http://other:8080/api/v1", "FILEUPLOAD_API_KEY": "env-key"},
+			want:       Profile{Endpoint: "http://other:8080/api/v1", ApiKey: "env-key", Bucket: "devbucket"},
+		},
+		"missing config file": {
+			configPath: filepath.Join(t.TempDir(), "missing.yml"),
+			want:       Profile{Endpoint: "http://localhost:8080/api/v1"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("FILEUPLOAD_ENDPOINT", test.env["FILEUPLOAD_ENDPOINT"])
+			t.Setenv("FILEUPLOAD_API_KEY", test.env["FILEUPLOAD_API_KEY"])
+
+			got, err := loadProfile(test.configPath, test.name)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/cheggaaa/pb"
+
+	"github.com/pavva91/file-upload/pkg/client"
+)
+
+type uploadJob struct {
+	localPath  string
+	objectName string
+	size       int64
+}
+
+func runUpload(ctx context.Context, c *client.Client, profile Profile, args []string) error {
+	flags := flag.NewFlagSet("upload", flag.ExitOnError)
+	bucket := flags.String("bucket", profile.Bucket, "bucket name")
+	prefix := flags.String("prefix", "", "prefix prepended to the object names")
+	concurrency := flags.Int("concurrency", 4, "number of files uploaded in parallel")
+	resume := flags.Bool("resume", false, "skip files already uploaded with the same size by a previous run")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("upload: missing file or directory")
+	}
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+
+	jobs, err := collectUploadJobs(flags.Args(), *prefix)
+	if err != nil {
+		return err
+	}
+
+	if *resume {
+		jobs, err = skipUploaded(ctx, c, *bucket, *prefix, jobs)
+		if err != nil {
+			return err
+		}
+	}
+
+	var total int64
+	for _, job := range jobs {
+		total += job.size
+	}
+	progress := pb.New64(total).SetUnits(pb.U_BYTES).Prefix(fmt.Sprintf("%d files ", len(jobs)))
+	progress.Start()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []error
+	queue := make(chan uploadJob)
+
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				err := uploadFile(ctx, c, *bucket, job, progress)
+				if err != nil {
+					mu.Lock()
+					failed = append(failed, fmt.Errorf("upload %s: %w", job.localPath, err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+	progress.Finish()
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("upload interrupted, run again with -resume to upload the remaining files")
+	}
+	return errors.Join(failed...)
+}
+
+func uploadFile(ctx context.Context, c *client.Client, bucket string, job uploadJob, progress *pb.ProgressBar) error {
+	f, err := os.Open(job.localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = c.UploadFile(ctx, bucket, job.objectName, filepath.Base(job.localPath), progress.NewProxyReader(f))
+	return err
+}
+
+// collectUploadJobs function    Files to upload, directories are walked and their files keep the path relative to the directory parent
+func collectUploadJobs(paths []string, prefix string) ([]uploadJob, error) {
+	var jobs []uploadJob
+
+	for _, root := range paths {
+		root = filepath.Clean(root)
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			jobs = append(jobs, uploadJob{localPath: root, objectName: prefix + info.Name(), size: info.Size()})
+			continue
+		}
+
+		parent := filepath.Dir(root)
+		err = filepath.WalkDir(root, func(localPath string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			relativePath, err := filepath.Rel(parent, localPath)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, uploadJob{localPath: localPath, objectName: prefix + filepath.ToSlash(relativePath), size: info.Size()})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// skipUploaded function    Drop the jobs whose object already exists with the same size and is newer than the local file
+func skipUploaded(ctx context.Context, c *client.Client, bucket string, prefix string, jobs []uploadJob) ([]uploadJob, error) {
+	files, err := c.ListFiles(ctx, bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	uploaded := make(map[string]client.FileInfo, len(files))
+	for _, file := range files {
+		uploaded[file.Name] = file
+	}
+
+	remaining := jobs[:0]
+	for _, job := range jobs {
+		file, ok := uploaded[job.objectName]
+		if ok && file.Size == job.size {
+			info, err := os.Stat(job.localPath)
+			if err == nil && !info.ModTime().After(file.LastModified) {
+				continue
+			}
+		}
+		remaining = append(remaining, job)
+	}
+
+	if skipped := len(jobs) - len(remaining); skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipping %d files already uploaded\n", skipped)
+	}
+	return remaining, nil
+}
+
+func runDownload(ctx context.Context, c *client.Client, profile Profile, args []string) error {
+	flags := flag.NewFlagSet("download", flag.ExitOnError)
+	bucket := flags.String("bucket", profile.Bucket, "bucket name")
+	output := flags.String("o", "", "destination file (default: object base name)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("download: expected one object name")
+	}
+	name := flags.Arg(0)
+	if *output == "" {
+		*output = path.Base(name)
+	}
+
+	// Resume from the end of a partially downloaded file, if the object didn't change since (If-Range with the ETag
+	// stored next to it), otherwise the server sends the whole object again
+	etagPath := *output + ".etag"
+	var offset int64
+	var etag string
+	if stored, err := os.ReadFile(etagPath); err == nil {
+		if info, err := os.Stat(*output); err == nil {
+			offset = info.Size()
+			etag = string(stored)
+		}
+	}
+
+	file, err := c.ResumeFile(ctx, *bucket, name, offset, etag)
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		fmt.Fprintln(os.Stderr, *output, "already downloaded")
+		return os.Remove(etagPath)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if offset > 0 && file.Offset == 0 {
+		fmt.Fprintln(os.Stderr, name, "changed since the partial download, downloading it again")
+	}
+
+	openFlag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if file.Offset > 0 {
+		openFlag = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(*output, openFlag, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if file.ETag != "" {
+		err = os.WriteFile(etagPath, []byte(file.ETag), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+
+	progress := pb.New64(file.Size).SetUnits(pb.U_BYTES).Prefix(path.Base(name) + " ")
+	progress.Set64(file.Offset)
+	progress.Start()
+	defer progress.Finish()
+
+	_, err = io.Copy(f, progress.NewProxyReader(file))
+	if err != nil {
+		return fmt.Errorf("download %s interrupted, run again to resume: %w", name, err)
+	}
+	err = os.Remove(etagPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavva91/file-upload/pkg/client"
+)
+
+func TestRunDownload(t *testing.T) {
+	const content = "hello world"
+	const etag = `"v1"`
+
+	var gotRange, gotIfRange string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange, gotIfRange = r.Header.Get("Range"), r.Header.Get("If-Range")
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+	c := client.New(ts.URL, "")
+
+	tests := map[string]struct {
+		partial     string
+		storedETag  string
+		wantRange   string
+		wantIfRange string
+	}{
+		"new download": {},
+		"resume unchanged object": {
+			partial:     "hello ",
+			storedETag:  etag,
+			wantRange:   "bytes=6-",
+			wantIfRange: etag,
+		},
+		"resume changed object": {
+			partial:     "HELLO ",
+			storedETag:  `"v0"`,
+			wantRange:   "bytes=6-",
+			wantIfRange: `"v0"`,
+		},
+		"partial file without etag": {
+			partial: "HELLO ",
+		},
+		"already downloaded": {
+			partial:     content,
+			storedETag:  etag,
+			wantRange:   "bytes=11-",
+			wantIfRange: etag,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "hello.txt")
+			if test.partial != "" {
+				if err := os.WriteFile(output, []byte(test.partial), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.storedETag != "" {
+				if err := os.WriteFile(output+".etag", []byte(test.storedETag), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := runDownload(context.Background(), c, Profile{}, []string{"-o", output, "hello.txt"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gotRange != test.wantRange || gotIfRange != test.wantIfRange {
+				t.Errorf("got Range %q If-Range %q, want %q %q", gotRange, gotIfRange, test.wantRange, test.wantIfRange)
+			}
+			downloaded, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(downloaded) != content {
+				t.Errorf("got %q, want %q", downloaded, content)
+			}
+			if _, err := os.Stat(output + ".etag"); !os.IsNotExist(err) {
+				t.Errorf("etag file kept after the download: %v", err)
+			}
+		})
+	}
+}
+
+func TestCollectUploadJobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"site/index.html", "site/js/app.js", "single.txt"} {
+		localPath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(localPath, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		paths  []string
+		prefix string
+		want   []string
+	}{
+		"file": {
+			paths: []string{filepath.Join(dir, "single.txt")},
+			want:  []string{"single.txt"},
+		},
+		"directory keeps its name": {
+			paths: []string{filepath.Join(dir, "site")},
+			want:  []string{"site/index.html", "site/js/app.js"},
+		},
+		"prefix": {
+			paths:  []string{filepath.Join(dir, "site"), filepath.Join(dir, "single.txt")},
+			prefix: "backup/",
+			want:   []string{"backup/single.txt", "backup/site/index.html", "backup/site/js/app.js"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			jobs, err := collectUploadJobs(test.paths, test.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, job := range jobs {
+				got = append(got, job.objectName)
+				if job.size != int64(len(strings.TrimPrefix(job.objectName, test.prefix))) {
+					t.Errorf("got size %d for %s", job.size, job.objectName)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -5,14 +5,19 @@ var ServerConfigValues ServerConfig
 // Model that links to config.yml file
 type ServerConfig struct {
 	Minio struct {
-		Endpoint string    `yaml:"endpoint" env:"ENDPOINT" env-description:"Minio Server Endpoint"`
-		AccessKeyID        string `yaml:"access-key-id" env:"ACCESS_KEY_ID" env-description:"Access Key ID"`
-		SecretAccessKey        string `yaml:"secret-access-key" env:"SECRET_ACCESS_KEY" env-description:"Secret Access Key"`
-		EncryptionKeyID        string `yaml:"encryption-key-id" env:"ENCRYPTION_KEY_ID" env-description:"Encryption Key ID"`
-		Bucket        string `yaml:"bucket" env:"BUCKET" env-description:"Minio Bucket"`
-		Region        string `yaml:"region" env:"REGION" env-description:"AWS Region"`
-		EnableMultipartUpload        bool `yaml:"enable-multipart-upload" env:"ENABLE_MULTIPART_UPLOAD" env-description:"Enable Multipart Upload"`
-		FileChunkSize        int `yaml:"file-chunk-size" env:"FILE_CHUNK_SIZE" env-description:"File Chunk Size"`
+		Endpoint              string `yaml:"endpoint" env:"ENDPOINT" env-description:"Minio Server Endpoint"`
+		AccessKeyID           string `yaml:"access-key-id" env:"ACCESS_KEY_ID" env-description:"Access Key ID"`
+		SecretAccessKey       string `yaml:"secret-access-key" env:"SECRET_ACCESS_KEY" env-description:"Secret Access Key"`
+		EncryptionKeyID       string `yaml:"encryption-key-id" env:"ENCRYPTION_KEY_ID" env-description:"Encryption Key ID"`
+		Bucket                string `yaml:"bucket" env:"BUCKET" env-description:"Minio Bucket"`
+		Region                string `yaml:"region" env:"REGION" env-description:"AWS Region"`
+		EnableMultipartUpload bool   `yaml:"enable-multipart-upload" env:"ENABLE_MULTIPART_UPLOAD" env-description:"Enable Multipart Upload"`
+		FileChunkSize         int    `yaml:"file-chunk-size" env:"FILE_CHUNK_SIZE" env-description:"File Chunk Size"`
+
+		BucketEncryption   string `yaml:"bucket-encryption" env:"BUCKET_ENCRYPTION" env-description:"Default encryption of the buckets created by the service: NONE, SSE-S3 or SSE-KMS (with the encryption key ID)"`
+		EnableDedup        bool   `yaml:"enable-dedup" env:"ENABLE_DEDUP" env-description:"Store identical uploads once, under their SHA-256"`
+		DedupPrefix        string `yaml:"dedup-prefix" env:"DEDUP_PREFIX" env-description:"Prefix of the deduplicated blobs and references, default .dedup/"`
+		DedupSweepInterval int    `yaml:"dedup-sweep-interval" env:"DEDUP_SWEEP_INTERVAL" env-description:"Minutes between sweeps releasing the references removed behind the service (e.g. by lifecycle rules), 0 sweeps only at startup"`
 	} `yaml:"minio"`
 	Server struct {
 		ApiPath            string   `yaml:"api-path"  env:"API_PATH" env-description:"API base path"`
@@ -20,8 +25,145 @@ type ServerConfig struct {
 		CorsAllowedClients []string `yaml:"cors-allowed-clients" env:"CORS_ALLOWED_CLIENTS"  env-description:"List of allowed CORS Clients"`
 		Environment        string   `yaml:"environment" env:"SERVER_ENVIRONMENT"  env-description:"server environment"`
 
+		CorsAllowCredentials bool `yaml:"cors-allow-credentials" env:"CORS_ALLOW_CREDENTIALS"  env-description:"Allow CORS requests with credentials"`
+		CorsMaxAge           int  `yaml:"cors-max-age" env:"CORS_MAX_AGE"  env-description:"Seconds a CORS preflight response can be cached"`
+
 		Host     string `yaml:"host"  env:"SERVER_HOST" env-description:"server host"`
 		Port     string `yaml:"port" env:"SERVER_PORT"  env-description:"server port"`
 		Protocol string `yaml:"protocol" env:"SERVER_PROTOCOL"  env-description:"server protocol"`
 	} `yaml:"server"`
+	Archive struct {
+		MaxEntries          int `yaml:"max-entries" env:"ARCHIVE_MAX_ENTRIES" env-description:"Maximum number of entries expanded from an uploaded archive"`
+		MaxEntrySize        int `yaml:"max-entry-size" env:"ARCHIVE_MAX_ENTRY_SIZE" env-description:"Maximum uncompressed size of an archive entry in MiB"`
+		MaxTotalSize        int `yaml:"max-total-size" env:"ARCHIVE_MAX_TOTAL_SIZE" env-description:"Maximum uncompressed size of all the archive entries in MiB"`
+		MaxCompressionRatio int `yaml:"max-compression-ratio" env:"ARCHIVE_MAX_COMPRESSION_RATIO" env-description:"Maximum ratio between uncompressed and compressed size of a zip entry"`
+	} `yaml:"archive"`
+	Index struct {
+		Enable            bool   `yaml:"enable" env:"INDEX_ENABLE" env-description:"Keep a local SQLite index of the objects for GET /files/search"`
+		Path              string `yaml:"path" env:"INDEX_PATH" env-description:"SQLite database file of the index, default file-upload-index.db"`
+		ReconcileInterval int    `yaml:"reconcile-interval" env:"INDEX_RECONCILE_INTERVAL" env-description:"Minutes between full scans reconciling the index with the buckets, 0 scans only at startup"`
+	} `yaml:"index"`
+	Shares struct {
+		MaxExpires int    `yaml:"max-expires" env:"SHARES_MAX_EXPIRES" env-description:"Maximum validity of the share links in hours, default 168 (7 days)"`
+		BaseURL    string `yaml:"base-url" env:"SHARES_BASE_URL" env-description:"Public url of the API the share links start with, e.g. https://files.example.com/api/v1, default the protocol, host and port of the server config"`
+	} `yaml:"shares"`
+	Notifications struct {
+		Enable  bool     `yaml:"enable" env:"NOTIFICATIONS_ENABLE" env-description:"Listen to the MinIO bucket notifications to pick up the changes made outside the API"`
+		Buckets []string `yaml:"buckets" env:"NOTIFICATIONS_BUCKETS" env-description:"Buckets to listen to, default the minio bucket"`
+	} `yaml:"notifications"`
+	Webhooks struct {
+		QueueDir    string    `yaml:"queue-dir" env:"WEBHOOKS_QUEUE_DIR" env-description:"Directory of the webhook delivery queue and dead letters, default file-upload-webhooks"`
+		MaxAttempts int       `yaml:"max-attempts" env:"WEBHOOKS_MAX_ATTEMPTS" env-description:"Delivery attempts before a webhook event is dead-lettered, default 10"`
+		Endpoints   []Webhook `yaml:"endpoints" env:"WEBHOOKS_ENDPOINTS" env-description:"Webhook subscriptions, webhooks are disabled when empty"`
+	} `yaml:"webhooks"`
+	Processing struct {
+		Enable      bool        `yaml:"enable" env:"PROCESSING_ENABLE" env-description:"Run the processors on the uploaded objects in the background"`
+		Path        string      `yaml:"path" env:"PROCESSING_PATH" env-description:"SQLite database file of the processing jobs, default file-upload-jobs.db"`
+		Workers     int         `yaml:"workers" env:"PROCESSING_WORKERS" env-description:"Jobs run at the same time, default 2"`
+		MaxAttempts int         `yaml:"max-attempts" env:"PROCESSING_MAX_ATTEMPTS" env-description:"Attempts of a job before it is marked failed, default 3"`
+		Processors  []Processor `yaml:"processors" env:"PROCESSING_PROCESSORS" env-description:"Built-in processors run on every upload they accept"`
+	} `yaml:"processing"`
+	Uploads struct {
+		MaxConcurrent int `yaml:"max-concurrent" env:"UPLOADS_MAX_CONCURRENT" env-description:"Uploads to MinIO in flight, 0 for no limit"`
+		MaxMemory     int `yaml:"max-memory" env:"UPLOADS_MAX_MEMORY" env-description:"MiB of part buffers of the uploads in flight, 0 for no limit"`
+		QueueSize     int `yaml:"queue-size" env:"UPLOADS_QUEUE_SIZE" env-description:"Uploads waiting for admission, the next ones are refused with 503, 0 for no queue"`
+		QueueTimeout  int `yaml:"queue-timeout" env:"UPLOADS_QUEUE_TIMEOUT" env-description:"Seconds an upload waits in the queue before it is refused with 503, default 30"`
+		NumThreads    int `yaml:"num-threads" env:"UPLOADS_NUM_THREADS" env-description:"Parts of an upload sent in parallel, default 4"`
+	} `yaml:"uploads"`
+	Limits struct {
+		MaxObjectSize       int            `yaml:"max-object-size" env:"LIMITS_MAX_OBJECT_SIZE" env-description:"Maximum size of an uploaded object in MiB, 0 for no limit"`
+		MaxFormParts        int            `yaml:"max-form-parts" env:"LIMITS_MAX_FORM_PARTS" env-description:"Maximum number of parts of a multipart/form-data upload, default 64"`
+		MaxObjectNameLength int            `yaml:"max-object-name-length" env:"LIMITS_MAX_OBJECT_NAME_LENGTH" env-description:"Maximum length of an object name in bytes, default 1024"`
+		Buckets             []BucketLimits `yaml:"buckets" env:"LIMITS_BUCKETS" env-description:"Maximum object sizes of single buckets, replacing the global one"`
+	} `yaml:"limits"`
+	ContentTypes struct {
+		Allow   []string            `yaml:"allow" env:"CONTENT_TYPES_ALLOW" env-description:"Media types accepted in every bucket, e.g. image/*, every type is accepted when empty"`
+		Deny    []string            `yaml:"deny" env:"CONTENT_TYPES_DENY" env-description:"Media types refused in every bucket"`
+		Buckets []ContentTypePolicy `yaml:"buckets" env:"CONTENT_TYPES_BUCKETS" env-description:"Allow and deny lists of single buckets"`
+	} `yaml:"content-types"`
+	Scan struct {
+		Enable           bool     `yaml:"enable" env:"SCAN_ENABLE" env-description:"Scan the uploads for malware before storing them"`
+		Address          string   `yaml:"address" env:"SCAN_ADDRESS" env-description:"clamd address, tcp://host:3310 or unix:///path/to/clamd.sock"`
+		Timeout          int      `yaml:"timeout" env:"SCAN_TIMEOUT" env-description:"Seconds a scan can take, default 60"`
+		FailOpen         bool     `yaml:"fail-open" env:"SCAN_FAIL_OPEN" env-description:"Store the uploads as unscanned when the scanner fails, instead of refusing them"`
+		QuarantineBucket string   `yaml:"quarantine-bucket" env:"SCAN_QUARANTINE_BUCKET" env-description:"Bucket keeping the infected uploads, they are dropped when empty"`
+		Buckets          []string `yaml:"buckets" env:"SCAN_BUCKETS" env-description:"Buckets whose uploads are scanned, default every bucket"`
+	} `yaml:"scan"`
+	Lifecycle struct {
+		ExpiresAfter []int             `yaml:"expires-after" env:"LIFECYCLE_EXPIRES_AFTER" env-description:"Days accepted by the X-Expires-After upload header, each one gets an expiration rule on the expires-after tag"`
+		Buckets      []BucketLifecycle `yaml:"buckets" env:"LIFECYCLE_BUCKETS" env-description:"Lifecycle rules applied at startup, replacing the ones of the buckets"`
+	} `yaml:"lifecycle"`
+	RateLimit struct {
+		Requests        float64              `yaml:"requests" env:"RATE_LIMIT_REQUESTS" env-description:"Requests per second of each principal (of each client address when anonymous), 0 for no limit"`
+		Burst           int                  `yaml:"burst" env:"RATE_LIMIT_BURST" env-description:"Requests accepted at once above the rate, default the requests per second"`
+		Bandwidth       int64                `yaml:"bandwidth" env:"RATE_LIMIT_BANDWIDTH" env-description:"Bytes per second of the uploads and of the downloads of each principal, 0 for no limit"`
+		GlobalBandwidth int64                `yaml:"global-bandwidth" env:"RATE_LIMIT_GLOBAL_BANDWIDTH" env-description:"Bytes per second of the uploads and of the downloads of all the principals together, 0 for no limit"`
+		Principals      []PrincipalRateLimit `yaml:"principals" env:"RATE_LIMIT_PRINCIPALS" env-description:"Limits of single principals, replacing the ones above"`
+	} `yaml:"rate-limit"`
+	Auth struct {
+		ApiKeys             []ApiKey `yaml:"api-keys" env:"API_KEYS" env-description:"API keys accepted by the server, authentication is disabled when empty"`
+		AllowAnonymousAdmin bool     `yaml:"allow-anonymous-admin" env:"AUTH_ALLOW_ANONYMOUS_ADMIN" env-description:"Without api keys, let every request use the admin routes (/buckets, webhook deliveries), for local development only"`
+	} `yaml:"auth"`
+}
+
+// Model of a client identity (principal) authenticated with an API key
+type ApiKey struct {
+	Key       string `yaml:"key"`
+	Principal string `yaml:"principal"`
+	Admin     bool   `yaml:"admin"`
+}
+
+// Model of the rate limits of a principal, 0 keeps the default limit and a negative value removes it
+type PrincipalRateLimit struct {
+	Principal string  `yaml:"principal"`
+	Requests  float64 `yaml:"requests"`
+	Burst     int     `yaml:"burst"`
+	Bandwidth int64   `yaml:"bandwidth"`
+}
+
+// Model of a webhook subscription, empty events, bucket and prefix match every event
+type Webhook struct {
+	Name   string   `yaml:"name"`
+	URL    string   `yaml:"url"`
+	Events []string `yaml:"events"`
+	Bucket string   `yaml:"bucket"`
+	Prefix string   `yaml:"prefix"`
+	Secret string   `yaml:"secret"`
+}
+
+// Model of a built-in processor of the processing pipeline and its options
+type Processor struct {
+	Name    string            `yaml:"name"`
+	Options map[string]string `yaml:"options"`
+}
+
+// Model of the limits of a bucket, a max object size of 0 keeps the global one
+type BucketLimits struct {
+	Bucket        string `yaml:"bucket"`
+	MaxObjectSize int    `yaml:"max-object-size"`
+}
+
+// Model of the content types of a bucket, a non empty allow list replaces the global one, the deny list adds to the global one
+type ContentTypePolicy struct {
+	Bucket string   `yaml:"bucket"`
+	Allow  []string `yaml:"allow"`
+	Deny   []string `yaml:"deny"`
+}
+
+// Model of the lifecycle rules of a bucket
+type BucketLifecycle struct {
+	Bucket string          `yaml:"bucket"`
+	Rules  []LifecycleRule `yaml:"rules"`
+}
+
+// Model of a lifecycle rule, the objects under prefix and with all the tags match it, 0 days disable an action
+type LifecycleRule struct {
+	ID                        string            `yaml:"id"`
+	Prefix                    string            `yaml:"prefix"`
+	Tags                      map[string]string `yaml:"tags"`
+	ExpirationDays            int               `yaml:"expiration-days"`
+	NoncurrentExpirationDays  int               `yaml:"noncurrent-expiration-days"`
+	AbortIncompleteUploadDays int               `yaml:"abort-incomplete-upload-days"`
+	TransitionDays            int               `yaml:"transition-days"`
+	TransitionStorageClass    string            `yaml:"transition-storage-class"`
 }
@@ -0,0 +1,129 @@
+// Package admission bounds the uploads in flight by number and by memory. Uploads above the budget wait in a FIFO
+// queue of limited size, a full queue refuses them right away.
+package admission
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrQueueFull    = errors.New("upload queue full")
+	ErrQueueTimeout = errors.New("timed out in the upload queue")
+)
+
+// Controller    Budget of the uploads in flight, safe for concurrent use
+type Controller struct {
+	// MaxConcurrent uploads in flight, 0 for no limit
+	MaxConcurrent int
+	// MaxMemory in bytes of the uploads in flight, 0 for no limit
+	MaxMemory int64
+	// QueueSize is the number of uploads waiting for admission, 0 refuses them when the budget is used up
+	QueueSize int
+
+	mu       sync.Mutex
+	inFlight int
+	memory   int64
+	waiters  list.List
+}
+
+type waiter struct {
+	memory int64
+	ready  chan struct{}
+}
+
+// Acquire method    Wait until an upload taking memory bytes fits in the budget, the context bounds the wait.
+// Release must be called with the same memory once the upload is done.
+func (c *Controller) Acquire(ctx context.Context, memory int64) error {
+	// A single upload above the budget runs alone
+	if c.MaxMemory > 0 {
+		memory = min(memory, c.MaxMemory)
+	}
+
+	c.mu.Lock()
+	// First come, first served: nobody overtakes the queue
+	if c.waiters.Len() == 0 && c.fits(memory) {
+		c.admit(memory)
+		c.mu.Unlock()
+		return nil
+	}
+	if c.waiters.Len() >= c.QueueSize {
+		c.mu.Unlock()
+		return ErrQueueFull
+	}
+	w := &waiter{memory: memory, ready: make(chan struct{})}
+	element := c.waiters.PushBack(w)
+	c.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Admitted meanwhile, give the budget back
+			c.release(memory)
+		default:
+			c.waiters.Remove(element)
+			// The next waiters can fit now that the head left
+			c.admitWaiters()
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrQueueTimeout
+		}
+		return ctx.Err()
+	}
+}
+
+// Release method    Give back the budget of an upload admitted by Acquire
+func (c *Controller) Release(memory int64) {
+	if c.MaxMemory > 0 {
+		memory = min(memory, c.MaxMemory)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.release(memory)
+}
+
+// Stats method    Uploads in flight, their memory in bytes and uploads waiting in the queue
+func (c *Controller) Stats() (int, int64, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight, c.memory, c.waiters.Len()
+}
+
+func (c *Controller) fits(memory int64) bool {
+	if c.MaxConcurrent > 0 && c.inFlight >= c.MaxConcurrent {
+		return false
+	}
+	return c.MaxMemory <= 0 || c.memory+memory <= c.MaxMemory
+}
+
+func (c *Controller) admit(memory int64) {
+	c.inFlight++
+	c.memory += memory
+}
+
+func (c *Controller) release(memory int64) {
+	c.inFlight--
+	c.memory -= memory
+	c.admitWaiters()
+}
+
+// admitWaiters method    Admit the waiters at the head of the queue while they fit
+func (c *Controller) admitWaiters() {
+	for element := c.waiters.Front(); element != nil; element = c.waiters.Front() {
+		w := element.Value.(*waiter)
+		if !c.fits(w.memory) {
+			return
+		}
+		c.waiters.Remove(element)
+		c.admit(w.memory)
+		close(w.ready)
+	}
+}
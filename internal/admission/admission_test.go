@@ -0,0 +1,100 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	tests := map[string]struct {
+		controller *Controller
+		// held is the memory of the uploads in flight before the one acquired
+		held    []int64
+		memory  int64
+		timeout time.Duration
+		err     error
+	}{
+		"no limit":               {controller: &Controller{}, held: []int64{1 << 30}, memory: 1 << 30},
+		"below the concurrency":  {controller: &Controller{MaxConcurrent: 2}, held: []int64{10}, memory: 10},
+		"above the concurrency":  {controller: &Controller{MaxConcurrent: 1}, held: []int64{10}, memory: 10, err: ErrQueueFull},
+		"within the memory":      {controller: &Controller{MaxMemory: 100}, held: []int64{60}, memory: 40},
+		"above the memory":       {controller: &Controller{MaxMemory: 100}, held: []int64{60}, memory: 41, err: ErrQueueFull},
+		"alone above the memory": {controller: &Controller{MaxMemory: 100}, memory: 1000},
+		"timed out in the queue": {controller: &Controller{MaxConcurrent: 1, QueueSize: 1}, held: []int64{10}, memory: 10, timeout: 10 * time.Millisecond, err: ErrQueueTimeout},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			c := test.controller
+			for _, memory := range test.held {
+				err := c.Acquire(context.Background(), memory)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx := context.Background()
+			if test.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, test.timeout)
+				defer cancel()
+			}
+			err := c.Acquire(ctx, test.memory)
+			if !errors.Is(err, test.err) {
+				t.Errorf("got %v, want %v", err, test.err)
+			}
+			if _, _, waiting := c.Stats(); waiting != 0 {
+				t.Errorf("got %d uploads waiting, want none", waiting)
+			}
+		})
+	}
+}
+
+func TestQueue(t *testing.T) {
+	c := &Controller{MaxMemory: 100, QueueSize: 2}
+	err := c.Acquire(context.Background(), 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(waiting int) {
+		for _, _, n := c.Stats(); n != waiting; _, _, n = c.Stats() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The large upload waits for the first one, the small one waits behind it although it fits
+	admitted := make(chan int64, 2)
+	for i, memory := range []int64{50, 10} {
+		memory := memory
+		go func() {
+			err := c.Acquire(context.Background(), memory)
+			if err != nil {
+				t.Error(err)
+			}
+			admitted <- memory
+		}()
+		waitFor(i + 1)
+	}
+
+	err = c.Acquire(context.Background(), 10)
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got %v, want %v", err, ErrQueueFull)
+	}
+
+	c.Release(80)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-admitted:
+		case <-time.After(time.Second):
+			t.Fatal("queued uploads not admitted")
+		}
+	}
+	inFlight, memory, waiting := c.Stats()
+	if inFlight != 2 || memory != 60 || waiting != 0 {
+		t.Errorf("got %d in flight with %d bytes and %d waiting, want 2 with 60 and none waiting", inFlight, memory, waiting)
+	}
+}
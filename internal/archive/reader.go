@@ -0,0 +1,245 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	FormatTar   = "tar"
+	FormatTarGz = "tar.gz"
+	FormatZip   = "zip"
+)
+
+var (
+	ErrUnsafePath     = errors.New("unsafe entry path")
+	ErrLimitExceeded  = errors.New("archive limit exceeded")
+	ErrUnknownFormat  = errors.New("unknown archive format, use tar, tar.gz or zip")
+	errEntryTruncated = errors.New("entry shorter than its declared size")
+)
+
+// Limits    Bounds against zip bombs, zero values disable a limit
+type Limits struct {
+	MaxEntries          int
+	MaxEntrySize        int64
+	MaxTotalSize        int64
+	MaxCompressionRatio int64
+}
+
+// Entry    Regular file of an archive, Name is a cleaned relative path
+type Entry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// SkippedEntry    Archive entry that is not a regular file (e.g. directory or symlink)
+type SkippedEntry struct {
+	Name   string
+	Reason string
+}
+
+// WalkFunc    Called for every regular file, content must be fully read before returning
+type WalkFunc func(entry Entry, content io.Reader) error
+
+// FormatFromContentType function    Detect the archive format from the Content-Type of a request
+func FormatFromContentType(contentType string) string {
+	switch strings.TrimSpace(strings.Split(contentType, ";")[0]) {
+	case "application/x-tar", "application/tar":
+		return FormatTar
+	case "application/gzip", "application/x-gzip", "application/x-compressed-tar":
+		return FormatTarGz
+	case "application/zip", "application/x-zip-compressed":
+		return FormatZip
+	}
+	return ""
+}
+
+// SafePath function    Clean an entry name, rejecting absolute paths and paths escaping the archive root
+func SafePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\x00") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return cleaned, nil
+}
+
+// Walk function    Read an archive stream and call fn for every regular file.
+// Zip archives are spooled to a temporary file since their index is at the end.
+// Returned errors wrapping ErrLimitExceeded or ErrUnsafePath abort the walk.
+func Walk(r io.Reader, format string, limits Limits, fn WalkFunc) ([]SkippedEntry, error) {
+	w := &walker{limits: limits, fn: fn}
+
+	var err error
+	switch format {
+	case FormatTar:
+		err = w.walkTar(r)
+	case FormatTarGz:
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		err = w.walkTar(gz)
+	case FormatZip:
+		err = w.walkZip(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	return w.skipped, err
+}
+
+type walker struct {
+	limits    Limits
+	fn        WalkFunc
+	entries   int
+	totalSize int64
+	skipped   []SkippedEntry
+}
+
+func (w *walker) walkTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name, err := SafePath(header.Name)
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			w.skip(name, header.Typeflag == tar.TypeDir, header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeLink)
+			continue
+		}
+
+		err = w.entry(Entry{Name: name, Size: header.Size, ModTime: header.ModTime}, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (w *walker) walkZip(r io.Reader) error {
+	spool, err := os.CreateTemp("", "file-upload-archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	// The compressed archive can't be bigger than the total uncompressed size allowed
+	var source io.Reader = r
+	if w.limits.MaxTotalSize > 0 {
+		source = io.LimitReader(r, w.limits.MaxTotalSize+1)
+	}
+	size, err := io.Copy(spool, source)
+	if err != nil {
+		return err
+	}
+	if w.limits.MaxTotalSize > 0 && size > w.limits.MaxTotalSize {
+		return fmt.Errorf("%w: archive bigger than %d bytes", ErrLimitExceeded, w.limits.MaxTotalSize)
+	}
+
+	zr, err := zip.NewReader(spool, size)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range zr.File {
+		name, err := SafePath(file.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := file.Mode()
+		if !mode.IsRegular() {
+			w.skip(name, mode.IsDir(), mode&os.ModeSymlink != 0)
+			continue
+		}
+
+		if w.limits.MaxCompressionRatio > 0 && file.UncompressedSize64 > 0 &&
+			file.UncompressedSize64 > uint64(w.limits.MaxCompressionRatio)*(file.CompressedSize64+1) {
+			return fmt.Errorf("%w: compression ratio of %s above %d", ErrLimitExceeded, name, w.limits.MaxCompressionRatio)
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = w.entry(Entry{Name: name, Size: int64(file.UncompressedSize64), ModTime: file.Modified}, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) skip(name string, isDir bool, isLink bool) {
+	reason := "not a regular file"
+	switch {
+	case isDir:
+		reason = "directory"
+	case isLink:
+		reason = "link"
+	}
+	w.skipped = append(w.skipped, SkippedEntry{Name: name, Reason: reason})
+}
+
+// entry method    Enforce the limits and hand over the content, which is cut at the declared size since zip headers can lie
+func (w *walker) entry(entry Entry, content io.Reader) error {
+	w.entries++
+	if w.limits.MaxEntries > 0 && w.entries > w.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, w.limits.MaxEntries)
+	}
+	if entry.Size < 0 || (w.limits.MaxEntrySize > 0 && entry.Size > w.limits.MaxEntrySize) {
+		return fmt.Errorf("%w: entry %s bigger than %d bytes", ErrLimitExceeded, entry.Name, w.limits.MaxEntrySize)
+	}
+	w.totalSize += entry.Size
+	if w.limits.MaxTotalSize > 0 && w.totalSize > w.limits.MaxTotalSize {
+		return fmt.Errorf("%w: entries bigger than %d bytes", ErrLimitExceeded, w.limits.MaxTotalSize)
+	}
+
+	limited := &exactReader{r: io.LimitReader(content, entry.Size), remaining: entry.Size}
+	err := w.fn(entry, limited)
+	if err != nil {
+		return err
+	}
+	if limited.remaining > 0 {
+		return fmt.Errorf("%s: %w", entry.Name, errEntryTruncated)
+	}
+	return nil
+}
+
+type exactReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (r *exactReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if err == io.EOF && r.remaining > 0 {
+		return n, errEntryTruncated
+	}
+	return n, err
+}
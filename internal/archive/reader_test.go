@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testFile struct {
+	name    string
+	content string
+	dir     bool
+}
+
+func newTar(t *testing.T, files []testFile) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.content)), ModTime: time.Unix(1700000000, 0), Typeflag: tar.TypeReg}
+		if f.dir {
+			header.Typeflag = tar.TypeDir
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func newZip(t *testing.T, files []testFile) *bytes.Buffer {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestWalk(t *testing.T) {
+	tests := map[string]struct {
+		format  string
+		archive func(t *testing.T) io.Reader
+		limits  Limits
+		entries []string
+		skipped int
+		err     error
+	}{
+		"tar": {
+			format: FormatTar,
+			archive: func(t *testing.T) io.Reader {
+				return newTar(t, []testFile{{name: "dist/", dir: true}, {name: "dist/a.txt", content: "a"}, {name: "./dist/sub/b.js", content: "bb"}})
+			},
+			entries: []string{"dist/a.txt", "dist/sub/b.js"},
+			skipped: 1,
+		},
+		"tar path traversal": {
+			format: FormatTar,
+			archive: func(t *testing.T) io.Reader {
+				return newTar(t, []testFile{{name: "../../etc/passwd", content: "root"}})
+			},
+			err: ErrUnsafePath,
+		},
+		"tar absolute path": {
+			format: FormatTar,
+			archive: func(t *testing.T) io.Reader {
+				return newTar(t, []testFile{{name: "/etc/passwd", content: "root"}})
+			},
+			err: ErrUnsafePath,
+		},
+		"tar too many entries": {
+			format: FormatTar,
+			archive: func(t *testing.T) io.Reader {
+				return newTar(t, []testFile{{name: "a", content: "a"}, {name: "b", content: "b"}})
+			},
+			limits:  Limits{MaxEntries: 1},
+			entries: []string{"a"},
+			err:     ErrLimitExceeded,
+		},
+		"zip": {
+			format: FormatZip,
+			archive: func(t *testing.T) io.Reader {
+				return newZip(t, []testFile{{name: "a.txt", content: "a"}, {name: "dir\\b.txt", content: "b"}})
+			},
+			entries: []string{"a.txt", "dir/b.txt"},
+		},
+		"zip bomb": {
+			format: FormatZip,
+			archive: func(t *testing.T) io.Reader {
+				return newZip(t, []testFile{{name: "bomb", content: strings.Repeat("0", 1<<20)}})
+			},
+			limits: Limits{MaxCompressionRatio: 100},
+			err:    ErrLimitExceeded,
+		},
+		"zip total size": {
+			format: FormatZip,
+			archive: func(t *testing.T) io.Reader {
+				return newZip(t, []testFile{{name: "a", content: "aaaa"}, {name: "b", content: "bbbb"}})
+			},
+			limits: Limits{MaxTotalSize: 100},
+			err:    ErrLimitExceeded,
+		},
+		"unknown format": {
+			format: "rar",
+			archive: func(t *testing.T) io.Reader {
+				return strings.NewReader("")
+			},
+			err: ErrUnknownFormat,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			var entries []string
+			skipped, err := Walk(test.archive(t), test.format, test.limits, func(entry Entry, content io.Reader) error {
+				data, err := io.ReadAll(content)
+				if err != nil {
+					return err
+				}
+				if int64(len(data)) != entry.Size {
+					t.Errorf("%s: got %d bytes, want %d", entry.Name, len(data), entry.Size)
+				}
+				entries = append(entries, entry.Name)
+				return nil
+			})
+
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+			if strings.Join(entries, ",") != strings.Join(test.entries, ",") {
+				t.Errorf("got entries %v, want %v", entries, test.entries)
+			}
+			if len(skipped) != test.skipped {
+				t.Errorf("got %d skipped, want %d", len(skipped), test.skipped)
+			}
+		})
+	}
+}
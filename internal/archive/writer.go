@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+)
+
+// Writer    Archive streamed to an io.Writer one entry at a time, nothing is buffered on disk
+type Writer interface {
+	// Add writes an entry, content must hold exactly entry.Size bytes
+	Add(entry Entry, content io.Reader) error
+	// Close writes the archive trailer, it doesn't close the underlying writer
+	Close() error
+}
+
+// NewWriter function    Create a zip or tar.gz Writer
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatZip:
+		return &zipWriter{zw: zip.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{gz: gz, tw: tar.NewWriter(gz)}, nil
+	case FormatTar:
+		return &tarWriter{tw: tar.NewWriter(w)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType function    Content-Type of an archive format
+func ContentType(format string) string {
+	switch format {
+	case FormatZip:
+		return "application/zip"
+	case FormatTarGz:
+		return "application/gzip"
+	case FormatTar:
+		return "application/x-tar"
+	}
+	return "application/octet-stream"
+}
+
+// Extension function    File extension of an archive format
+func Extension(format string) string {
+	return "." + format
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+// Add method    Entries are streamed with a data descriptor, archive/zip writes zip64 descriptors and directory records for entries and archives above 4GiB
+func (w *zipWriter) Add(entry Entry, content io.Reader) error {
+	header := &zip.FileHeader{
+		Name:     entry.Name,
+		Method:   zip.Deflate,
+		Modified: entry.ModTime,
+	}
+	header.SetMode(0o644)
+
+	fw, err := w.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, content)
+	return err
+}
+
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
+
+type tarWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (w *tarWriter) Add(entry Entry, content io.Reader) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:     entry.Name,
+		Mode:     0o644,
+		Size:     entry.Size,
+		ModTime:  entry.ModTime,
+		Typeflag: tar.TypeReg,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w.tw, content)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	err := w.tw.Close()
+	if err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	files := map[string]string{"a.txt": "hello", "dir/b.txt": strings.Repeat("b", 100000)}
+
+	for _, format := range []string{FormatZip, FormatTarGz, FormatTar} {
+		format := format
+
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"a.txt", "dir/b.txt"} {
+				err := w.Add(Entry{Name: name, Size: int64(len(files[name])), ModTime: modTime}, strings.NewReader(files[name]))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			read := map[string]string{}
+			_, err = Walk(&buf, format, Limits{}, func(entry Entry, content io.Reader) error {
+				data, err := io.ReadAll(content)
+				if !entry.ModTime.Equal(modTime) {
+					t.Errorf("%s: got mod time %s, want %s", entry.Name, entry.ModTime, modTime)
+				}
+				read[entry.Name] = string(data)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(read) != len(files) || read["a.txt"] != files["a.txt"] || read["dir/b.txt"] != files["dir/b.txt"] {
+				t.Errorf("got %d entries, want a.txt and dir/b.txt", len(read))
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Request headers, Content-MD5 is base64 (RFC 1864), the others are hex or base64
+const (
+	HeaderMD5    = "Content-MD5"
+	HeaderSHA256 = "X-Checksum-Sha256"
+	HeaderCRC32C = "X-Checksum-Crc32c"
+)
+
+// HeaderResponseMD5    Response header of the MD5, Content-MD5 would describe partial bodies of Range requests
+const HeaderResponseMD5 = "X-Checksum-Md5"
+
+// User metadata keys of the verified checksums
+const (
+	MetadataMD5    = "Checksum-Md5"
+	MetadataSHA256 = "Checksum-Sha256"
+	MetadataCRC32C = "Checksum-Crc32c"
+)
+
+var (
+	ErrMismatch = errors.New("checksum mismatch")
+	ErrInvalid  = errors.New("invalid checksum")
+
+	errNotSeekable = errors.New("checksum verifier can only be rewound before reading")
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksums    Digests of a content, nil when not provided
+type Checksums struct {
+	MD5    []byte
+	SHA256 []byte
+	CRC32C []byte
+}
+
+// MismatchError    Computed checksum different from the one provided by the client
+type MismatchError struct {
+	Header   string
+	Expected string
+	Actual   string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s: %s expected %s, got %s", ErrMismatch, e.Header, e.Expected, e.Actual)
+}
+
+func (e *MismatchError) Unwrap() error {
+	return ErrMismatch
+}
+
+// FromHeader function    Checksums of the request headers, an error wrapping ErrInvalid for malformed values
+func FromHeader(h http.Header) (Checksums, error) {
+	var checksums Checksums
+	var err error
+
+	if value := h.Get(HeaderMD5); value != "" {
+		checksums.MD5, err = base64.StdEncoding.DecodeString(value)
+		if err != nil || len(checksums.MD5) != md5.Size {
+			return Checksums{}, fmt.Errorf("%w: %s must be the base64 of an MD5 digest", ErrInvalid, HeaderMD5)
+		}
+	}
+	if value := h.Get(HeaderSHA256); value != "" {
+		checksums.SHA256, err = decode(value, sha256.Size)
+		if err != nil {
+			return Checksums{}, fmt.Errorf("%w: %s must be the hex or base64 of a SHA-256 digest", ErrInvalid, HeaderSHA256)
+		}
+	}
+	if value := h.Get(HeaderCRC32C); value != "" {
+		checksums.CRC32C, err = decode(value, crc32.Size)
+		if err != nil {
+			return Checksums{}, fmt.Errorf("%w: %s must be the hex or base64 of a CRC32C", ErrInvalid, HeaderCRC32C)
+		}
+	}
+	return checksums, nil
+}
+
+// FromMetadata function    Checksums stored in the user metadata of an object
+func FromMetadata(userMetadata map[string]string) Checksums {
+	var checksums Checksums
+	checksums.MD5, _ = base64.StdEncoding.DecodeString(userMetadata[MetadataMD5])
+	checksums.SHA256, _ = hex.DecodeString(userMetadata[MetadataSHA256])
+	checksums.CRC32C, _ = hex.DecodeString(userMetadata[MetadataCRC32C])
+	return checksums
+}
+
+func (c Checksums) IsZero() bool {
+	return len(c.MD5) == 0 && len(c.SHA256) == 0 && len(c.CRC32C) == 0
+}
+
+// Metadata method    User metadata to store with the object
+func (c Checksums) Metadata() map[string]string {
+	metadata := map[string]string{}
+	if len(c.MD5) > 0 {
+		metadata[MetadataMD5] = base64.StdEncoding.EncodeToString(c.MD5)
+	}
+	if len(c.SHA256) > 0 {
+		metadata[MetadataSHA256] = hex.EncodeToString(c.SHA256)
+	}
+	if len(c.CRC32C) > 0 {
+		metadata[MetadataCRC32C] = hex.EncodeToString(c.CRC32C)
+	}
+	return metadata
+}
+
+// SetHeaders method    Response headers of the checksums of the whole object, hex encoded except the base64 MD5
+func (c Checksums) SetHeaders(h http.Header) {
+	if len(c.MD5) > 0 {
+		h.Set(HeaderResponseMD5, base64.StdEncoding.EncodeToString(c.MD5))
+	}
+	if len(c.SHA256) > 0 {
+		h.Set(HeaderSHA256, hex.EncodeToString(c.SHA256))
+	}
+	if len(c.CRC32C) > 0 {
+		h.Set(HeaderCRC32C, hex.EncodeToString(c.CRC32C))
+	}
+}
+
+// Verifier    Reader computing the checksums of what it reads.
+// The chunk completing the content is held back when a checksum doesn't match, so that consumers
+// never read the whole content and an upload fed by the Verifier fails instead of completing.
+type Verifier struct {
+	r        io.Reader
+	size     int64
+	read     int64
+	expected Checksums
+	hashes   map[string]hash.Hash
+	err      error
+	verified bool
+}
+
+// NewVerifier function    Verify the content of r against expected, size is -1 when unknown (verified at EOF)
+func NewVerifier(r io.Reader, size int64, expected Checksums) *Verifier {
+	v := &Verifier{r: r, size: size, expected: expected, hashes: map[string]hash.Hash{}}
+	if len(expected.MD5) > 0 {
+		v.hashes[HeaderMD5] = md5.New()
+	}
+	if len(expected.SHA256) > 0 {
+		v.hashes[HeaderSHA256] = sha256.New()
+	}
+	if len(expected.CRC32C) > 0 {
+		v.hashes[HeaderCRC32C] = crc32.New(crc32cTable)
+	}
+	return v
+}
+
+func (v *Verifier) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	n, err := v.r.Read(p)
+	for _, h := range v.hashes {
+		h.Write(p[:n])
+	}
+	v.read += int64(n)
+
+	if !v.verified && (err == io.EOF || (v.size >= 0 && v.read >= v.size)) {
+		v.verified = true
+		v.err = v.verify()
+		if v.err != nil {
+			return 0, v.err
+		}
+	}
+	return n, err
+}
+
+// Seek method    Only rewinding an unread Verifier is supported, so that uploads are not retried after a mismatch
+func (v *Verifier) Seek(offset int64, whence int) (int64, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	if offset != 0 || whence != io.SeekStart || v.read > 0 {
+		return 0, errNotSeekable
+	}
+	return 0, nil
+}
+
+// Verified method    True when the whole content was read and matched the expected checksums
+func (v *Verifier) Verified() bool {
+	return v.verified && v.err == nil
+}
+
+func (v *Verifier) verify() error {
+	for _, header := range []string{HeaderMD5, HeaderSHA256, HeaderCRC32C} {
+		h, ok := v.hashes[header]
+		if !ok {
+			continue
+		}
+		expected := v.expectedOf(header)
+		actual := h.Sum(nil)
+		if !bytes.Equal(expected, actual) {
+			encode := hex.EncodeToString
+			if header == HeaderMD5 {
+				encode = base64.StdEncoding.EncodeToString
+			}
+			return &MismatchError{Header: header, Expected: encode(expected), Actual: encode(actual)}
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) expectedOf(header string) []byte {
+	switch header {
+	case HeaderMD5:
+		return v.expected.MD5
+	case HeaderSHA256:
+		return v.expected.SHA256
+	}
+	return v.expected.CRC32C
+}
+
+// decode function    Decode a hex or base64 digest of size bytes
+func decode(value string, size int) ([]byte, error) {
+	if len(value) == hex.EncodedLen(size) && !strings.HasSuffix(value, "=") {
+		if digest, err := hex.DecodeString(value); err == nil {
+			return digest, nil
+		}
+	}
+	digest, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(digest) != size {
+		return nil, ErrInvalid
+	}
+	return digest, nil
+}
@@ -0,0 +1,124 @@
+package checksum
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	content = "hello world"
+	// Digests of content
+	md5Base64    = "XrY7u+Ae7tCTyyK7j1rNww=="
+	sha256Hex    = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	sha256Base64 = "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="
+	crc32cHex    = "c99465aa"
+	crc32cBase64 = "yZRlqg=="
+)
+
+func TestVerifier(t *testing.T) {
+	tests := map[string]struct {
+		header http.Header
+		size   int64
+		err    error
+	}{
+		"md5": {
+			header: header(HeaderMD5, md5Base64),
+			size:   -1,
+		},
+		"sha256 hex with known size": {
+			header: header(HeaderSHA256, sha256Hex),
+			size:   int64(len(content)),
+		},
+		"sha256 base64": {
+			header: header(HeaderSHA256, sha256Base64),
+			size:   -1,
+		},
+		"all checksums": {
+			header: header(HeaderMD5, md5Base64, HeaderSHA256, sha256Hex, HeaderCRC32C, crc32cBase64),
+			size:   -1,
+		},
+		"crc32c mismatch": {
+			header: header(HeaderCRC32C, "00000000"),
+			size:   -1,
+			err:    ErrMismatch,
+		},
+		"sha256 mismatch with known size": {
+			header: header(HeaderSHA256, strings.Repeat("0", 64), HeaderCRC32C, crc32cHex),
+			size:   int64(len(content)),
+			err:    ErrMismatch,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			expected, err := FromHeader(test.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := NewVerifier(strings.NewReader(content), test.size, expected)
+			var read []byte
+			if test.size < 0 {
+				read, err = io.ReadAll(v)
+			} else {
+				// Read exactly the content like PutObject does with a known size, without waiting for EOF
+				read = make([]byte, test.size)
+				var n int
+				n, err = io.ReadFull(v, read)
+				read = read[:n]
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+			if test.err != nil && test.size >= 0 && len(read) == len(content) {
+				t.Errorf("got the whole content despite the mismatch")
+			}
+			if v.Verified() != (test.err == nil) {
+				t.Errorf("got verified %t, want %t", v.Verified(), test.err == nil)
+			}
+		})
+	}
+}
+
+func header(keyValues ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i < len(keyValues); i += 2 {
+		h.Set(keyValues[i], keyValues[i+1])
+	}
+	return h
+}
+
+func TestFromHeader(t *testing.T) {
+	tests := map[string]struct {
+		header http.Header
+		err    error
+	}{
+		"none":           {header: header()},
+		"md5 hex":        {header: header(HeaderMD5, "5eb63bbbe01eeed093cb22bb8f5acdc3"), err: ErrInvalid},
+		"sha256 short":   {header: header(HeaderSHA256, "b94d27b9"), err: ErrInvalid},
+		"crc32c base64":  {header: header(HeaderCRC32C, crc32cBase64)},
+		"crc32c garbage": {header: header(HeaderCRC32C, "not a crc"), err: ErrInvalid},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			checksums, err := FromHeader(test.header)
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+			if err == nil && len(test.header) > 0 {
+				metadata := checksums.Metadata()
+				if FromMetadata(metadata).IsZero() {
+					t.Errorf("checksums lost in the metadata %v", metadata)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Package contenttype detects the media type of uploaded content from its first bytes.
+// http.DetectContentType covers the formats of the web, the signatures here add archives,
+// executables and a few image and document formats. The detected type is reconciled with the
+// type declared by the client and the extension of the object name.
+package contenttype
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// SniffLen    Bytes of the content read to detect its type
+const SniffLen = 512
+
+// Generic    Type of content without a known signature
+const Generic = "application/octet-stream"
+
+type signature struct {
+	offset    int
+	magic     string
+	mediaType string
+}
+
+// signatures    Checked before http.DetectContentType, which doesn't know these formats
+var signatures = []signature{
+	{0, "7z\xbc\xaf\x27\x1c", "application/x-7z-compressed"},
+	{0, "BZh", "application/x-bzip2"},
+	{0, "\xfd7zXZ\x00", "application/x-xz"},
+	{0, "\x28\xb5\x2f\xfd", "application/zstd"},
+	{257, "ustar", "application/x-tar"},
+	{0, "SQLite format 3\x00", "application/vnd.sqlite3"},
+	{0, "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1", "application/x-ole-storage"},
+	{0, "\x7fELF", "application/x-executable"},
+	{0, "MZ", "application/vnd.microsoft.portable-executable"},
+	{0, "\xcf\xfa\xed\xfe", "application/x-mach-binary"},
+	{0, "\xce\xfa\xed\xfe", "application/x-mach-binary"},
+	{0, "#!", "text/x-shellscript"},
+	{0, "II*\x00", "image/tiff"},
+	{0, "MM\x00*", "image/tiff"},
+	{4, "ftypheic", "image/heic"},
+	{4, "ftypheix", "image/heic"},
+	{4, "ftypavif", "image/avif"},
+	{4, "ftypqt  ", "video/quicktime"},
+}
+
+// extensions    Types of the extensions missing from the builtin table of the mime package,
+// the system tables are not relied upon so that the result is the same on every host
+var extensions = map[string]string{
+	".csv":  "text/csv; charset=utf-8",
+	".md":   "text/markdown; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".zip":  "application/zip",
+	".tar":  "application/x-tar",
+	".gz":   "application/gzip",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".epub": "application/epub+zip",
+	".jar":  "application/java-archive",
+	".apk":  "application/vnd.android.package-archive",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".msg":  "application/vnd.ms-outlook",
+}
+
+// Detect function    Type of the content from its first bytes, at most SniffLen are considered
+func Detect(head []byte) string {
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	for _, sig := range signatures {
+		if len(head) >= sig.offset+len(sig.magic) && bytes.Equal(head[sig.offset:sig.offset+len(sig.magic)], []byte(sig.magic)) {
+			return sig.mediaType
+		}
+	}
+	return http.DetectContentType(head)
+}
+
+// ByExtension function    Type of the extension of name, empty when unknown
+func ByExtension(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return ""
+	}
+	if mediaType, ok := extensions[ext]; ok {
+		return mediaType
+	}
+	return Normalize(mime.TypeByExtension(ext))
+}
+
+// Resolve function    Type stored for content detected as detected, declared by the client (empty when not declared)
+// and named name. The detected type wins, the declared type or else the extension only refine it: any type for
+// content without a signature, a textual type for plain text and the formats based on the zip or OLE containers.
+func Resolve(detected string, declared string, name string) string {
+	claimed := Normalize(declared)
+	if claimed == "" || claimed == Generic {
+		claimed = ByExtension(name)
+	}
+	if claimed == "" || claimed == Generic {
+		return detected
+	}
+
+	switch base := MediaType(detected); {
+	case base == Generic:
+		return claimed
+	case base == "text/plain" && textual(claimed):
+		return withCharset(claimed, detected)
+	case (base == "text/xml" || base == "application/xml") && xmlBased(claimed):
+		return withCharset(claimed, detected)
+	case base == "application/zip" && zipBased(claimed):
+		return claimed
+	case base == "application/x-ole-storage" && oleBased(claimed):
+		return claimed
+	}
+	return detected
+}
+
+// Normalize function    Media type in canonical form with its parameters, empty when invalid
+func Normalize(contentType string) string {
+	if strings.TrimSpace(contentType) == "" {
+		return ""
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mime.FormatMediaType(mediaType, params)
+}
+
+// MediaType function    Media type without the parameters, lower case
+func MediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// Match function    Whether contentType matches one of the patterns: a media type, a type wildcard like image/* or */*
+func Match(patterns []string, contentType string) bool {
+	mediaType := MediaType(contentType)
+	for _, pattern := range patterns {
+		pattern = MediaType(pattern)
+		if pattern == "*/*" || pattern == "*" || pattern == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func textual(contentType string) bool {
+	mediaType := MediaType(contentType)
+	switch mediaType {
+	case "application/json", "application/javascript", "application/yaml", "application/x-yaml", "application/toml", "application/sql":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/") || xmlBased(mediaType) || strings.HasSuffix(mediaType, "+json")
+}
+
+func xmlBased(contentType string) bool {
+	mediaType := MediaType(contentType)
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func zipBased(contentType string) bool {
+	mediaType := MediaType(contentType)
+	switch mediaType {
+	case "application/java-archive", "application/vnd.android.package-archive":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "application/vnd.openxmlformats-officedocument.") ||
+		strings.HasPrefix(mediaType, "application/vnd.oasis.opendocument.") ||
+		strings.HasSuffix(mediaType, "+zip")
+}
+
+func oleBased(contentType string) bool {
+	switch MediaType(contentType) {
+	case "application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint", "application/vnd.ms-outlook":
+		return true
+	}
+	return false
+}
+
+// withCharset function    Text type claimed with the charset of the detected type when it has none
+func withCharset(claimed string, detected string) string {
+	mediaType, params, err := mime.ParseMediaType(claimed)
+	if err != nil || params["charset"] != "" || !strings.HasPrefix(mediaType, "text/") {
+		return claimed
+	}
+	_, detectedParams, err := mime.ParseMediaType(detected)
+	if err != nil || detectedParams["charset"] == "" {
+		return claimed
+	}
+	params["charset"] = detectedParams["charset"]
+	return mime.FormatMediaType(mediaType, params)
+}
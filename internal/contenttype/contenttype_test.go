@@ -0,0 +1,102 @@
+package contenttype
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar, "file.txt")
+	copy(tar[257:], "ustar\x0000")
+
+	tests := map[string]struct {
+		head []byte
+		want string
+	}{
+		"png":        {head: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), want: "image/png"},
+		"text":       {head: []byte("hello world"), want: "text/plain; charset=utf-8"},
+		"html":       {head: []byte("  <!DOCTYPE html><p>hi"), want: "text/html; charset=utf-8"},
+		"7z":         {head: []byte("7z\xbc\xaf\x27\x1c\x00\x04"), want: "application/x-7z-compressed"},
+		"tar":        {head: tar, want: "application/x-tar"},
+		"elf":        {head: []byte("\x7fELF\x02\x01\x01"), want: "application/x-executable"},
+		"heic":       {head: []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), want: "image/heic"},
+		"script":     {head: []byte("#!/bin/sh\nrm -rf /tmp/x\n"), want: "text/x-shellscript"},
+		"binary":     {head: []byte{0x00, 0x01, 0x02, 0x03}, want: Generic},
+		"empty":      {head: nil, want: "text/plain; charset=utf-8"},
+		"truncated":  {head: []byte("II*"), want: "text/plain; charset=utf-8"},
+		"past sniff": {head: append([]byte(strings.Repeat("a", SniffLen)), "\x00\x01"...), want: "text/plain; charset=utf-8"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if got := Detect(test.head); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	const docx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+	tests := map[string]struct {
+		detected string
+		declared string
+		name     string
+		want     string
+	}{
+		"detected wins":             {detected: "image/png", declared: "text/plain", name: "cat.txt", want: "image/png"},
+		"declared refines text":     {detected: "text/plain; charset=utf-8", declared: "application/json", name: "data", want: "application/json"},
+		"extension refines text":    {detected: "text/plain; charset=utf-8", name: "reports/2024.csv", want: "text/csv; charset=utf-8"},
+		"charset of the detection":  {detected: "text/plain; charset=utf-16le", declared: "text/csv", name: "a.csv", want: "text/csv; charset=utf-16le"},
+		"text is not an image":      {detected: "text/plain; charset=utf-8", declared: "image/png", name: "fake.png", want: "text/plain; charset=utf-8"},
+		"generic declaration":       {detected: "text/plain; charset=utf-8", declared: Generic, name: "a.md", want: "text/markdown; charset=utf-8"},
+		"unknown content":           {detected: Generic, declared: "application/x-custom", name: "a.bin", want: "application/x-custom"},
+		"unknown content extension": {detected: Generic, name: "a.pdf", want: "application/pdf"},
+		"unknown everything":        {detected: Generic, name: "a", want: Generic},
+		"zip container":             {detected: "application/zip", name: "letter.docx", want: docx},
+		"zip is not a pdf":          {detected: "application/zip", name: "a.pdf", want: "application/zip"},
+		"svg":                       {detected: "text/xml; charset=utf-8", name: "logo.svg", want: "image/svg+xml"},
+		"invalid declaration":       {detected: "text/plain; charset=utf-8", declared: "text/", name: "a.csv", want: "text/csv; charset=utf-8"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if got := Resolve(test.detected, test.declared, test.name); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := map[string]struct {
+		patterns    []string
+		contentType string
+		want        bool
+	}{
+		"exact":          {patterns: []string{"application/pdf"}, contentType: "application/pdf", want: true},
+		"parameters":     {patterns: []string{"text/csv"}, contentType: "text/csv; charset=utf-8", want: true},
+		"case":           {patterns: []string{"Image/PNG"}, contentType: "image/png", want: true},
+		"type wildcard":  {patterns: []string{"image/*"}, contentType: "image/heic", want: true},
+		"other type":     {patterns: []string{"image/*"}, contentType: "imagex/png", want: false},
+		"any":            {patterns: []string{"*/*"}, contentType: "application/zip", want: true},
+		"none":           {patterns: nil, contentType: "image/png", want: false},
+		"other subtypes": {patterns: []string{"text/plain", "text/csv"}, contentType: "text/html", want: false},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if got := Match(test.patterns, test.contentType); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+	"github.com/minio/minio-go/v7/pkg/tags"
+)
+
+// LifecycleRule    Actions on the objects under a prefix, and with all the tags when set, 0 days disable an action
+type LifecycleRule struct {
+	ID     string            `json:"id"`
+	Prefix string            `json:"prefix,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	// ExpirationDays after the creation of the objects
+	ExpirationDays int `json:"expirationDays,omitempty"`
+	// NoncurrentExpirationDays after a version of an object is replaced
+	NoncurrentExpirationDays int `json:"noncurrentExpirationDays,omitempty"`
+	// AbortIncompleteUploadDays after a multipart upload is started
+	AbortIncompleteUploadDays int `json:"abortIncompleteUploadDays,omitempty"`
+	// TransitionDays after the creation of the objects they move to TransitionStorageClass
+	TransitionDays         int    `json:"transitionDays,omitempty"`
+	TransitionStorageClass string `json:"transitionStorageClass,omitempty"`
+}
+
+// BucketLifecycle    Body of GET and PUT /buckets/{name}/lifecycle
+type BucketLifecycle struct {
+	Rules []LifecycleRule `json:"rules"`
+}
+
+func (l *BucketLifecycle) Validate() error {
+	return ValidateLifecycleRules(l.Rules)
+}
+
+// CreateBucketRequest    Body of POST /buckets
+type CreateBucketRequest struct {
+	Name string `json:"name"`
+	// Region defaults to the region of the config
+	Region        string `json:"region,omitempty"`
+	ObjectLocking bool   `json:"objectLocking,omitempty"`
+	Versioning    bool   `json:"versioning,omitempty"`
+	// Encryption of the objects by default: NONE, SSE-S3 or SSE-KMS, default the bucket-encryption of the config
+	Encryption string `json:"encryption,omitempty"`
+	// EncryptionKeyID of SSE-KMS, default the key of the config
+	EncryptionKeyID string            `json:"encryptionKeyId,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	Lifecycle       []LifecycleRule   `json:"lifecycle,omitempty"`
+}
+
+func (r *CreateBucketRequest) Validate() error {
+	err := s3utils.CheckValidBucketNameStrict(r.Name)
+	if err != nil {
+		return fmt.Errorf("Insert valid bucket name: %w", err)
+	}
+
+	switch r.Encryption {
+	case "", "NONE", "SSE-S3":
+		if r.EncryptionKeyID != "" {
+			return errors.New("Encryption key ID is set only with the SSE-KMS encryption")
+		}
+	case "SSE-KMS":
+	default:
+		return errors.New("Insert valid encryption: NONE, SSE-S3 or SSE-KMS")
+	}
+
+	_, err = tags.NewTags(r.Tags, false)
+	if err != nil {
+		return fmt.Errorf("Insert valid tags: %w", err)
+	}
+
+	return ValidateLifecycleRules(r.Lifecycle)
+}
+
+// ValidateLifecycleRules function    Check the rules have distinct IDs and one action at least
+func ValidateLifecycleRules(rules []LifecycleRule) error {
+	ids := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule.ID == "" || len(rule.ID) > 255 {
+			return fmt.Errorf("lifecycle rule %d: Insert valid id (max 255 characters)", i)
+		}
+		if strings.HasPrefix(rule.ID, "expires-after-") {
+			return fmt.Errorf("lifecycle rule %s: Insert valid id, expires-after- is reserved to the X-Expires-After rules", rule.ID)
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("lifecycle rule %s: Insert unique id", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		if rule.ExpirationDays < 0 || rule.NoncurrentExpirationDays < 0 || rule.AbortIncompleteUploadDays < 0 || rule.TransitionDays < 0 {
+			return fmt.Errorf("lifecycle rule %s: Insert valid days", rule.ID)
+		}
+		if (rule.TransitionDays > 0) != (rule.TransitionStorageClass != "") {
+			return fmt.Errorf("lifecycle rule %s: Insert transition days and storage class together", rule.ID)
+		}
+		if rule.ExpirationDays == 0 && rule.NoncurrentExpirationDays == 0 && rule.AbortIncompleteUploadDays == 0 && rule.TransitionDays == 0 {
+			return fmt.Errorf("lifecycle rule %s: Insert at least one action", rule.ID)
+		}
+		if rule.AbortIncompleteUploadDays > 0 && len(rule.Tags) > 0 {
+			return fmt.Errorf("lifecycle rule %s: Incomplete uploads have no tags to filter on", rule.ID)
+		}
+		_, err := tags.NewTags(rule.Tags, true)
+		if err != nil {
+			return fmt.Errorf("lifecycle rule %s: Insert valid tags: %w", rule.ID, err)
+		}
+	}
+	return nil
+}
+
+// BucketInfo    Bucket and its configuration, the listings only have the name and creation date
+type BucketInfo struct {
+	Name            string            `json:"name"`
+	CreationDate    *time.Time        `json:"creationDate,omitempty"`
+	Region          string            `json:"region,omitempty"`
+	ObjectLocking   bool              `json:"objectLocking,omitempty"`
+	Versioning      bool              `json:"versioning,omitempty"`
+	Encryption      string            `json:"encryption,omitempty"`
+	EncryptionKeyID string            `json:"encryptionKeyId,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	Lifecycle       []LifecycleRule   `json:"lifecycle,omitempty"`
+}
+
+type ListBucketsResponse struct {
+	Buckets []BucketInfo `json:"buckets"`
+}
@@ -0,0 +1,61 @@
+package dto
+
+import "testing"
+
+func TestCreateBucketRequestValidate(t *testing.T) {
+	tests := map[string]struct {
+		request CreateBucketRequest
+		valid   bool
+	}{
+		"name only":             {request: CreateBucketRequest{Name: "reports"}, valid: true},
+		"SSE-KMS with a key":    {request: CreateBucketRequest{Name: "reports", Encryption: "SSE-KMS", EncryptionKeyID: "key"}, valid: true},
+		"invalid name":          {request: CreateBucketRequest{Name: "Reports_2024"}},
+		"key without SSE-KMS":   {request: CreateBucketRequest{Name: "reports", Encryption: "SSE-S3", EncryptionKeyID: "key"}},
+		"unknown encryption":    {request: CreateBucketRequest{Name: "reports", Encryption: "AES"}},
+		"invalid tags":          {request: CreateBucketRequest{Name: "reports", Tags: map[string]string{"": "value"}}},
+		"invalid lifecycle":     {request: CreateBucketRequest{Name: "reports", Lifecycle: []LifecycleRule{{ID: "tmp"}}}},
+		"lifecycle and options": {request: CreateBucketRequest{Name: "reports", Versioning: true, Lifecycle: []LifecycleRule{{ID: "tmp", ExpirationDays: 1}}}, valid: true},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			err := test.request.Validate()
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, want valid %t", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestValidateLifecycleRules(t *testing.T) {
+	tests := map[string]struct {
+		rules []LifecycleRule
+		valid bool
+	}{
+		"expiration":                    {rules: []LifecycleRule{{ID: "tmp", Prefix: "tmp/", ExpirationDays: 7}}, valid: true},
+		"transition":                    {rules: []LifecycleRule{{ID: "cold", TransitionDays: 30, TransitionStorageClass: "COLD"}}, valid: true},
+		"tagged expiration":             {rules: []LifecycleRule{{ID: "tagged", ExpirationDays: 1, Tags: map[string]string{"temp": "true"}}}, valid: true},
+		"no rules":                      {valid: true},
+		"missing id":                    {rules: []LifecycleRule{{ExpirationDays: 1}}},
+		"reserved id":                   {rules: []LifecycleRule{{ID: "expires-after-7", ExpirationDays: 7}}},
+		"duplicate id":                  {rules: []LifecycleRule{{ID: "tmp", ExpirationDays: 1}, {ID: "tmp", ExpirationDays: 2}}},
+		"negative days":                 {rules: []LifecycleRule{{ID: "tmp", ExpirationDays: -1}}},
+		"transition without class":      {rules: []LifecycleRule{{ID: "cold", TransitionDays: 30}}},
+		"class without transition days": {rules: []LifecycleRule{{ID: "cold", ExpirationDays: 1, TransitionStorageClass: "COLD"}}},
+		"no action":                     {rules: []LifecycleRule{{ID: "tmp"}}},
+		"tagged incomplete uploads":     {rules: []LifecycleRule{{ID: "tmp", AbortIncompleteUploadDays: 1, Tags: map[string]string{"temp": "true"}}}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			err := ValidateLifecycleRules(test.rules)
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, want valid %t", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxComposeSources    Parts of a multipart upload, every source takes one at least
+const MaxComposeSources = 10000
+
+// ComposeSource    Object, or byte range of an object, concatenated by POST /files/{name}:compose
+type ComposeSource struct {
+	// BucketName defaults to the bucket of the composed object
+	BucketName string `json:"bucketName,omitempty"`
+	Name       string `json:"name"`
+	// Offset and Length select a byte range of the source, Length 0 reads up to the end
+	Offset int64 `json:"offset,omitempty"`
+	Length int64 `json:"length,omitempty"`
+}
+
+// ComposeFileRequest    Body of POST /files/{name}:compose, the destination bucket is the ?bucketName= query parameter
+type ComposeFileRequest struct {
+	// Sources in the order of concatenation, all but the last one must have 5MiB at least
+	Sources []ComposeSource `json:"sources"`
+	// ContentType defaults to the content type of the first source
+	ContentType string            `json:"contentType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	// EncryptionKeyID encrypts the composed object with another KMS key, default the key of the config
+	EncryptionKeyID string `json:"encryptionKeyId,omitempty"`
+}
+
+func (r *ComposeFileRequest) Validate() error {
+	if len(r.Sources) == 0 {
+		return errors.New("Insert at least one source")
+	}
+	if len(r.Sources) > MaxComposeSources {
+		return fmt.Errorf("Insert at most %d sources", MaxComposeSources)
+	}
+	for i, source := range r.Sources {
+		err := ValidateObjectName(source.Name)
+		if err != nil {
+			return fmt.Errorf("source %d: %w", i, err)
+		}
+		if source.Offset < 0 || source.Length < 0 {
+			return fmt.Errorf("source %d: Insert valid offset and length", i)
+		}
+	}
+
+	metadata, err := CanonicalMetadata(r.Metadata)
+	if err != nil {
+		return err
+	}
+	r.Metadata = metadata
+
+	return ValidateTags(r.Tags)
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "testing"
+
+func TestComposeFileRequestValidate(t *testing.T) {
+	tests := map[string]struct {
+		request ComposeFileRequest
+		valid   bool
+	}{
+		"sources":          {request: ComposeFileRequest{Sources: []ComposeSource{{Name: "a"}, {Name: "b", Offset: 1, Length: 2}}}, valid: true},
+		"no sources":       {request: ComposeFileRequest{}},
+		"too many sources": {request: ComposeFileRequest{Sources: make([]ComposeSource, MaxComposeSources+1)}},
+		"unnamed source":   {request: ComposeFileRequest{Sources: []ComposeSource{{Name: "a"}, {}}}},
+		"negative offset":  {request: ComposeFileRequest{Sources: []ComposeSource{{Name: "a", Offset: -1}}}},
+		"negative length":  {request: ComposeFileRequest{Sources: []ComposeSource{{Name: "a", Length: -1}}}},
+		"invalid tags":     {request: ComposeFileRequest{Sources: []ComposeSource{{Name: "a"}}, Tags: map[string]string{"": "value"}}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			err := test.request.Validate()
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, want valid %t", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package dto
+
+import "errors"
+
+// Directives of a copy, like the x-amz-metadata-directive and x-amz-tagging-directive of S3
+const (
+	DirectiveCopy    = "COPY"
+	DirectiveReplace = "REPLACE"
+)
+
+// CopyFileRequest    Body of POST /files/{name}:copy and /files/{name}:move, the source bucket is the ?bucketName= query parameter
+type CopyFileRequest struct {
+	// DestinationBucket defaults to the source bucket
+	DestinationBucket string `json:"destinationBucket,omitempty"`
+	DestinationName   string `json:"destinationName"`
+	// MetadataDirective COPY (default) keeps the user metadata of the source, REPLACE sets Metadata instead
+	MetadataDirective string            `json:"metadataDirective,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	// TaggingDirective COPY (default) keeps the tags of the source, REPLACE sets Tags instead
+	TaggingDirective string            `json:"taggingDirective,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	// EncryptionKeyID re-encrypts the copy with another KMS key, default the key of the config
+	EncryptionKeyID string `json:"encryptionKeyId,omitempty"`
+}
+
+func (r *CopyFileRequest) Validate() error {
+	err := ValidateObjectName(r.DestinationName)
+	if err != nil {
+		return err
+	}
+
+	switch r.MetadataDirective {
+	case "", DirectiveCopy:
+		if len(r.Metadata) > 0 {
+			return errors.New("Metadata is set only with the REPLACE metadata directive")
+		}
+	case DirectiveReplace:
+	default:
+		return errors.New("Insert valid metadata directive: COPY or REPLACE")
+	}
+
+	switch r.TaggingDirective {
+	case "", DirectiveCopy:
+		if len(r.Tags) > 0 {
+			return errors.New("Tags are set only with the REPLACE tagging directive")
+		}
+	case DirectiveReplace:
+	default:
+		return errors.New("Insert valid tagging directive: COPY or REPLACE")
+	}
+
+	metadata, err := CanonicalMetadata(r.Metadata)
+	if err != nil {
+		return err
+	}
+	r.Metadata = metadata
+
+	return ValidateTags(r.Tags)
+}
+
+// MoveFileResponse    Response of POST /files/{name}:move, SourceRemoved is false when the object was copied but the
+// source couldn't be removed afterwards, both are kept then
+type MoveFileResponse struct {
+	UploadFileResponse
+	SourceRemoved bool `json:"sourceRemoved"`
+}
@@ -1,6 +1,9 @@
 package dto
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 type DownloadFileRequest struct {
 	BucketName   string `json:"bucketName"`
@@ -25,3 +28,8 @@ func (r *DownloadFileRequest) Validate() error {
 
 	return nil
 }
+
+type PresignFileResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
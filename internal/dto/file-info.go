@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// FileInfo    Object listed in a bucket, json names follow minio.ObjectInfo
+type FileInfo struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	ContentType  string    `json:"contentType,omitempty"`
+	LastModified time.Time `json:"lastModified"`
+	StorageClass string    `json:"storageClass,omitempty"`
+	// Tags are only listed when filtering on tags and by search, UserMetadata by search
+	Tags         map[string]string `json:"tags,omitempty"`
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+}
+
+func NewFileInfo(objectInfo minio.ObjectInfo) FileInfo {
+	return FileInfo{
+		Name:         objectInfo.Key,
+		Size:         objectInfo.Size,
+		ETag:         objectInfo.ETag,
+		ContentType:  objectInfo.ContentType,
+		LastModified: objectInfo.LastModified,
+		StorageClass: objectInfo.StorageClass,
+		Tags:         objectInfo.UserTags,
+	}
+}
@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+// FileJobsResponse    Processing jobs of an object, newest first
+type FileJobsResponse struct {
+	BucketName string          `json:"bucketName"`
+	Name       string          `json:"name"`
+	Jobs       []ProcessingJob `json:"jobs"`
+}
+
+// ProcessingJob    Run of a processor on an object, processing.Job as sent to the clients
+type ProcessingJob struct {
+	ID          int64             `json:"id"`
+	Bucket      string            `json:"bucket"`
+	Name        string            `json:"name"`
+	ETag        string            `json:"etag"`
+	Processor   string            `json:"processor"`
+	Status      string            `json:"status"`
+	Attempts    int               `json:"attempts"`
+	Error       string            `json:"error,omitempty"`
+	Result      *ProcessingResult `json:"result,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+	NextAttempt *time.Time        `json:"nextAttempt,omitempty"`
+}
+
+// ProcessingResult    Outputs and metadata of a succeeded job
+type ProcessingResult struct {
+	Outputs  []string          `json:"outputs,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/checksum"
+)
+
+// FileMetadata    Properties of an object returned by GET /files/{name}/metadata
+type FileMetadata struct {
+	Name            string            `json:"name"`
+	BucketName      string            `json:"bucketName"`
+	Size            int64             `json:"size"`
+	ContentType     string            `json:"contentType"`
+	ETag            string            `json:"etag"`
+	LastModified    time.Time         `json:"lastModified"`
+	VersionID       string            `json:"versionId,omitempty"`
+	Encryption      string            `json:"encryption,omitempty"`
+	EncryptionKeyID string            `json:"encryptionKeyId,omitempty"`
+	RetentionMode   string            `json:"retentionMode,omitempty"`
+	RetainUntil     *time.Time        `json:"retainUntil,omitempty"`
+	LegalHold       string            `json:"legalHold,omitempty"`
+	Checksums       FileChecksums     `json:"checksums"`
+	UserMetadata    map[string]string `json:"userMetadata"`
+}
+
+// FileChecksums    Checksums verified on upload, hex encoded except the base64 MD5
+type FileChecksums struct {
+	MD5    string `json:"md5,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	CRC32C string `json:"crc32c,omitempty"`
+}
+
+// NewFileMetadata function    Build the metadata from StatObject, userMetadata is the metadata set by the uploader
+func NewFileMetadata(bucketName string, objectInfo minio.ObjectInfo, userMetadata map[string]string) FileMetadata {
+	checksums := checksum.FromMetadata(objectInfo.UserMetadata)
+
+	metadata := FileMetadata{
+		Name:            objectInfo.Key,
+		BucketName:      bucketName,
+		Size:            objectInfo.Size,
+		ContentType:     objectInfo.ContentType,
+		ETag:            objectInfo.ETag,
+		LastModified:    objectInfo.LastModified,
+		VersionID:       objectInfo.VersionID,
+		Encryption:      objectInfo.Metadata.Get("X-Amz-Server-Side-Encryption"),
+		EncryptionKeyID: objectInfo.Metadata.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"),
+		RetentionMode:   objectInfo.Metadata.Get("X-Amz-Object-Lock-Mode"),
+		LegalHold:       objectInfo.Metadata.Get("X-Amz-Object-Lock-Legal-Hold"),
+		Checksums: FileChecksums{
+			SHA256: hex.EncodeToString(checksums.SHA256),
+			CRC32C: hex.EncodeToString(checksums.CRC32C),
+		},
+		UserMetadata: userMetadata,
+	}
+	if len(checksums.MD5) > 0 {
+		metadata.Checksums.MD5 = base64.StdEncoding.EncodeToString(checksums.MD5)
+	}
+	if retainUntil, err := time.Parse(time.RFC3339, objectInfo.Metadata.Get("X-Amz-Object-Lock-Retain-Until-Date")); err == nil {
+		metadata.RetainUntil = &retainUntil
+	}
+	return metadata
+}
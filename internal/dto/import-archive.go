@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	ImportModeExpand = "expand"
+	ImportModeSingle = "single"
+
+	ImportStatusUploaded = "uploaded"
+	ImportStatusSkipped  = "skipped"
+	ImportStatusFailed   = "failed"
+)
+
+// ImportArchiveEntry    Result of one archive entry
+type ImportArchiveEntry struct {
+	Name        string `json:"name"`
+	ObjectName  string `json:"objectName,omitempty"`
+	Size        int64  `json:"size"`
+	ETag        string `json:"etag,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ImportArchiveResponse    Per-entry report of an archive upload, Error is set when the import was aborted
+type ImportArchiveResponse struct {
+	BucketName string               `json:"bucketName"`
+	Mode       string               `json:"mode"`
+	Format     string               `json:"format"`
+	Uploaded   int                  `json:"uploaded"`
+	Skipped    int                  `json:"skipped"`
+	Failed     int                  `json:"failed"`
+	Entries    []ImportArchiveEntry `json:"entries"`
+	Error      string               `json:"error,omitempty"`
+}
+
+func (r *ImportArchiveResponse) Add(entry ImportArchiveEntry) {
+	switch entry.Status {
+	case ImportStatusUploaded:
+		r.Uploaded++
+	case ImportStatusSkipped:
+		r.Skipped++
+	case ImportStatusFailed:
+		r.Failed++
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+const maxExportArchiveKeys = 10000
+
+// ExportArchiveRequest    Explicit list of objects to download as one archive
+type ExportArchiveRequest struct {
+	BucketName string   `json:"bucketName"`
+	Keys       []string `json:"keys"`
+	// Format is zip (default) or tar.gz
+	Format string `json:"format"`
+}
+
+func (r *ExportArchiveRequest) Validate() error {
+	if len(r.Keys) == 0 {
+		return errors.New("Insert at least one key")
+	}
+	if len(r.Keys) > maxExportArchiveKeys {
+		return fmt.Errorf("Insert at most %d keys", maxExportArchiveKeys)
+	}
+	for _, key := range r.Keys {
+		err := ValidateObjectName(key)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+	}
+	return nil
+}
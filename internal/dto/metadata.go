@@ -0,0 +1,162 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/tags"
+)
+
+// User metadata and tags of an upload, as X-Meta-* and X-Tags headers or as x-meta-* and tags form fields.
+// Tags use the query string format of the X-Amz-Tagging header: project=apollo&stage=raw
+const (
+	HeaderMetadataPrefix = "X-Meta-"
+	FormMetadataPrefix   = "x-meta-"
+	HeaderTags           = "X-Tags"
+	FormTags             = "tags"
+
+	// Days after which an upload expires, one of the lifecycle.expires-after of the config
+	HeaderExpiresAfter = "X-Expires-After"
+
+	// Limit of S3 on the user metadata of an object, keys and values included
+	maxUserMetadataSize = 2 * 1024
+)
+
+var ErrInvalidTags = errors.New("Insert valid tags")
+
+// Metadata keys managed by the service (dedup references, checksums, scan verdicts), they can't be set by the uploader
+var reservedMetadataPrefixes = []string{"Dedup-", "Checksum-", "Scan-", "Quarantine-"}
+
+// ObjectTags    Tags of an object, body of GET and PUT /files/{name}:tags
+type ObjectTags struct {
+	Tags map[string]string `json:"tags"`
+}
+
+func (t *ObjectTags) Validate() error {
+	return ValidateTags(t.Tags)
+}
+
+// MetadataFromHeader function    User metadata of the X-Meta-* headers
+func MetadataFromHeader(h http.Header) map[string]string {
+	metadata := map[string]string{}
+	for k := range h {
+		if strings.HasPrefix(k, HeaderMetadataPrefix) && len(k) > len(HeaderMetadataPrefix) {
+			metadata[strings.TrimPrefix(k, HeaderMetadataPrefix)] = h.Get(k)
+		}
+	}
+	return metadata
+}
+
+// MetadataFromForm function    User metadata of the x-meta-* form fields, the prefix is case insensitive
+func MetadataFromForm(form url.Values) map[string]string {
+	metadata := map[string]string{}
+	for k, values := range form {
+		if len(k) > len(FormMetadataPrefix) && strings.EqualFold(k[:len(FormMetadataPrefix)], FormMetadataPrefix) && len(values) > 0 {
+			metadata[k[len(FormMetadataPrefix):]] = values[0]
+		}
+	}
+	return metadata
+}
+
+// CanonicalMetadata function    Validate the user metadata and canonicalize its keys like MinIO stores them (e.g. project-id as Project-Id)
+func CanonicalMetadata(metadata map[string]string) (map[string]string, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	canonical := make(map[string]string, len(metadata))
+	size := 0
+	for k, v := range metadata {
+		if !validMetadataKey(k) {
+			return nil, fmt.Errorf("Insert valid metadata key %q: letters, digits, - and _ only", k)
+		}
+		if !validMetadataValue(v) {
+			return nil, fmt.Errorf("Insert valid metadata value for %q: printable ASCII only", k)
+		}
+		key := http.CanonicalHeaderKey(k)
+		for _, prefix := range reservedMetadataPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return nil, fmt.Errorf("Metadata keys starting with %s are reserved", prefix)
+			}
+		}
+		canonical[key] = v
+		size += len(key) + len(v)
+	}
+	if size > maxUserMetadataSize {
+		return nil, fmt.Errorf("User metadata exceeds %d bytes", maxUserMetadataSize)
+	}
+	return canonical, nil
+}
+
+// ParseTags function    Tags in query string format, empty when s is empty
+func ParseTags(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("Insert valid tags in query string format: %w", err)
+	}
+	t := make(map[string]string, len(values))
+	for k, v := range values {
+		if len(v) > 1 {
+			return nil, fmt.Errorf("Tag %s is repeated", k)
+		}
+		t[k] = v[0]
+	}
+	return t, ValidateTags(t)
+}
+
+// ValidateTags function    Check the S3 limits of object tags: 10 tags, keys of 128 and values of 256 characters
+func ValidateTags(t map[string]string) error {
+	_, err := tags.NewTags(t, true)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTags, err)
+	}
+	return nil
+}
+
+// ParseTagFilters function    Tags required by the ?tag=key:value query parameters of a listing
+func ParseTagFilters(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	filters := make(map[string]string, len(values))
+	for _, value := range values {
+		k, v, found := strings.Cut(value, ":")
+		if !found || k == "" {
+			return nil, errors.New("Insert valid tag filter: key:value")
+		}
+		if previous, ok := filters[k]; ok && previous != v {
+			return nil, fmt.Errorf("Tag %s is filtered on different values", k)
+		}
+		filters[k] = v
+	}
+	return filters, nil
+}
+
+func validMetadataKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, c := range k {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func validMetadataValue(v string) bool {
+	for _, c := range v {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalMetadata(t *testing.T) {
+	tests := map[string]struct {
+		metadata map[string]string
+		want     map[string]string
+		valid    bool
+	}{
+		"empty":               {valid: true},
+		"canonical keys":      {metadata: map[string]string{"project-id": "42", "owner_team": "ops"}, want: map[string]string{"Project-Id": "42", "Owner_team": "ops"}, valid: true},
+		"invalid key":         {metadata: map[string]string{"project id": "42"}},
+		"empty key":           {metadata: map[string]string{"": "42"}},
+		"non-ASCII value":     {metadata: map[string]string{"city": "Zürich"}},
+		"control character":   {metadata: map[string]string{"note": "a\nb"}},
+		"dedup prefix":        {metadata: map[string]string{"dedup-blob": "0123"}},
+		"checksum prefix":     {metadata: map[string]string{"Checksum-Sha256": "0123"}},
+		"scan prefix":         {metadata: map[string]string{"scan-result": "clean"}},
+		"quarantine prefix":   {metadata: map[string]string{"QUARANTINE-REASON": "none"}},
+		"reserved word later": {metadata: map[string]string{"last-checksum-run": "today"}, want: map[string]string{"Last-Checksum-Run": "today"}, valid: true},
+		"at the size limit":   {metadata: map[string]string{"Note": strings.Repeat("x", maxUserMetadataSize-4)}, want: map[string]string{"Note": strings.Repeat("x", maxUserMetadataSize-4)}, valid: true},
+		"above the size limit": {
+			metadata: map[string]string{"Note": strings.Repeat("x", maxUserMetadataSize-4), "A": "b"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			got, err := CanonicalMetadata(test.metadata)
+			if (err == nil) != test.valid {
+				t.Fatalf("got %v, want valid %t", err, test.valid)
+			}
+			if test.valid && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := map[string]struct {
+		tags  string
+		want  map[string]string
+		valid bool
+	}{
+		"empty":          {valid: true},
+		"tags":           {tags: "project=apollo&stage=raw", want: map[string]string{"project": "apollo", "stage": "raw"}, valid: true},
+		"escaped value":  {tags: "owner=data%20team", want: map[string]string{"owner": "data team"}, valid: true},
+		"empty value":    {tags: "draft=", want: map[string]string{"draft": ""}, valid: true},
+		"repeated tag":   {tags: "stage=raw&stage=clean"},
+		"repeated value": {tags: "stage=raw&stage=raw"},
+		"bad escape":     {tags: "stage=%zz"},
+		"too many tags":  {tags: "a=1&b=2&c=3&d=4&e=5&f=6&g=7&h=8&i=9&j=10&k=11"},
+		"long key":       {tags: strings.Repeat("k", 129) + "=v"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseTags(test.tags)
+			if (err == nil) != test.valid {
+				t.Fatalf("got %v, want valid %t", err, test.valid)
+			}
+			if test.valid && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTagFilters(t *testing.T) {
+	tests := map[string]struct {
+		values []string
+		want   map[string]string
+		valid  bool
+	}{
+		"none":              {valid: true},
+		"filters":           {values: []string{"project:apollo", "stage:raw"}, want: map[string]string{"project": "apollo", "stage": "raw"}, valid: true},
+		"colon in value":    {values: []string{"url:http://example.com"}, want: map[string]string{"url": "http://example.com"}, valid: true},
+		"empty value":       {values: []string{"draft:"}, want: map[string]string{"draft": ""}, valid: true},
+		"same filter twice": {values: []string{"stage:raw", "stage:raw"}, want: map[string]string{"stage": "raw"}, valid: true},
+		"conflicting value": {values: []string{"stage:raw", "stage:clean"}},
+		"missing colon":     {values: []string{"stage"}},
+		"missing key":       {values: []string{":raw"}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseTagFilters(test.values)
+			if (err == nil) != test.valid {
+				t.Fatalf("got %v, want valid %t", err, test.valid)
+			}
+			if test.valid && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+// Limit of S3 on the length of the object keys
+const defaultMaxObjectNameLength = 1024
+
+const defaultDedupPrefix = ".dedup/"
+
+// VariantPrefix    Prefix of the objects derived by the processors, e.g. the thumbnails, names under it are reserved
+const VariantPrefix = ".variants/"
+
+// Suffixes of the sub-resources of /files/{name}, objects named like them couldn't be downloaded
+var (
+	reservedObjectNameSuffixes = []string{"/metadata", "/jobs", "/shares", ":tags", ":copy", ":move", ":compose", ":presign"}
+	reservedObjectNameRe       = regexp.MustCompile(`^search$|/shares/[0-9a-f]{16}(/accesses)?$`)
+)
+
+// DedupPrefix function    Prefix of the dedup blobs and reference markers, the minio dedup-prefix of the config.
+// The blobs are shared by the references to the same content, names under the prefix are reserved.
+func DedupPrefix() string {
+	if prefix := config.ServerConfigValues.Minio.DedupPrefix; prefix != "" {
+		return strings.TrimSuffix(prefix, "/") + "/"
+	}
+	return defaultDedupPrefix
+}
+
+// ServiceObject function    Whether an object is a dedup blob or marker, or a variant, stored by the service itself
+func ServiceObject(name string) bool {
+	for _, prefix := range servicePrefixes() {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func servicePrefixes() []string {
+	return []string{DedupPrefix(), VariantPrefix}
+}
+
+// ValidateObjectName function    Object names are valid UTF-8 without control characters or backslashes,
+// relative (no leading slash) and without . or .. segments, so that they can't escape a directory once used as a path.
+// Names ending like a sub-resource of /files/{name} (e.g. /metadata or :tags) are refused as the routes would shadow them,
+// names under DedupPrefix and VariantPrefix as they belong to the service.
+func ValidateObjectName(name string) error {
+	if name == "" {
+		return errors.New("Insert valid object name")
+	}
+
+	maxLength := config.ServerConfigValues.Limits.MaxObjectNameLength
+	if maxLength <= 0 {
+		maxLength = defaultMaxObjectNameLength
+	}
+	if len(name) > maxLength {
+		return fmt.Errorf("Insert valid object name: longer than %d bytes", maxLength)
+	}
+	if !utf8.ValidString(name) {
+		return errors.New("Insert valid object name: invalid UTF-8")
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return errors.New("Insert valid object name: control characters are not allowed")
+		}
+		if r == '\\' {
+			return errors.New("Insert valid object name: backslashes are not allowed")
+		}
+	}
+	if strings.HasPrefix(name, "/") {
+		return errors.New("Insert valid object name: it can't start with /")
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "." || segment == ".." {
+			return errors.New("Insert valid object name: . and .. segments are not allowed")
+		}
+	}
+	for _, prefix := range servicePrefixes() {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("Insert valid object name: %s is reserved by the service", prefix)
+		}
+	}
+	for _, suffix := range reservedObjectNameSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Errorf("Insert valid object name: %s at the end is reserved by the API", suffix)
+		}
+	}
+	if reservedObjectNameRe.MatchString(name) {
+		return errors.New("Insert valid object name: reserved by the API")
+	}
+	return nil
+}
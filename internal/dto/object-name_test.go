@@ -0,0 +1,48 @@
+package dto
+
+import "testing"
+
+func TestValidateObjectName(t *testing.T) {
+	tests := map[string]struct {
+		name  string
+		valid bool
+	}{
+		"plain name":                  {name: "report.csv", valid: true},
+		"nested name":                 {name: "dir/sub/report.csv", valid: true},
+		"sub-resource name in a path": {name: "metadata/report.csv", valid: true},
+		"name containing a suffix":    {name: "dir/jobs.txt", valid: true},
+		"search in a directory":       {name: "dir/search", valid: true},
+		"empty":                       {name: ""},
+		"leading slash":               {name: "/etc/passwd"},
+		"dot dot segment":             {name: "dir/../../etc/passwd"},
+		"backslash":                   {name: `dir\report.csv`},
+		"control character":           {name: "report\n.csv"},
+		"search":                      {name: "search"},
+		"metadata suffix":             {name: "dir/metadata"},
+		"jobs suffix":                 {name: "dir/jobs"},
+		"shares suffix":               {name: "dir/shares"},
+		"share id suffix":             {name: "dir/shares/0123456789abcdef"},
+		"share accesses suffix":       {name: "dir/shares/0123456789abcdef/accesses"},
+		"tags suffix":                 {name: "report.csv:tags"},
+		"copy suffix":                 {name: "report.csv:copy"},
+		"move suffix":                 {name: "report.csv:move"},
+		"compose suffix":              {name: "report.csv:compose"},
+		"presign suffix":              {name: "report.csv:presign"},
+		"dedup blob":                  {name: ".dedup/blobs/sha256/0123456789abcdef"},
+		"dedup marker":                {name: ".dedup/refs/0123456789abcdef/report.csv"},
+		"dedup prefix in a directory": {name: "dir/.dedup/blobs/report.csv", valid: true},
+		"variant":                     {name: ".variants/photos/cat.jpg.thumb-256"},
+		"variants in a directory":     {name: "photos/.variants/cat.jpg", valid: true},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			err := ValidateObjectName(test.name)
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, want valid %t", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package dto
+
+// SearchFilesResponse    Page of the objects matching GET /files/search, Total counts all the matches
+type SearchFilesResponse struct {
+	BucketName string     `json:"bucketName"`
+	Total      int        `json:"total"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+	Files      []FileInfo `json:"files"`
+}
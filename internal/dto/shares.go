@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+// HeaderSharePassword    Password of a protected share link, HTTP Basic authentication works too
+const HeaderSharePassword = "X-Share-Password"
+
+// CreateShareRequest    Body of POST /files/{name}/shares, zero values don't restrict the link
+type CreateShareRequest struct {
+	// ExpiresIn seconds, default 24 hours
+	ExpiresIn    int    `json:"expiresIn,omitempty"`
+	Password     string `json:"password,omitempty"`
+	MaxDownloads int    `json:"maxDownloads,omitempty"`
+}
+
+func (r *CreateShareRequest) Validate() error {
+	if r.ExpiresIn < 0 {
+		return errors.New("Insert valid expiresIn in seconds")
+	}
+	if r.MaxDownloads < 0 {
+		return errors.New("Insert valid maxDownloads")
+	}
+	// bcrypt ignores the bytes after the 72nd
+	if len(r.Password) > 72 {
+		return errors.New("Insert valid password (max 72 bytes)")
+	}
+	return nil
+}
+
+// ShareInfo    Share link of an object, Token and URL are only returned at creation
+type ShareInfo struct {
+	ID           string     `json:"id"`
+	Token        string     `json:"token,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	BucketName   string     `json:"bucketName"`
+	Name         string     `json:"name"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	Protected    bool       `json:"protected"`
+	MaxDownloads int        `json:"maxDownloads,omitempty"`
+	Downloads    int        `json:"downloads"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+}
+
+type ListSharesResponse struct {
+	Shares []ShareInfo `json:"shares"`
+}
+
+// ShareAccess    Access to a share link, Result is downloaded, revoked, expired, exhausted or wrong-password
+type ShareAccess struct {
+	At         time.Time `json:"at"`
+	RemoteAddr string    `json:"remoteAddr"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	Result     string    `json:"result"`
+}
+
+type ShareAccessesResponse struct {
+	Accesses []ShareAccess `json:"accesses"`
+}
@@ -1,13 +1,22 @@
 package dto
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/contenttype"
+)
 
 type UploadFileRequest struct {
-	BucketName  string `json:"bucketName"`
+	BucketName string `json:"bucketName"`
 	// Location    string `json:"location"`
-	ObjectName  string `json:"objectName"`
-	Filepath    string `json:"filepath"`
+	ObjectName string `json:"objectName"`
+	// Filepath is the temporary file of the uploaded multipart/form-data part, never set by the clients
+	Filepath    string `json:"-"`
 	ContentType string `json:"contentType"`
+	// Metadata is stored as MinIO user metadata, Tags as object tags
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
 }
 
 func (r *UploadFileRequest) Validate() error {
@@ -25,11 +34,9 @@ func (r *UploadFileRequest) Validate() error {
 	// 	return err
 	// }
 
-	if r.ObjectName == "" {
-		errorMsg = "Insert valid object name"
-		err := errors.New(errorMsg)
+	err := ValidateObjectName(r.ObjectName)
+	if err != nil {
 		return err
-
 	}
 
 	if r.Filepath == "" {
@@ -39,11 +46,39 @@ func (r *UploadFileRequest) Validate() error {
 
 	}
 
-	if r.ContentType == "" {
+	// The content type is optional, the stored type is detected from the content
+	if r.ContentType != "" && contenttype.Normalize(r.ContentType) == "" {
 		errorMsg = "Insert valid content type"
 		err := errors.New(errorMsg)
 		return err
 
 	}
-	return nil
+
+	metadata, err := CanonicalMetadata(r.Metadata)
+	if err != nil {
+		return err
+	}
+	r.Metadata = metadata
+
+	return ValidateTags(r.Tags)
+}
+
+type UploadFileResponse struct {
+	BucketName  string `json:"bucketName"`
+	ObjectName  string `json:"objectName"`
+	ETag        string `json:"etag"`
+	Size        int64  `json:"size"`
+	VersionID   string `json:"versionId,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+func NewUploadFileResponse(uploadInfo minio.UploadInfo, contentType string) UploadFileResponse {
+	return UploadFileResponse{
+		BucketName:  uploadInfo.Bucket,
+		ObjectName:  uploadInfo.Key,
+		ETag:        uploadInfo.ETag,
+		Size:        uploadInfo.Size,
+		VersionID:   uploadInfo.VersionID,
+		ContentType: contentType,
+	}
 }
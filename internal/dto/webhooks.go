@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+// WebhookDeliveriesResponse    Webhook deliveries with the given status, pending or dead
+type WebhookDeliveriesResponse struct {
+	Status     string            `json:"status"`
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookDelivery    Delivery of an event to a webhook, webhooks.Delivery as sent to the clients
+type WebhookDelivery struct {
+	ID          string       `json:"id"`
+	Webhook     string       `json:"webhook"`
+	Event       WebhookEvent `json:"event"`
+	Status      string       `json:"status"`
+	Attempts    int          `json:"attempts"`
+	NextAttempt time.Time    `json:"nextAttempt"`
+	LastError   string       `json:"lastError,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+}
+
+// WebhookEvent    Body posted to the webhooks, webhooks.Event
+type WebhookEvent struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	Time         time.Time `json:"time"`
+	Bucket       string    `json:"bucket"`
+	Name         string    `json:"name"`
+	Size         int64     `json:"size,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	Principal    string    `json:"principal,omitempty"`
+	SourceBucket string    `json:"sourceBucket,omitempty"`
+	SourceName   string    `json:"sourceName,omitempty"`
+}
@@ -1,6 +1,11 @@
 package errorhandlers
 
-import "net/http"
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
 
 func InternalServerErrorHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusInternalServerError)
@@ -17,3 +22,57 @@ func BadRequestHandler(w http.ResponseWriter, r *http.Request, err error) {
 	w.Write([]byte(err.Error()))
 	// http.Error(w, err.Error(), http.StatusBadRequest)
 }
+
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	w.Write([]byte("405 Method Not Allowed"))
+}
+
+func UnauthorizedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="file-upload"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte("401 Unauthorized"))
+}
+
+func ServiceUnavailableHandler(w http.ResponseWriter, r *http.Request, err error) {
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(err.Error()))
+}
+
+func ForbiddenHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("403 Forbidden"))
+}
+
+func UnsupportedMediaTypeHandler(w http.ResponseWriter, r *http.Request, err error) {
+	w.WriteHeader(http.StatusUnsupportedMediaType)
+	w.Write([]byte(err.Error()))
+}
+
+func UnprocessableEntityHandler(w http.ResponseWriter, r *http.Request, err error) {
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	w.Write([]byte(err.Error()))
+}
+
+func ConflictHandler(w http.ResponseWriter, r *http.Request, err error) {
+	w.WriteHeader(http.StatusConflict)
+	w.Write([]byte(err.Error()))
+}
+
+func PayloadTooLargeHandler(w http.ResponseWriter, r *http.Request, err error) {
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	w.Write([]byte(err.Error()))
+}
+
+// TooManyRequestsHandler function    Retry-After tells the client how many seconds to wait before retrying
+func TooManyRequestsHandler(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte("429 Too Many Requests"))
+}
+
+func GoneHandler(w http.ResponseWriter, r *http.Request, err error) {
+	w.WriteHeader(http.StatusGone)
+	w.Write([]byte(err.Error()))
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/archive"
+	"github.com/pavva91/file-upload/internal/checksum"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/services"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+const (
+	defaultArchiveMaxEntries          = 10000
+	defaultArchiveMaxEntrySize        = 5 * 1024  // MiB
+	defaultArchiveMaxTotalSize        = 10 * 1024 // MiB
+	defaultArchiveMaxCompressionRatio = 100
+)
+
+// ImportArchive method    Upload a tar, tar.gz or zip stream, stored as one object (?mode=single&objectName=)
+// or expanded into one object per entry under ?prefix= (?mode=expand, default)
+func (h *FilesHandler) ImportArchive(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	bucketName := bucketFromRequest(r)
+
+	format := query.Get("format")
+	if format == "" {
+		format = archive.FormatFromContentType(r.Header.Get("Content-Type"))
+	}
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = dto.ImportModeExpand
+	}
+	if mode != dto.ImportModeExpand && mode != dto.ImportModeSingle {
+		errorhandlers.BadRequestHandler(w, r, errors.New("Insert valid mode: expand or single"))
+		return
+	}
+
+	bucketExists, err := services.BucketExist(bucketName)
+	if err != nil {
+		log.Println(err.Error())
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	if !bucketExists {
+		msg := fmt.Sprintln("bucket", bucketName, "does not exist")
+		err := errors.New(msg)
+		log.Println(err.Error())
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	// X-Meta-* and X-Tags headers apply to every imported object
+	metadata, tags, err := mergeHeaderMetadata(r, nil, nil)
+	if err == nil {
+		metadata, err = dto.CanonicalMetadata(metadata)
+	}
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	tags, err = expiresAfterTags(r, bucketName, tags)
+	if err != nil {
+		expiresAfterErrorHandler(w, r, err)
+		return
+	}
+
+	report := dto.ImportArchiveResponse{
+		BucketName: bucketName,
+		Mode:       mode,
+		Format:     format,
+		Entries:    []dto.ImportArchiveEntry{},
+	}
+
+	if mode == dto.ImportModeSingle {
+		objectName := query.Get("objectName")
+		err := dto.ValidateObjectName(objectName)
+		if err != nil {
+			errorhandlers.BadRequestHandler(w, r, err)
+			return
+		}
+
+		// The body is the object, bounded by the limit of the bucket
+		if limit := services.MaxObjectSize(bucketName); limit > 0 {
+			if r.ContentLength > limit {
+				errorhandlers.PayloadTooLargeHandler(w, r, fmt.Errorf("%w: the limit of bucket %s is %d bytes", services.ErrTooLarge, bucketName, limit))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+
+		checksums, err := checksum.FromHeader(r.Header)
+		if err != nil {
+			errorhandlers.BadRequestHandler(w, r, err)
+			return
+		}
+
+		size := r.ContentLength
+		if size == 0 {
+			size = -1
+		}
+		uploadInfo, err := services.EncryptAndUploadStream(objectName, r.Body, size, bucketName, services.UploadOptions{
+			ContentType:  r.Header.Get("Content-Type"),
+			UserMetadata: metadata,
+			Tags:         tags,
+			Checksums:    checksums,
+		})
+		if err != nil {
+			log.Println(err)
+			uploadErrorHandler(w, r, err)
+			return
+		}
+
+		report.Add(dto.ImportArchiveEntry{
+			Name:        objectName,
+			ObjectName:  objectName,
+			Size:        uploadInfo.Size,
+			ETag:        uploadInfo.ETag,
+			ContentType: uploadInfo.ContentType,
+			Status:      dto.ImportStatusUploaded,
+		})
+		publishImported(r, report)
+		writeImportReport(w, http.StatusOK, report)
+		return
+	}
+
+	if format == "" {
+		errorhandlers.BadRequestHandler(w, r, archive.ErrUnknownFormat)
+		return
+	}
+
+	prefix := query.Get("prefix")
+	skipped, err := archive.Walk(r.Body, format, archiveLimits(), func(entry archive.Entry, content io.Reader) error {
+		objectName := prefix + entry.Name
+		err := dto.ValidateObjectName(objectName)
+		if err != nil {
+			io.Copy(io.Discard, content)
+			report.Add(dto.ImportArchiveEntry{Name: entry.Name, ObjectName: objectName, Size: entry.Size, Status: dto.ImportStatusFailed, Error: err.Error()})
+			return nil
+		}
+
+		entryMetadata := make(map[string]string, len(metadata)+1)
+		for k, v := range metadata {
+			entryMetadata[k] = v
+		}
+		if !entry.ModTime.IsZero() {
+			entryMetadata[services.MetadataModTime] = entry.ModTime.UTC().Format(time.RFC3339)
+		}
+
+		// The type is detected from the content and the extension of the entry
+		uploadInfo, err := services.EncryptAndUploadStream(objectName, content, entry.Size, bucketName, services.UploadOptions{
+			UserMetadata: entryMetadata,
+			Tags:         tags,
+		})
+		if err != nil {
+			// Keep going with the next entries, the failure is in the report
+			io.Copy(io.Discard, content)
+			report.Add(dto.ImportArchiveEntry{Name: entry.Name, ObjectName: objectName, Size: entry.Size, Status: dto.ImportStatusFailed, Error: err.Error()})
+			return nil
+		}
+
+		report.Add(dto.ImportArchiveEntry{
+			Name:        entry.Name,
+			ObjectName:  objectName,
+			Size:        uploadInfo.Size,
+			ETag:        uploadInfo.ETag,
+			ContentType: uploadInfo.ContentType,
+			Status:      dto.ImportStatusUploaded,
+		})
+		return nil
+	})
+
+	for _, entry := range skipped {
+		report.Add(dto.ImportArchiveEntry{Name: entry.Name, Status: dto.ImportStatusSkipped, Error: entry.Reason})
+	}
+
+	// The entries uploaded before an abort are in the bucket all the same
+	publishImported(r, report)
+
+	if err != nil {
+		log.Println(err)
+		report.Error = err.Error()
+		status := http.StatusBadRequest
+		if errors.Is(err, archive.ErrLimitExceeded) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeImportReport(w, status, report)
+		return
+	}
+
+	writeImportReport(w, http.StatusOK, report)
+}
+
+// publishImported function    Webhook event of every uploaded entry of the report
+func publishImported(r *http.Request, report dto.ImportArchiveResponse) {
+	for _, entry := range report.Entries {
+		if entry.Status == dto.ImportStatusUploaded {
+			publishEvent(r, webhooks.Event{
+				Type:        webhooks.EventFileUploaded,
+				Bucket:      report.BucketName,
+				Name:        entry.ObjectName,
+				Size:        entry.Size,
+				ETag:        entry.ETag,
+				ContentType: entry.ContentType,
+			})
+		}
+	}
+}
+
+// archiveLimits function    Limits of the archive config section converted to bytes, defaults for missing values
+func archiveLimits() archive.Limits {
+	archiveConfig := config.ServerConfigValues.Archive
+
+	limits := archive.Limits{
+		MaxEntries:          defaultArchiveMaxEntries,
+		MaxEntrySize:        defaultArchiveMaxEntrySize,
+		MaxTotalSize:        defaultArchiveMaxTotalSize,
+		MaxCompressionRatio: defaultArchiveMaxCompressionRatio,
+	}
+	if archiveConfig.MaxEntries > 0 {
+		limits.MaxEntries = archiveConfig.MaxEntries
+	}
+	if archiveConfig.MaxEntrySize > 0 {
+		limits.MaxEntrySize = int64(archiveConfig.MaxEntrySize)
+	}
+	if archiveConfig.MaxTotalSize > 0 {
+		limits.MaxTotalSize = int64(archiveConfig.MaxTotalSize)
+	}
+	if archiveConfig.MaxCompressionRatio > 0 {
+		limits.MaxCompressionRatio = int64(archiveConfig.MaxCompressionRatio)
+	}
+
+	limits.MaxEntrySize *= 1024 * 1024
+	limits.MaxTotalSize *= 1024 * 1024
+	return limits
+}
+
+// ExportArchive method    Download a zip or tar.gz of the objects under ?prefix= (GET) or of a dto.ExportArchiveRequest key list (POST).
+// The archive is built on the fly from the object streams, entry names are relative to the prefix directory.
+func (h *FilesHandler) ExportArchive(w http.ResponseWriter, r *http.Request) {
+	bucketName := bucketFromRequest(r)
+	format := r.URL.Query().Get("format")
+	var objects []exportObject
+
+	if r.Method == http.MethodPost {
+		var reqBody dto.ExportArchiveRequest
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		if err != nil {
+			bodyErrorHandler(w, r, err)
+			return
+		}
+
+		err = reqBody.Validate()
+		if err != nil {
+			errorhandlers.BadRequestHandler(w, r, err)
+			return
+		}
+
+		if reqBody.BucketName != "" {
+			bucketName = reqBody.BucketName
+		}
+		if reqBody.Format != "" {
+			format = reqBody.Format
+		}
+
+		// Check every key before the response is started, a missing one can't be reported afterwards
+		for _, key := range reqBody.Keys {
+			_, err := services.StatObject(bucketName, key)
+			if err != nil {
+				log.Println(err)
+				if services.IsNotFound(err) {
+					errorhandlers.BadRequestHandler(w, r, fmt.Errorf("Specified file %s is not present in bucket %s", key, bucketName))
+					return
+				}
+				errorhandlers.InternalServerErrorHandler(w, r)
+				return
+			}
+			objects = append(objects, exportObject{key: key, name: key})
+		}
+	} else {
+		prefix := r.URL.Query().Get("prefix")
+		objectInfos, err := services.ListObjects(r.Context(), bucketName, prefix, nil)
+		if err != nil {
+			log.Println(err)
+			if services.IsNotFound(err) {
+				errorhandlers.NotFoundHandler(w, r)
+				return
+			}
+			errorhandlers.InternalServerErrorHandler(w, r)
+			return
+		}
+
+		prefixDir := prefix[:strings.LastIndex(prefix, "/")+1]
+		for _, o := range objectInfos {
+			objects = append(objects, exportObject{key: o.Key, name: strings.TrimPrefix(o.Key, prefixDir)})
+		}
+		if len(objects) == 0 {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+	}
+
+	if format == "" {
+		format = archive.FormatZip
+	}
+	if format != archive.FormatZip && format != archive.FormatTarGz {
+		errorhandlers.BadRequestHandler(w, r, errors.New("Insert valid format: zip or tar.gz"))
+		return
+	}
+
+	fileName := path.Base(strings.TrimSuffix(r.URL.Query().Get("prefix"), "/"))
+	if fileName == "." || fileName == "/" {
+		fileName = "files"
+	}
+	w.Header().Set("Content-Type", archive.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName + archive.Extension(format)}))
+
+	archiveWriter, err := archive.NewWriter(w, format)
+	if err != nil {
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	for _, o := range objects {
+		err := exportObjectTo(archiveWriter, bucketName, o)
+		if err != nil {
+			// The status is already sent, abort the connection so that the client doesn't get a truncated archive
+			log.Printf("archive of bucket %s aborted on %s: %v", bucketName, o.key, err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	err = archiveWriter.Close()
+	if err != nil {
+		log.Println(err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+type exportObject struct {
+	key  string
+	name string
+}
+
+// exportObjectTo function    Stream an object into the archive, objects whose name can't be extracted safely are left out
+func exportObjectTo(archiveWriter archive.Writer, bucketName string, o exportObject) error {
+	name, err := archive.SafePath(o.name)
+	if err != nil || strings.HasSuffix(o.key, "/") {
+		log.Printf("object %s left out of the archive", o.key)
+		return nil
+	}
+
+	object, objectInfo, err := services.GetObject(bucketName, o.key)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	return archiveWriter.Add(archive.Entry{Name: name, Size: objectInfo.Size, ModTime: objectModTime(objectInfo)}, object)
+}
+
+// objectModTime function    Modification time stored in the object metadata on upload, the object last modified time when missing
+func objectModTime(objectInfo minio.ObjectInfo) time.Time {
+	if modTime, err := time.Parse(time.RFC3339, objectInfo.UserMetadata[services.MetadataModTime]); err == nil {
+		return modTime
+	}
+	return objectInfo.LastModified
+}
+
+func writeImportReport(w http.ResponseWriter, status int, report dto.ImportArchiveResponse) {
+	js, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
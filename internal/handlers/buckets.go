@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/middleware"
+	"github.com/pavva91/file-upload/internal/router"
+	"github.com/pavva91/file-upload/internal/services"
+)
+
+// BucketsHandler    Admin endpoints to manage the buckets
+type BucketsHandler struct {
+	once   sync.Once
+	routes *router.Router
+}
+
+// Routes are relative to the API base path
+var (
+	BucketRe          = regexp.MustCompile(`^/buckets/*$`)
+	BucketReWithName  = regexp.MustCompile(`^/buckets/(?P<name>[^/]+)$`)
+	BucketReLifecycle = regexp.MustCompile(`^/buckets/(?P<name>[^/]+)/lifecycle$`)
+)
+
+// BucketsPaths are the paths to mount the BucketsHandler on, relative to the API base path
+var BucketsPaths = []string{"/buckets", "/buckets/"}
+
+// ListBuckets method    Name and creation date of all the buckets
+func (h *BucketsHandler) ListBuckets(w http.ResponseWriter, r *http.Request) {
+	buckets, err := services.ListBuckets()
+	if err != nil {
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	response := dto.ListBucketsResponse{Buckets: make([]dto.BucketInfo, 0, len(buckets))}
+	for _, bucket := range buckets {
+		creationDate := bucket.CreationDate
+		response.Buckets = append(response.Buckets, dto.BucketInfo{Name: bucket.Name, CreationDate: &creationDate})
+	}
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// CreateBucket method    Create the bucket of a dto.CreateBucketRequest JSON body, 409 when it already exists
+func (h *BucketsHandler) CreateBucket(w http.ResponseWriter, r *http.Request) {
+	var reqBody dto.CreateBucketRequest
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("No Request JSON Body")
+		}
+		bodyErrorHandler(w, r, err)
+		return
+	}
+
+	err = reqBody.Validate()
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	err = services.MakeBucket(reqBody.Name, services.BucketOptions{
+		Region:          reqBody.Region,
+		ObjectLocking:   reqBody.ObjectLocking,
+		Versioning:      reqBody.Versioning,
+		Encryption:      reqBody.Encryption,
+		EncryptionKeyID: reqBody.EncryptionKeyID,
+		Tags:            reqBody.Tags,
+		Lifecycle:       lifecycleRulesFromRequest(reqBody.Lifecycle),
+	})
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, services.ErrLifecycleRule) {
+			errorhandlers.BadRequestHandler(w, r, err)
+			return
+		}
+		if errors.Is(err, services.ErrBucketExists) {
+			errorhandlers.ConflictHandler(w, r, err)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	h.writeBucket(w, r, reqBody.Name, http.StatusCreated)
+}
+
+// GetBucket method    Configuration of a bucket
+func (h *BucketsHandler) GetBucket(w http.ResponseWriter, r *http.Request) {
+	h.writeBucket(w, r, router.Param(r, "name"), http.StatusOK)
+}
+
+// DeleteBucket method    Remove an empty bucket, 409 when it has objects or is a bucket of the config
+func (h *BucketsHandler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
+	err := services.RemoveBucket(router.Param(r, "name"))
+	if err != nil {
+		log.Println(err)
+		switch {
+		case services.IsNotFound(err):
+			errorhandlers.NotFoundHandler(w, r)
+		case errors.Is(err, services.ErrBucketNotEmpty), errors.Is(err, services.ErrBucketInUse):
+			errorhandlers.ConflictHandler(w, r, err)
+		default:
+			errorhandlers.InternalServerErrorHandler(w, r)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *BucketsHandler) writeBucket(w http.ResponseWriter, r *http.Request, bucketName string, status int) {
+	bucket, err := services.GetBucket(bucketName)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	js, err := json.Marshal(dto.BucketInfo{
+		Name:            bucket.Name,
+		Region:          bucket.Region,
+		ObjectLocking:   bucket.ObjectLocking,
+		Versioning:      bucket.Versioning,
+		Encryption:      bucket.Encryption,
+		EncryptionKeyID: bucket.EncryptionKeyID,
+		Tags:            bucket.Tags,
+		Lifecycle:       lifecycleRulesResponse(bucket.Lifecycle),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// ServeHTTP method    Only admins can manage the buckets
+func (h *BucketsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		h.routes = router.New()
+		h.routes.Handle(http.MethodGet, BucketRe, h.ListBuckets)
+		h.routes.Handle(http.MethodPost, BucketRe, h.CreateBucket)
+		h.routes.Handle(http.MethodGet, BucketReWithName, h.GetBucket)
+		h.routes.Handle(http.MethodDelete, BucketReWithName, h.DeleteBucket)
+		h.routes.Handle(http.MethodGet, BucketReLifecycle, h.GetBucketLifecycle)
+		h.routes.Handle(http.MethodPut, BucketReLifecycle, h.PutBucketLifecycle)
+		h.routes.Handle(http.MethodDelete, BucketReLifecycle, h.DeleteBucketLifecycle)
+	})
+
+	if !middleware.PrincipalFromRequest(r).Admin {
+		errorhandlers.ForbiddenHandler(w, r)
+		return
+	}
+	limitJSONBody(w, r)
+	h.routes.ServeHTTP(w, r)
+}
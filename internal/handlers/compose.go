@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/services"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+// ComposeFile method    Concatenate the sources of a dto.ComposeFileRequest JSON body into the {name} object, server-side
+func (h *FilesHandler) ComposeFile(w http.ResponseWriter, r *http.Request) {
+	var reqBody dto.ComposeFileRequest
+
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("No Request JSON Body")
+		}
+		bodyErrorHandler(w, r, err)
+		return
+	}
+
+	err = reqBody.Validate()
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	bucketExists, err := services.BucketExist(bucketName)
+	if err != nil {
+		log.Println(err.Error())
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	if !bucketExists {
+		msg := fmt.Sprintln("bucket", bucketName, "does not exist")
+		err := errors.New(msg)
+		log.Println(err.Error())
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	sources := make([]services.ComposeSource, len(reqBody.Sources))
+	for i, source := range reqBody.Sources {
+		sources[i] = services.ComposeSource{
+			Bucket: source.BucketName,
+			Name:   source.Name,
+			Offset: source.Offset,
+			Length: source.Length,
+		}
+	}
+
+	uploadInfo, err := services.ComposeObject(bucketName, fileName, sources, services.ComposeOptions{
+		ContentType:     reqBody.ContentType,
+		UserMetadata:    reqBody.Metadata,
+		Tags:            reqBody.Tags,
+		EncryptionKeyID: reqBody.EncryptionKeyID,
+	})
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, services.ErrPartTooSmall), errors.Is(err, services.ErrInvalidRange):
+			errorhandlers.BadRequestHandler(w, r, err)
+		default:
+			copyErrorHandler(w, r, err)
+		}
+		return
+	}
+
+	publishEvent(r, webhooks.Event{
+		Type:        webhooks.EventFileComposed,
+		Bucket:      bucketName,
+		Name:        fileName,
+		Size:        uploadInfo.Size,
+		ETag:        uploadInfo.ETag,
+		ContentType: uploadInfo.ContentType,
+	})
+
+	js, err := json.Marshal(dto.NewUploadFileResponse(uploadInfo.UploadInfo, uploadInfo.ContentType))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
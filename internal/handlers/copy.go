@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/services"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+// CopyFile method    Server-side copy of an object to the destination of a dto.CopyFileRequest JSON body
+func (h *FilesHandler) CopyFile(w http.ResponseWriter, r *http.Request) {
+	h.copyFile(w, r, false)
+}
+
+// MoveFile method    Server-side copy of an object, then remove the source
+func (h *FilesHandler) MoveFile(w http.ResponseWriter, r *http.Request) {
+	h.copyFile(w, r, true)
+}
+
+func (h *FilesHandler) copyFile(w http.ResponseWriter, r *http.Request, move bool) {
+	var reqBody dto.CopyFileRequest
+
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("No Request JSON Body")
+		}
+		bodyErrorHandler(w, r, err)
+		return
+	}
+
+	err = reqBody.Validate()
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	destinationBucket := reqBody.DestinationBucket
+	if destinationBucket == "" {
+		destinationBucket = bucketName
+	}
+
+	bucketExists, err := services.BucketExist(destinationBucket)
+	if err != nil {
+		log.Println(err.Error())
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	if !bucketExists {
+		msg := fmt.Sprintln("bucket", destinationBucket, "does not exist")
+		err := errors.New(msg)
+		log.Println(err.Error())
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	opts := services.CopyOptions{
+		DestinationBucket: destinationBucket,
+		DestinationName:   reqBody.DestinationName,
+		ReplaceMetadata:   reqBody.MetadataDirective == dto.DirectiveReplace,
+		UserMetadata:      reqBody.Metadata,
+		ReplaceTags:       reqBody.TaggingDirective == dto.DirectiveReplace,
+		Tags:              reqBody.Tags,
+		EncryptionKeyID:   reqBody.EncryptionKeyID,
+	}
+
+	copyObject, eventType := services.CopyObject, webhooks.EventFileCopied
+	if move {
+		copyObject, eventType = services.MoveObject, webhooks.EventFileMoved
+	}
+
+	uploadInfo, err := copyObject(bucketName, fileName, opts)
+	sourceRemoved := err == nil
+	if errors.Is(err, services.ErrSourceNotRemoved) {
+		// The copy is there, the move is reported as a copy keeping the source
+		log.Println(err)
+		err, eventType = nil, webhooks.EventFileCopied
+	}
+	if err != nil {
+		log.Println(err)
+		copyErrorHandler(w, r, err)
+		return
+	}
+
+	publishEvent(r, webhooks.Event{
+		Type:         eventType,
+		Bucket:       destinationBucket,
+		Name:         reqBody.DestinationName,
+		Size:         uploadInfo.Size,
+		ETag:         uploadInfo.ETag,
+		ContentType:  uploadInfo.ContentType,
+		SourceBucket: bucketName,
+		SourceName:   fileName,
+	})
+
+	uploaded := dto.NewUploadFileResponse(uploadInfo.UploadInfo, uploadInfo.ContentType)
+	var response interface{} = uploaded
+	if move {
+		response = dto.MoveFileResponse{UploadFileResponse: uploaded, SourceRemoved: sourceRemoved}
+	}
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// copyErrorHandler function    Response of a failed copy or move: 404 for a missing source, 409 when the retention or
+// legal hold forbids it, the limits of the destination bucket like on upload
+func copyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case services.IsNotFound(err):
+		errorhandlers.NotFoundHandler(w, r)
+	case errors.Is(err, services.ErrMoveOntoItself):
+		errorhandlers.BadRequestHandler(w, r, err)
+	case errors.Is(err, services.ErrObjectLocked):
+		errorhandlers.ConflictHandler(w, r, err)
+	case errors.Is(err, services.ErrTooLarge):
+		errorhandlers.PayloadTooLargeHandler(w, r, err)
+	case errors.Is(err, services.ErrContentTypeNotAllowed):
+		errorhandlers.UnsupportedMediaTypeHandler(w, r, err)
+	default:
+		errorhandlers.InternalServerErrorHandler(w, r)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+func TestMoveFile(t *testing.T) {
+	s3 := newTestStorage(t)
+
+	tests := map[string]struct {
+		source        string
+		destination   string
+		denyRemoval   bool
+		status        int
+		sourceRemoved bool
+	}{
+		"moved":              {source: "a.txt", destination: "a-moved.txt", status: http.StatusOK, sourceRemoved: true},
+		"source not removed": {source: "b.txt", destination: "b-moved.txt", denyRemoval: true, status: http.StatusOK},
+		"onto itself":        {source: "c.txt", destination: "c.txt", status: http.StatusBadRequest},
+		"missing source":     {destination: "d-moved.txt", status: http.StatusNotFound},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			source := test.source
+			if source == "" {
+				source = "missing.txt"
+			} else {
+				_, err := storage.MinioClient.PutObject(context.Background(), testBucket, source, strings.NewReader("content"), 7, minio.PutObjectOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			s3.DenyRequest = func(r *http.Request) bool {
+				return test.denyRemoval && r.Method == http.MethodDelete && r.URL.Path == "/"+testBucket+"/"+source
+			}
+
+			body := `{"destinationName":"` + test.destination + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/files/"+source+":move", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			(&FilesHandler{}).ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body.String(), test.status)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var moved dto.MoveFileResponse
+			err := json.NewDecoder(w.Body).Decode(&moved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved.ObjectName != test.destination || moved.SourceRemoved != test.sourceRemoved {
+				t.Errorf("got %+v, want %s with source removed %t", moved, test.destination, test.sourceRemoved)
+			}
+			if s3.Object(testBucket, test.destination) == nil {
+				t.Errorf("%s not copied", test.destination)
+			}
+			if kept := s3.Object(testBucket, source) != nil; kept == test.sourceRemoved {
+				t.Errorf("got source kept %t, want %t", kept, !test.sourceRemoved)
+			}
+		})
+	}
+}
@@ -9,28 +9,58 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/minio/minio-go/v7"
 	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/admission"
+	"github.com/pavva91/file-upload/internal/checksum"
 	"github.com/pavva91/file-upload/internal/dto"
 	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/processing"
+	"github.com/pavva91/file-upload/internal/router"
 	"github.com/pavva91/file-upload/internal/services"
-	"github.com/pavva91/file-upload/internal/storage"
+	"github.com/pavva91/file-upload/internal/webhooks"
 )
 
-type FilesHandler struct{}
+type FilesHandler struct {
+	once   sync.Once
+	routes *router.Router
+}
 
+// Routes are relative to the API base path, object names can contain slashes
 var (
+	FileReImport   = regexp.MustCompile(`^/files:import$`)
+	FileReArchive  = regexp.MustCompile(`^/files:archive$`)
 	FileRe         = regexp.MustCompile(`^/files/*$`)
-	FileReWithID   = regexp.MustCompile(`^/files/([a-z0-9]+(?:-[a-z0-9]+)+)$`)
-	FileReWithName = regexp.MustCompile(`^/files/.+$`)
+	FileReSearch   = regexp.MustCompile(`^/files/search$`)
+	FileReWithName = regexp.MustCompile(`^/files/(?P<name>.+)$`)
+	FileRePresign  = regexp.MustCompile(`^/files/(?P<name>.+):presign$`)
+	FileReMetadata = regexp.MustCompile(`^/files/(?P<name>.+)/metadata$`)
+	FileReTags     = regexp.MustCompile(`^/files/(?P<name>.+):tags$`)
+	FileReJobs     = regexp.MustCompile(`^/files/(?P<name>.+)/jobs$`)
+	FileReCopy     = regexp.MustCompile(`^/files/(?P<name>.+):copy$`)
+	FileReMove     = regexp.MustCompile(`^/files/(?P<name>.+):move$`)
+	FileReCompose  = regexp.MustCompile(`^/files/(?P<name>.+):compose$`)
+	FileReShares   = regexp.MustCompile(`^/files/(?P<name>.+)/shares$`)
+	FileReShare    = regexp.MustCompile(`^/files/(?P<name>.+)/shares/(?P<id>[0-9a-f]{16})$`)
+	FileReAccesses = regexp.MustCompile(`^/files/(?P<name>.+)/shares/(?P<id>[0-9a-f]{16})/accesses$`)
 )
 
+// FilesPaths are the paths to mount the FilesHandler on, relative to the API base path
+var FilesPaths = []string{"/files", "/files/", "/files:import", "/files:archive"}
+
 // UploadFileOnLocalStorage method    Simply upload a file into local storage
 // TODO: Multipart Upload https://gist.github.com/andrewmilson/19185aab2347f6ad29f5
 func (h *FilesHandler) UploadFileOnLocalStorage(w http.ResponseWriter, r *http.Request) {
+	if limit := services.MaxUploadSize(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit+maxFormOverhead)
+	}
+
 	// Parse request body as multipart form data with 32MB max memory
 	err := r.ParseMultipartForm(32 << 20)
 	if err != nil {
@@ -46,8 +76,13 @@ func (h *FilesHandler) UploadFileOnLocalStorage(w http.ResponseWriter, r *http.R
 	}
 	defer file.Close()
 
-	// Create file locally
-	filePath := fmt.Sprintf("tmp/%s", handler.Filename)
+	// Create file locally, the name must not escape the directory
+	err = dto.ValidateObjectName(handler.Filename)
+	if err != nil || strings.Contains(handler.Filename, "/") {
+		errorhandlers.BadRequestHandler(w, r, errors.New("Insert valid file name"))
+		return
+	}
+	filePath := filepath.Join("tmp", handler.Filename)
 	localFile, err := os.Create(filePath)
 	if err != nil {
 		log.Println(err)
@@ -66,41 +101,62 @@ func (h *FilesHandler) UploadFileOnLocalStorage(w http.ResponseWriter, r *http.R
 }
 
 // UploadFile method    Upload a file into minio bucket
+// The file is sent as multipart/form-data ("file" field), other content types are refused with 415
 func (h *FilesHandler) UploadFileOnMinioStorage(w http.ResponseWriter, r *http.Request) {
-	// Parse request body as multipart form data with 32MB max memory
-	err := r.ParseMultipartForm(32 << 20)
+	var reqBody dto.UploadFileRequest
+
+	// Checksums of the uploaded file content (not of the multipart/form-data body)
+	checksums, err := checksum.FromHeader(r.Header)
 	if err != nil {
-		log.Println(err)
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
 	}
 
-	// Get file uploaded via Form
-	file, handler, err := r.FormFile("file")
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		errorhandlers.UnsupportedMediaTypeHandler(w, r, errors.New("Upload the file as multipart/form-data"))
+		return
+	}
+
+	form, err := readUploadForm(w, r)
 	if err != nil {
 		log.Println(err)
-		errorhandlers.InternalServerErrorHandler(w, r)
+		bodyErrorHandler(w, r, err)
 		return
 	}
-	defer file.Close()
+	// Remove tmp file stored locally
+	defer form.Remove()
 
-	// Create file locally
-	filePath := fmt.Sprintf("tmp/%s", handler.Filename)
-	localFile, err := os.Create(filePath)
+	reqBody.BucketName = form.Values.Get("bucketName")
+	if reqBody.BucketName == "" {
+		reqBody.BucketName = config.ServerConfigValues.Minio.Bucket
+	}
+	reqBody.ObjectName = form.Values.Get("objectName")
+	if reqBody.ObjectName == "" {
+		reqBody.ObjectName = form.Filename
+	}
+	reqBody.Filepath = form.Filepath
+	reqBody.ContentType = form.ContentType
+	reqBody.Metadata = dto.MetadataFromForm(form.Values)
+	reqBody.Tags, err = dto.ParseTags(form.Values.Get(dto.FormTags))
 	if err != nil {
-		log.Println(err)
-		errorhandlers.InternalServerErrorHandler(w, r)
+		errorhandlers.BadRequestHandler(w, r, err)
 		return
 	}
-	defer localFile.Close()
 
-	// Copy the uploaded file data to the newly created file on the filesystem
-	if _, err := io.Copy(localFile, file); err != nil {
-		log.Println(err)
-		errorhandlers.InternalServerErrorHandler(w, r)
+	// Headers complete the metadata and tags of the body
+	reqBody.Metadata, reqBody.Tags, err = mergeHeaderMetadata(r, reqBody.Metadata, reqBody.Tags)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
 		return
 	}
 
-	// Update temporary file on minio
-	bucketName := "devbucket"
+	err = reqBody.Validate()
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	bucketName := reqBody.BucketName
 
 	bucketExists, err := services.BucketExist(bucketName)
 	if err != nil {
@@ -117,34 +173,86 @@ func (h *FilesHandler) UploadFileOnMinioStorage(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	services.EncryptAndUploadFileMultipart(
-		handler.Filename,
-		filePath,
-		"application/octet-stream",
+	reqBody.Tags, err = expiresAfterTags(r, bucketName, reqBody.Tags)
+	if err != nil {
+		expiresAfterErrorHandler(w, r, err)
+		return
+	}
+
+	uploadInfo, err := services.EncryptAndUploadFileMultipart(
+		reqBody.ObjectName,
+		reqBody.Filepath,
 		bucketName,
+		services.UploadOptions{
+			ContentType:  reqBody.ContentType,
+			UserMetadata: reqBody.Metadata,
+			Tags:         reqBody.Tags,
+			Checksums:    checksums,
+		},
 	)
 	if err != nil {
 		log.Println(err)
-		errorhandlers.InternalServerErrorHandler(w, r)
+		uploadErrorHandler(w, r, err)
 		return
 	}
 
-	// Remove tmp file stored locally
-	err = os.Remove(filePath)
+	publishEvent(r, webhooks.Event{
+		Type:        webhooks.EventFileUploaded,
+		Bucket:      bucketName,
+		Name:        reqBody.ObjectName,
+		Size:        uploadInfo.Size,
+		ETag:        uploadInfo.ETag,
+		ContentType: uploadInfo.ContentType,
+	})
+
+	js, err := json.Marshal(dto.NewUploadFileResponse(uploadInfo.UploadInfo, uploadInfo.ContentType))
 	if err != nil {
-		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// Seconds the clients are asked to wait when the upload queue is full
+const busyRetryAfter = 5
+
+// uploadErrorHandler function    Response of a failed upload: 400 for a checksum mismatch, 415 for a refused content type,
+// 422 for malware and 503 when the scanner is unavailable or the upload queue is full
+func uploadErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, checksum.ErrMismatch):
+		errorhandlers.BadRequestHandler(w, r, err)
+	case errors.Is(err, services.ErrTooLarge), errors.As(err, new(*http.MaxBytesError)):
+		errorhandlers.PayloadTooLargeHandler(w, r, err)
+	case errors.Is(err, services.ErrContentTypeNotAllowed):
+		errorhandlers.UnsupportedMediaTypeHandler(w, r, err)
+	case errors.Is(err, services.ErrInfected):
+		errorhandlers.UnprocessableEntityHandler(w, r, err)
+	case errors.Is(err, services.ErrScanUnavailable):
+		errorhandlers.ServiceUnavailableHandler(w, r, err)
+	case errors.Is(err, admission.ErrQueueFull), errors.Is(err, admission.ErrQueueTimeout):
+		w.Header().Set("Retry-After", strconv.Itoa(busyRetryAfter))
+		errorhandlers.ServiceUnavailableHandler(w, r, err)
+	default:
+		errorhandlers.InternalServerErrorHandler(w, r)
 	}
 }
 
 func (h *FilesHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 	var reqBody dto.DownloadFileRequest
 
-	fileName := strings.TrimPrefix(r.URL.Path, "/files/")
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
 	log.Println(fmt.Sprintf("Request download file: %s", fileName))
 
-	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
 	if err != nil {
-		if err.Error() == "EOF" {
+		if errors.Is(err, io.EOF) {
 			err = errors.New("No Request JSON Body")
 		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -179,39 +287,49 @@ func (h *FilesHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 	err = services.DownloadFile(bucket, fileName, downloadPath)
 	if err != nil {
 		log.Println(err)
-		if err.Error() == "The specified key does not exist." {
-			err = errors.New(fmt.Sprintf("Specified file %s is not present in bucket %s", fileName, bucket))
-			log.Println(err)
-			errorhandlers.BadRequestHandler(w, r, err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
 		} else {
 			errorhandlers.InternalServerErrorHandler(w, r)
 		}
 		return
 	}
 
+	publishEvent(r, webhooks.Event{Type: webhooks.EventFileDownloaded, Bucket: bucket, Name: fileName})
+
 	msg := fmt.Sprintf("File %s correctly downloaded in: %s", fileName, downloadPath)
 	log.Println(msg)
 	w.Write([]byte(msg))
 }
 
 func (h *FilesHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
-	var objects []minio.ObjectInfo
 	ctx, cancel := context.WithCancel(context.Background())
 
 	defer cancel()
 
-	bucketName := config.ServerConfigValues.Minio.Bucket
-	objectCh := storage.MinioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
-		Prefix:    "",
-		Recursive: true,
-	})
-	for o := range objectCh {
-		if o.Err != nil {
-			fmt.Println(o.Err)
+	bucketName := bucketFromRequest(r)
+	prefix := r.URL.Query().Get("prefix")
+
+	tagFilters, err := dto.ParseTagFilters(r.URL.Query()["tag"])
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	objectInfos, err := services.ListObjects(ctx, bucketName, prefix, tagFilters)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
 			return
 		}
-		fmt.Println(o)
-		objects = append(objects, o)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	objects := []dto.FileInfo{}
+	for _, o := range objectInfos {
+		objects = append(objects, dto.NewFileInfo(o))
 	}
 
 	js, err := json.Marshal(objects)
@@ -222,21 +340,316 @@ func (h *FilesHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(js)
+}
+
+// GetFile method    Stream an object, Range requests are supported to resume downloads
+// Requests with a dto.DownloadFileRequest JSON body keep downloading the object into a path on the server
+func (h *FilesHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	if r.ContentLength != 0 {
+		h.DownloadFile(w, r)
+		return
+	}
+
+	fileName, err := objectNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	object, objectInfo, err := services.GetObject(bucketName, fileName)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+	defer object.Close()
+
+	w.Header().Set("Content-Type", objectInfo.ContentType)
+	w.Header().Set("ETag", fmt.Sprintf("%q", objectInfo.ETag))
+	checksum.FromMetadata(objectInfo.UserMetadata).SetHeaders(w.Header())
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	http.ServeContent(rec, r, fileName, objectInfo.LastModified, object)
+
+	// Not modified and unsatisfiable range responses are not downloads
+	if rec.status == http.StatusOK || rec.status == http.StatusPartialContent {
+		publishEvent(r, webhooks.Event{
+			Type:        webhooks.EventFileDownloaded,
+			Bucket:      bucketName,
+			Name:        fileName,
+			Size:        objectInfo.Size,
+			ETag:        objectInfo.ETag,
+			ContentType: objectInfo.ContentType,
+		})
+	}
+}
+
+// HeadFile method    Object properties as headers, without downloading the content
+func (h *FilesHandler) HeadFile(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := statFile(w, r)
+	if !ok {
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Length", strconv.FormatInt(metadata.Size, 10))
+	header.Set("Content-Type", metadata.ContentType)
+	header.Set("ETag", fmt.Sprintf("%q", metadata.ETag))
+	header.Set("Last-Modified", metadata.LastModified.UTC().Format(http.TimeFormat))
+	header.Set("Accept-Ranges", "bytes")
+	for name, value := range map[string]string{
+		"X-Version-Id":        metadata.VersionID,
+		"X-Encryption":        metadata.Encryption,
+		"X-Encryption-Key-Id": metadata.EncryptionKeyID,
+		"X-Retention-Mode":    metadata.RetentionMode,
+		"X-Legal-Hold":        metadata.LegalHold,
+		"X-Checksum-Md5":      metadata.Checksums.MD5,
+		"X-Checksum-Sha256":   metadata.Checksums.SHA256,
+		"X-Checksum-Crc32c":   metadata.Checksums.CRC32C,
+	} {
+		if value != "" {
+			header.Set(name, value)
+		}
+	}
+	if metadata.RetainUntil != nil {
+		header.Set("X-Retain-Until", metadata.RetainUntil.UTC().Format(time.RFC3339))
+	}
+	for k, v := range metadata.UserMetadata {
+		header.Set("X-Meta-"+k, v)
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *FilesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	switch {
-	case r.Method == http.MethodPost && FileRe.MatchString(r.URL.Path):
-		h.UploadFileOnMinioStorage(w, r)
+// GetFileMetadata method    Object properties as dto.FileMetadata JSON
+func (h *FilesHandler) GetFileMetadata(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := statFile(w, r)
+	if !ok {
 		return
-	case r.Method == http.MethodGet && FileRe.MatchString(r.URL.Path):
-		h.ListFiles(w, r)
+	}
+
+	js, err := json.Marshal(metadata)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
-	case r.Method == http.MethodGet && FileReWithName.MatchString(r.URL.Path):
-		h.DownloadFile(w, r)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// statFile function    Metadata of the {name} object, the error response is written when false is returned
+func statFile(w http.ResponseWriter, r *http.Request) (dto.FileMetadata, bool) {
+	fileName, err := objectNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return dto.FileMetadata{}, false
+	}
+	bucketName := bucketFromRequest(r)
+
+	objectInfo, err := services.StatObject(bucketName, fileName)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return dto.FileMetadata{}, false
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return dto.FileMetadata{}, false
+	}
+
+	return dto.NewFileMetadata(bucketName, objectInfo, services.UserMetadata(objectInfo)), true
+}
+
+func (h *FilesHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
 		return
-	default:
+	}
+	bucketName := bucketFromRequest(r)
+
+	err = services.RemoveObject(fileName, bucketName)
+	if err != nil {
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	publishEvent(r, webhooks.Event{Type: webhooks.EventFileDeleted, Bucket: bucketName, Name: fileName})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PresignFile method    Return a presigned MinIO url to download an object, valid for ?expires= seconds (default 1 hour)
+func (h *FilesHandler) PresignFile(w http.ResponseWriter, r *http.Request) {
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	expires := time.Hour
+	if expiresParam := r.URL.Query().Get("expires"); expiresParam != "" {
+		seconds, err := strconv.Atoi(expiresParam)
+		if err != nil || seconds < 1 || seconds > 7*24*60*60 {
+			errorhandlers.BadRequestHandler(w, r, errors.New("Insert valid expires in seconds (max 7 days)"))
+			return
+		}
+		expires = time.Duration(seconds) * time.Second
+	}
+
+	_, err = services.StatObject(bucketName, fileName)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	presignedURL, err := services.PresignedGetObject(bucketName, fileName, expires)
+	if err != nil {
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	js, err := json.Marshal(dto.PresignFileResponse{
+		URL:       presignedURL.String(),
+		ExpiresAt: time.Now().Add(expires).UTC(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// mergeHeaderMetadata function    Add the X-Meta-* headers to metadata and use the X-Tags header when tags is empty
+func mergeHeaderMetadata(r *http.Request, metadata map[string]string, tags map[string]string) (map[string]string, map[string]string, error) {
+	merged := dto.MetadataFromHeader(r.Header)
+	for k, v := range metadata {
+		merged[http.CanonicalHeaderKey(k)] = v
+	}
+	if len(merged) == 0 {
+		merged = nil
+	}
+
+	if len(tags) == 0 {
+		var err error
+		tags, err = dto.ParseTags(r.Header.Get(dto.HeaderTags))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return merged, tags, nil
+}
+
+// expiresAfterTags function    Add the expires-after tag of the X-Expires-After header to tags, the objects then expire
+// with the matching lifecycle rule of the bucket
+func expiresAfterTags(r *http.Request, bucketName string, tags map[string]string) (map[string]string, error) {
+	header := r.Header.Get(dto.HeaderExpiresAfter)
+	if header == "" {
+		return tags, nil
+	}
+
+	days, err := services.ExpiresAfterTag(bucketName, header)
+	if err != nil {
+		return nil, err
+	}
+	withTag := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		withTag[k] = v
+	}
+	withTag[services.TagExpiresAfter] = days
+	return withTag, dto.ValidateTags(withTag)
+}
+
+// expiresAfterErrorHandler function    Response of an X-Expires-After header that can't be applied: 400 for invalid
+// days or too many tags, 500 when the lifecycle rules of the bucket can't be set
+func expiresAfterErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	log.Println(err)
+	if errors.Is(err, services.ErrExpiresAfter) || errors.Is(err, dto.ErrInvalidTags) {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	errorhandlers.InternalServerErrorHandler(w, r)
+}
+
+// fileNameFromRequest function    Object of the {name} parameter, refused like on upload when it isn't a valid object
+// name, e.g. a blob of the dedup prefix
+func fileNameFromRequest(r *http.Request) (string, error) {
+	fileName := router.Param(r, "name")
+	err := dto.ValidateObjectName(fileName)
+	if err != nil {
+		return "", err
+	}
+	return fileName, nil
+}
+
+// objectNameFromRequest function    Object of the {name} parameter, or its ?variant= derived by the processors (e.g. thumb-256)
+func objectNameFromRequest(r *http.Request) (string, error) {
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		return "", err
+	}
+	variant := r.URL.Query().Get("variant")
+	if variant == "" {
+		return fileName, nil
+	}
+	if !processing.ValidVariant(variant) {
+		return "", errors.New("Insert valid variant, e.g. thumb-256")
+	}
+	return processing.VariantName(fileName, variant), nil
+}
+
+// bucketFromRequest function    Bucket of the ?bucketName= query parameter, the configured bucket when missing
+func bucketFromRequest(r *http.Request) string {
+	bucketName := r.URL.Query().Get("bucketName")
+	if bucketName == "" {
+		bucketName = config.ServerConfigValues.Minio.Bucket
+	}
+	return bucketName
+}
+
+func (h *FilesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		h.routes = router.New()
+		h.routes.Handle(http.MethodPost, FileRe, h.UploadFileOnMinioStorage)
+		h.routes.Handle(http.MethodGet, FileRe, h.ListFiles)
+		h.routes.Handle(http.MethodGet, FileReSearch, h.SearchFiles)
+		h.routes.Handle(http.MethodPost, FileReImport, h.ImportArchive)
+		h.routes.Handle(http.MethodGet, FileReArchive, h.ExportArchive)
+		h.routes.Handle(http.MethodPost, FileReArchive, h.ExportArchive)
+		h.routes.Handle(http.MethodGet, FileRePresign, h.PresignFile)
+		h.routes.Handle(http.MethodGet, FileReMetadata, h.GetFileMetadata)
+		h.routes.Handle(http.MethodGet, FileReJobs, h.GetFileJobs)
+		h.routes.Handle(http.MethodGet, FileReTags, h.GetFileTags)
+		h.routes.Handle(http.MethodPut, FileReTags, h.PutFileTags)
+		h.routes.Handle(http.MethodDelete, FileReTags, h.DeleteFileTags)
+		h.routes.Handle(http.MethodPost, FileReCopy, h.CopyFile)
+		h.routes.Handle(http.MethodPost, FileReMove, h.MoveFile)
+		h.routes.Handle(http.MethodPost, FileReCompose, h.ComposeFile)
+		h.routes.Handle(http.MethodPost, FileReShares, h.CreateShare)
+		h.routes.Handle(http.MethodGet, FileReShares, h.ListShares)
+		h.routes.Handle(http.MethodDelete, FileReShare, h.RevokeShare)
+		h.routes.Handle(http.MethodGet, FileReAccesses, h.GetShareAccesses)
+		h.routes.Handle(http.MethodHead, FileReWithName, h.HeadFile)
+		h.routes.Handle(http.MethodGet, FileReWithName, h.GetFile)
+		h.routes.Handle(http.MethodDelete, FileReWithName, h.DeleteFile)
+	})
+	limitJSONBody(w, r)
+	h.routes.ServeHTTP(w, r)
 }
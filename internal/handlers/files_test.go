@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/storage"
+	"github.com/pavva91/file-upload/internal/testutil/fakes3"
+)
+
+const testBucket = "testbucket"
+
+// newTestStorage function    In-memory S3 with testBucket as the default bucket
+func newTestStorage(t *testing.T) *fakes3.Server {
+	s3 := fakes3.New()
+	t.Cleanup(s3.Close)
+
+	minioClient, err := s3.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MinioClient = minioClient
+	err = minioClient.MakeBucket(context.Background(), testBucket, minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ServerConfigValues.Minio.Bucket = testBucket
+	return s3
+}
+
+// testUploadForm function    multipart/form-data body with the given fields and a "file" part
+func testUploadForm(t *testing.T, fields map[string]string, content string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	for k, v := range fields {
+		if err := form.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := form.CreateFormFile("file", "upload.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, form.FormDataContentType()
+}
+
+func TestUploadFileOnMinioStorage(t *testing.T) {
+	s3 := newTestStorage(t)
+
+	formBody, formType := testUploadForm(t, map[string]string{"objectName": "dir/hello.txt"}, "hello world")
+
+	tests := map[string]struct {
+		body        string
+		contentType string
+		status      int
+		stored      string
+	}{
+		"multipart form": {
+			body:        formBody.String(),
+			contentType: formType,
+			status:      http.StatusOK,
+			stored:      "dir/hello.txt",
+		},
+		"JSON body with a server path": {
+			body:        `{"bucketName":"testbucket","objectName":"passwd","filepath":"/etc/passwd"}`,
+			contentType: "application/json",
+			status:      http.StatusUnsupportedMediaType,
+		},
+		"raw body": {
+			body:        "hello world",
+			contentType: "text/plain",
+			status:      http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", test.contentType)
+			w := httptest.NewRecorder()
+			(&FilesHandler{}).ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("got %d %s, want %d", w.Code, w.Body.String(), test.status)
+			}
+			if test.stored != "" && s3.Object(testBucket, test.stored) == nil {
+				t.Errorf("object %s not stored", test.stored)
+			}
+		})
+	}
+
+	if s3.Object(testBucket, "passwd") != nil {
+		t.Error("server file uploaded from a JSON body")
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	newTestStorage(t)
+
+	_, err := storage.MinioClient.PutObject(context.Background(), testBucket, "dir/report.txt", strings.NewReader("report"), 6, minio.PutObjectOptions{
+		ContentType:  "text/plain",
+		UserMetadata: map[string]string{"Owner": "ops"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		method string
+		path   string
+		status int
+	}{
+		"head":             {method: http.MethodHead, path: "/files/dir/report.txt", status: http.StatusOK},
+		"metadata":         {method: http.MethodGet, path: "/files/dir/report.txt/metadata", status: http.StatusOK},
+		"head missing":     {method: http.MethodHead, path: "/files/dir/missing.txt", status: http.StatusNotFound},
+		"metadata missing": {method: http.MethodGet, path: "/files/dir/missing.txt/metadata", status: http.StatusNotFound},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, nil)
+			w := httptest.NewRecorder()
+			(&FilesHandler{}).ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body.String(), test.status)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			metadata := dto.FileMetadata{
+				Size:         w.Result().ContentLength,
+				ContentType:  w.Header().Get("Content-Type"),
+				UserMetadata: map[string]string{"Owner": w.Header().Get("X-Meta-Owner")},
+			}
+			if test.method == http.MethodGet {
+				err := json.NewDecoder(w.Body).Decode(&metadata)
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if w.Body.Len() != 0 {
+				t.Errorf("got a body of %d bytes, want none", w.Body.Len())
+			}
+			if metadata.Size != 6 || metadata.ContentType != "text/plain" || metadata.UserMetadata["Owner"] != "ops" {
+				t.Errorf("got %+v, want the properties of dir/report.txt", metadata)
+			}
+		})
+	}
+}
+
+func TestReservedObjectNames(t *testing.T) {
+	s3 := newTestStorage(t)
+
+	blob := dto.DedupPrefix() + "blobs/sha256/0123456789abcdef"
+	_, err := storage.MinioClient.PutObject(context.Background(), testBucket, blob, strings.NewReader("shared"), 6, minio.PutObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		method string
+		path   string
+		body   string
+	}{
+		"get":            {method: http.MethodGet, path: "/files/" + blob},
+		"head":           {method: http.MethodHead, path: "/files/" + blob},
+		"metadata":       {method: http.MethodGet, path: "/files/" + blob + "/metadata"},
+		"delete":         {method: http.MethodDelete, path: "/files/" + blob},
+		"get tags":       {method: http.MethodGet, path: "/files/" + blob + ":tags"},
+		"put tags":       {method: http.MethodPut, path: "/files/" + blob + ":tags", body: `{"tags":{"a":"b"}}`},
+		"delete tags":    {method: http.MethodDelete, path: "/files/" + blob + ":tags"},
+		"presign":        {method: http.MethodGet, path: "/files/" + blob + ":presign"},
+		"copy source":    {method: http.MethodPost, path: "/files/" + blob + ":copy", body: `{"destinationName":"stolen.bin"}`},
+		"move source":    {method: http.MethodPost, path: "/files/" + blob + ":move", body: `{"destinationName":"stolen.bin"}`},
+		"copy target":    {method: http.MethodPost, path: "/files/a.txt:copy", body: `{"destinationName":"` + blob + `"}`},
+		"compose":        {method: http.MethodPost, path: "/files/" + blob + ":compose", body: `{"sources":[{"name":"a.txt"}]}`},
+		"compose part":   {method: http.MethodPost, path: "/files/b.txt:compose", body: `{"sources":[{"name":"` + blob + `"}]}`},
+		"share":          {method: http.MethodPost, path: "/files/" + blob + "/shares"},
+		"archive key":    {method: http.MethodPost, path: "/files:archive", body: `{"keys":["` + blob + `"]}`},
+		"get variant":    {method: http.MethodGet, path: "/files/" + dto.VariantPrefix + "photo.png.thumb-32"},
+		"delete variant": {method: http.MethodDelete, path: "/files/" + dto.VariantPrefix + "photo.png.thumb-32"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			(&FilesHandler{}).ServeHTTP(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("got %d %s, want %d", w.Code, w.Body.String(), http.StatusBadRequest)
+			}
+		})
+	}
+
+	if object := s3.Object(testBucket, blob); object == nil || string(object.Data) != "shared" {
+		t.Error("blob changed through the API")
+	}
+	if s3.Object(testBucket, "stolen.bin") != nil {
+		t.Error("blob copied through the API")
+	}
+
+	for _, path := range []string{"/files", "/files?prefix=" + dto.DedupPrefix()} {
+		w := httptest.NewRecorder()
+		(&FilesHandler{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "blobs/sha256") {
+			t.Errorf("%s lists the blob: %s", path, w.Body.String())
+		}
+	}
+}
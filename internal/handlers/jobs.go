@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/processing"
+	"github.com/pavva91/file-upload/internal/services"
+)
+
+// GetFileJobs method    Status of the processing jobs of an object, the jobs of removed objects are kept
+func (h *FilesHandler) GetFileJobs(w http.ResponseWriter, r *http.Request) {
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	jobs, err := services.FileJobs(r.Context(), bucketName, fileName)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, services.ErrProcessingDisabled) {
+			errorhandlers.ServiceUnavailableHandler(w, r, err)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	js, err := json.Marshal(dto.FileJobsResponse{BucketName: bucketName, Name: fileName, Jobs: newProcessingJobs(jobs)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// newProcessingJobs function    Jobs as sent to the clients
+func newProcessingJobs(jobs []processing.Job) []dto.ProcessingJob {
+	result := make([]dto.ProcessingJob, 0, len(jobs))
+	for _, job := range jobs {
+		processingJob := dto.ProcessingJob{
+			ID:          job.ID,
+			Bucket:      job.Bucket,
+			Name:        job.Name,
+			ETag:        job.ETag,
+			Processor:   job.Processor,
+			Status:      job.Status,
+			Attempts:    job.Attempts,
+			Error:       job.Error,
+			CreatedAt:   job.CreatedAt,
+			UpdatedAt:   job.UpdatedAt,
+			NextAttempt: job.NextAttempt,
+		}
+		if job.Result != nil {
+			processingJob.Result = &dto.ProcessingResult{Outputs: job.Result.Outputs, Metadata: job.Result.Metadata}
+		}
+		result = append(result, processingJob)
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/router"
+	"github.com/pavva91/file-upload/internal/services"
+)
+
+// GetBucketLifecycle method    Lifecycle rules of a bucket as dto.BucketLifecycle JSON
+func (h *BucketsHandler) GetBucketLifecycle(w http.ResponseWriter, r *http.Request) {
+	rules, err := services.GetLifecycle(router.Param(r, "name"))
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeBucketLifecycle(w, rules)
+}
+
+// PutBucketLifecycle method    Replace the lifecycle rules of a bucket with the ones of a dto.BucketLifecycle JSON body
+func (h *BucketsHandler) PutBucketLifecycle(w http.ResponseWriter, r *http.Request) {
+	var reqBody dto.BucketLifecycle
+
+	bucketName := router.Param(r, "name")
+
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("No Request JSON Body")
+		}
+		bodyErrorHandler(w, r, err)
+		return
+	}
+
+	err = reqBody.Validate()
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	err = services.PutLifecycle(bucketName, lifecycleRulesFromRequest(reqBody.Rules))
+	if err == nil {
+		h.GetBucketLifecycle(w, r)
+		return
+	}
+	log.Println(err)
+	if errors.Is(err, services.ErrLifecycleRule) {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	if services.IsNotFound(err) {
+		errorhandlers.NotFoundHandler(w, r)
+		return
+	}
+	errorhandlers.InternalServerErrorHandler(w, r)
+}
+
+// DeleteBucketLifecycle method    Remove the lifecycle rules of a bucket, but the expires-after ones of the config
+func (h *BucketsHandler) DeleteBucketLifecycle(w http.ResponseWriter, r *http.Request) {
+	err := services.PutLifecycle(router.Param(r, "name"), nil)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeBucketLifecycle(w http.ResponseWriter, rules []services.LifecycleRule) {
+	js, err := json.Marshal(dto.BucketLifecycle{Rules: lifecycleRulesResponse(rules)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+func lifecycleRulesFromRequest(rules []dto.LifecycleRule) []services.LifecycleRule {
+	serviceRules := make([]services.LifecycleRule, len(rules))
+	for i, rule := range rules {
+		serviceRules[i] = services.LifecycleRule(rule)
+	}
+	return serviceRules
+}
+
+func lifecycleRulesResponse(rules []services.LifecycleRule) []dto.LifecycleRule {
+	dtoRules := make([]dto.LifecycleRule, len(rules))
+	for i, rule := range rules {
+		dtoRules[i] = dto.LifecycleRule(rule)
+	}
+	return dtoRules
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/services"
+)
+
+const (
+	defaultMaxFormParts = 64
+	// Form fields are metadata, tags and names, far below this
+	maxFormFieldSize = 64 * 1024
+	// Room of the multipart/form-data framing and fields around the file
+	maxFormOverhead = 1024 * 1024
+	// JSON bodies: the key list of POST /files:archive is the largest (10000 keys)
+	maxJSONBodySize = 16 * 1024 * 1024
+)
+
+var errTooManyParts = errors.New("too many form parts")
+
+// uploadForm    Fields and file of a multipart/form-data upload
+type uploadForm struct {
+	Values url.Values
+	// Filename and ContentType of the file part, its content is in the temporary file at Filepath
+	Filename    string
+	ContentType string
+	Filepath    string
+}
+
+// Remove method    Delete the temporary file of the upload
+func (f *uploadForm) Remove() {
+	if f.Filepath == "" {
+		return
+	}
+	err := os.Remove(f.Filepath)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// readUploadForm function    Read the form one part at a time, the "file" part goes straight to a temporary file
+// instead of being buffered by ParseMultipartForm. The body is bounded by the largest object size of the buckets,
+// the file by the limit of its bucket when the "bucketName" field comes before it, the limit is checked again once the bucket is known.
+func readUploadForm(w http.ResponseWriter, r *http.Request) (*uploadForm, error) {
+	if limit := services.MaxUploadSize(); limit > 0 {
+		if r.ContentLength > limit+maxFormOverhead {
+			return nil, fmt.Errorf("%w: the limit is %d bytes", services.ErrTooLarge, limit)
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit+maxFormOverhead)
+	}
+	maxParts := config.ServerConfigValues.Limits.MaxFormParts
+	if maxParts <= 0 {
+		maxParts = defaultMaxFormParts
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	form := &uploadForm{Values: url.Values{}}
+	for parts := 1; ; parts++ {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			form.Remove()
+			return nil, err
+		}
+		if parts > maxParts {
+			form.Remove()
+			return nil, fmt.Errorf("%w: the limit is %d", errTooManyParts, maxParts)
+		}
+
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+			if err != nil {
+				form.Remove()
+				return nil, err
+			}
+			if len(value) > maxFormFieldSize {
+				form.Remove()
+				return nil, fmt.Errorf("form field %s longer than %d bytes", part.FormName(), maxFormFieldSize)
+			}
+			form.Values.Add(part.FormName(), string(value))
+			continue
+		}
+		if form.Filepath != "" {
+			form.Remove()
+			return nil, errors.New("one file per upload")
+		}
+
+		// A unique name, so that concurrent uploads of files with the same name don't collide
+		localFile, err := os.CreateTemp("", "file-upload-*")
+		if err != nil {
+			form.Remove()
+			return nil, err
+		}
+		form.Filepath = localFile.Name()
+		form.Filename = part.FileName()
+		form.ContentType = part.Header.Get("Content-Type")
+		err = copyFilePart(localFile, part, form.Values.Get("bucketName"))
+		localFile.Close()
+		if err != nil {
+			form.Remove()
+			return nil, err
+		}
+	}
+
+	if form.Filepath == "" {
+		return nil, http.ErrMissingFile
+	}
+	return form, nil
+}
+
+// copyFilePart function    Copy the file part, failing with ErrTooLarge past the limit of the bucket when it is already known
+func copyFilePart(dst io.Writer, part io.Reader, bucketName string) error {
+	limit := services.MaxUploadSize()
+	if bucketName != "" {
+		limit = services.MaxObjectSize(bucketName)
+	}
+	if limit <= 0 {
+		_, err := io.Copy(dst, part)
+		return err
+	}
+	// One byte more than the limit tells a file of the exact limit from a larger one
+	n, err := io.Copy(dst, io.LimitReader(part, limit+1))
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("%w: the limit of bucket %s is %d bytes", services.ErrTooLarge, bucketName, limit)
+	}
+	return nil
+}
+
+// limitJSONBody function    Bound the JSON request bodies, the uploads are bounded by their own limits
+func limitJSONBody(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxJSONBodySize)
+	}
+}
+
+// bodyErrorHandler function    Response of an unreadable request body: 413 above the limits, 400 otherwise
+func bodyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, services.ErrTooLarge) || errors.Is(err, errTooManyParts) {
+		errorhandlers.PayloadTooLargeHandler(w, r, err)
+		return
+	}
+	errorhandlers.BadRequestHandler(w, r, err)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+func TestReadUploadForm(t *testing.T) {
+	config.ServerConfigValues.Limits.MaxObjectSize = 1
+	config.ServerConfigValues.Limits.MaxFormParts = 3
+	t.Cleanup(func() {
+		config.ServerConfigValues.Limits.MaxObjectSize = 0
+		config.ServerConfigValues.Limits.MaxFormParts = 0
+	})
+
+	file := func(form *multipart.Writer, content string) {
+		part, err := form.CreateFormFile("file", "upload.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+
+	tests := map[string]struct {
+		parts  func(form *multipart.Writer)
+		status int
+	}{
+		"file and fields": {
+			parts: func(form *multipart.Writer) {
+				form.WriteField("objectName", "report.txt")
+				file(form, "report")
+			},
+			status: http.StatusOK,
+		},
+		"too many parts": {
+			parts: func(form *multipart.Writer) {
+				for _, field := range []string{"a", "b", "c"} {
+					form.WriteField(field, "value")
+				}
+				file(form, "report")
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		"field too long": {
+			parts: func(form *multipart.Writer) {
+				form.WriteField("objectName", strings.Repeat("x", maxFormFieldSize+1))
+				file(form, "report")
+			},
+			status: http.StatusBadRequest,
+		},
+		"two files": {
+			parts: func(form *multipart.Writer) {
+				file(form, "report")
+				file(form, "other")
+			},
+			status: http.StatusBadRequest,
+		},
+		"no file": {
+			parts: func(form *multipart.Writer) {
+				form.WriteField("objectName", "report.txt")
+			},
+			status: http.StatusBadRequest,
+		},
+		"file above the limit": {
+			parts: func(form *multipart.Writer) {
+				file(form, strings.Repeat("x", 1024*1024+maxFormOverhead+1))
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			form := multipart.NewWriter(body)
+			test.parts(form)
+			form.Close()
+
+			r := httptest.NewRequest(http.MethodPost, "/files", body)
+			r.Header.Set("Content-Type", form.FormDataContentType())
+			w := httptest.NewRecorder()
+
+			uploaded, err := readUploadForm(w, r)
+			if err != nil {
+				bodyErrorHandler(w, r, err)
+				if w.Code != test.status {
+					t.Errorf("got %d (%v), want %d", w.Code, err, test.status)
+				}
+				return
+			}
+			defer uploaded.Remove()
+			if test.status != http.StatusOK {
+				t.Fatalf("got the form, want %d", test.status)
+			}
+
+			content, err := os.ReadFile(uploaded.Filepath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "report" || uploaded.Filename != "upload.txt" || uploaded.Values.Get("objectName") != "report.txt" {
+				t.Errorf("got %+v with %q, want the fields and file of the form", uploaded, content)
+			}
+		})
+	}
+}
+
+func TestReadUploadFormBucketLimit(t *testing.T) {
+	const MiB = 1024 * 1024
+
+	config.ServerConfigValues.Limits.MaxObjectSize = 1
+	config.ServerConfigValues.Limits.Buckets = []config.BucketLimits{{Bucket: "large", MaxObjectSize: 3}}
+	t.Cleanup(func() {
+		config.ServerConfigValues.Limits.MaxObjectSize = 0
+		config.ServerConfigValues.Limits.Buckets = nil
+	})
+
+	tests := map[string]struct {
+		bucketName string
+		size       int
+		status     int
+	}{
+		"within the bucket limit":      {bucketName: "large", size: 2 * MiB, status: http.StatusOK},
+		"above the bucket limit":       {bucketName: "small", size: MiB + 1, status: http.StatusRequestEntityTooLarge},
+		"exactly the bucket limit":     {bucketName: "small", size: MiB, status: http.StatusOK},
+		"bucket unknown until the end": {size: 2 * MiB, status: http.StatusOK},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			form := multipart.NewWriter(body)
+			if test.bucketName != "" {
+				form.WriteField("bucketName", test.bucketName)
+			}
+			part, err := form.CreateFormFile("file", "upload.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write(bytes.Repeat([]byte("x"), test.size))
+			form.Close()
+
+			r := httptest.NewRequest(http.MethodPost, "/files", body)
+			r.Header.Set("Content-Type", form.FormDataContentType())
+			w := httptest.NewRecorder()
+
+			uploaded, err := readUploadForm(w, r)
+			if err != nil {
+				bodyErrorHandler(w, r, err)
+				if w.Code != test.status {
+					t.Errorf("got %d (%v), want %d", w.Code, err, test.status)
+				}
+				return
+			}
+			defer uploaded.Remove()
+			if test.status != http.StatusOK {
+				t.Fatalf("got the form, want %d", test.status)
+			}
+		})
+	}
+}
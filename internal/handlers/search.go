@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/index"
+	"github.com/pavva91/file-upload/internal/services"
+)
+
+const (
+	defaultSearchLimit = 1000
+	maxSearchLimit     = 10000
+)
+
+// SearchFiles method    Search the metadata index, a dto.SearchFilesResponse page of the matching objects.
+// The index follows the uploads and deletes of the service, changes made directly in MinIO show up after the next full scan.
+func (h *FilesHandler) SearchFiles(w http.ResponseWriter, r *http.Request) {
+	bucketName := bucketFromRequest(r)
+
+	query, err := newSearchQuery(bucketName, r.URL.Query())
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	objects, total, err := services.SearchObjects(r.Context(), query)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, services.ErrIndexDisabled) {
+			errorhandlers.ServiceUnavailableHandler(w, r, err)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	response := dto.SearchFilesResponse{
+		BucketName: bucketName,
+		Total:      total,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+		Files:      make([]dto.FileInfo, 0, len(objects)),
+	}
+	for _, o := range objects {
+		response.Files = append(response.Files, newIndexedFileInfo(o))
+	}
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// newSearchQuery function    Index query of the GET /files/search query parameters
+func newSearchQuery(bucketName string, query url.Values) (index.Query, error) {
+	q := index.Query{
+		Bucket:      bucketName,
+		Prefix:      query.Get("prefix"),
+		Glob:        query.Get("name"),
+		ContentType: query.Get("contentType"),
+		Sort:        query.Get("sort"),
+		Limit:       defaultSearchLimit,
+	}
+
+	var err error
+	for param, size := range map[string]**int64{"minSize": &q.MinSize, "maxSize": &q.MaxSize} {
+		if value := query.Get(param); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return index.Query{}, fmt.Errorf("Insert valid %s in bytes", param)
+			}
+			*size = &n
+		}
+	}
+	for param, date := range map[string]*time.Time{"modifiedAfter": &q.ModifiedAfter, "modifiedBefore": &q.ModifiedBefore} {
+		if value := query.Get(param); value != "" {
+			*date, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				return index.Query{}, fmt.Errorf("Insert valid %s (RFC 3339, e.g. 2024-03-01T00:00:00Z)", param)
+			}
+		}
+	}
+
+	q.Tags, err = dto.ParseTagFilters(query["tag"])
+	if err != nil {
+		return index.Query{}, err
+	}
+	q.Metadata, err = dto.ParseTagFilters(query["meta"])
+	if err != nil {
+		return index.Query{}, errors.New("Insert valid metadata filter: key:value")
+	}
+	// Metadata keys are stored canonicalized
+	q.Metadata, err = dto.CanonicalMetadata(q.Metadata)
+	if err != nil {
+		return index.Query{}, err
+	}
+
+	switch q.Sort {
+	case "", index.SortName, index.SortSize, index.SortLastModified:
+	default:
+		return index.Query{}, errors.New("Insert valid sort: name, size or lastModified")
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return index.Query{}, errors.New("Insert valid order: asc or desc")
+	}
+
+	if value := query.Get("limit"); value != "" {
+		q.Limit, err = strconv.Atoi(value)
+		if err != nil || q.Limit < 1 || q.Limit > maxSearchLimit {
+			return index.Query{}, fmt.Errorf("Insert valid limit (1 to %d)", maxSearchLimit)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		q.Offset, err = strconv.Atoi(value)
+		if err != nil || q.Offset < 0 {
+			return index.Query{}, errors.New("Insert valid offset")
+		}
+	}
+	return q, nil
+}
+
+// newIndexedFileInfo function    Indexed object as listed by the search
+func newIndexedFileInfo(o index.Object) dto.FileInfo {
+	return dto.FileInfo{
+		Name:         o.Name,
+		Size:         o.Size,
+		ETag:         o.ETag,
+		ContentType:  o.ContentType,
+		LastModified: o.LastModified,
+		Tags:         o.Tags,
+		UserMetadata: o.Metadata,
+	}
+}
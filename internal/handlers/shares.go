@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/index"
+	"github.com/pavva91/file-upload/internal/middleware"
+	"github.com/pavva91/file-upload/internal/router"
+	"github.com/pavva91/file-upload/internal/services"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+// SharesHandler    Public downloads of the share links, authenticated by their token instead of an API key
+type SharesHandler struct {
+	once   sync.Once
+	routes *router.Router
+}
+
+// Routes are relative to the API base path
+var ShareReWithToken = regexp.MustCompile(`^/shares/(?P<token>[A-Za-z0-9_-]+)$`)
+
+// SharesPaths are the paths to mount the SharesHandler on, relative to the API base path
+var SharesPaths = []string{"/shares/"}
+
+// CreateShare method    Create a share link of an object with the restrictions of a dto.CreateShareRequest JSON body
+func (h *FilesHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	var reqBody dto.CreateShareRequest
+
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	// An empty body creates a link with the defaults
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil && !errors.Is(err, io.EOF) {
+		bodyErrorHandler(w, r, err)
+		return
+	}
+
+	err = reqBody.Validate()
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	share, token, err := services.CreateShare(r.Context(), bucketName, fileName, services.ShareOptions{
+		Expires:      time.Duration(reqBody.ExpiresIn) * time.Second,
+		Password:     reqBody.Password,
+		MaxDownloads: reqBody.MaxDownloads,
+		CreatedBy:    middleware.PrincipalFromRequest(r).Name,
+	})
+	if err != nil {
+		shareErrorHandler(w, r, err)
+		return
+	}
+
+	response := newShareInfo(share)
+	response.Token = token
+	response.URL = shareURL(token)
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(js)
+}
+
+// ListShares method    Share links of an object as dto.ListSharesResponse JSON, without their tokens
+func (h *FilesHandler) ListShares(w http.ResponseWriter, r *http.Request) {
+	shares, err := services.ListShares(r.Context(), bucketFromRequest(r), router.Param(r, "name"))
+	if err != nil {
+		shareErrorHandler(w, r, err)
+		return
+	}
+
+	response := dto.ListSharesResponse{Shares: make([]dto.ShareInfo, 0, len(shares))}
+	for _, share := range shares {
+		response.Shares = append(response.Shares, newShareInfo(share))
+	}
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// RevokeShare method    Revoke a share link of an object, it's kept with its access log
+func (h *FilesHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	err := services.RevokeShare(r.Context(), bucketFromRequest(r), router.Param(r, "name"), router.Param(r, "id"))
+	if err != nil {
+		shareErrorHandler(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetShareAccesses method    Access log of a share link as dto.ShareAccessesResponse JSON
+func (h *FilesHandler) GetShareAccesses(w http.ResponseWriter, r *http.Request) {
+	accesses, err := services.ShareAccesses(r.Context(), bucketFromRequest(r), router.Param(r, "name"), router.Param(r, "id"))
+	if err != nil {
+		shareErrorHandler(w, r, err)
+		return
+	}
+
+	response := dto.ShareAccessesResponse{Accesses: make([]dto.ShareAccess, 0, len(accesses))}
+	for _, access := range accesses {
+		response.Accesses = append(response.Accesses, dto.ShareAccess{
+			At:         access.At,
+			RemoteAddr: access.RemoteAddr,
+			UserAgent:  access.UserAgent,
+			Result:     access.Result,
+		})
+	}
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// DownloadShare method    Download the object of a share link. The password of a protected link is sent in the
+// X-Share-Password header or with HTTP Basic authentication, browsers prompt for it on 401.
+// HEAD and the ranges resuming a download are not counted as downloads.
+func (h *SharesHandler) DownloadShare(w http.ResponseWriter, r *http.Request) {
+	password := r.Header.Get(dto.HeaderSharePassword)
+	if _, basicPassword, ok := r.BasicAuth(); ok && password == "" {
+		password = basicPassword
+	}
+
+	kind := shareAccessKind(r)
+	share, err := services.OpenShare(r.Context(), router.Param(r, "token"), password, kind, middleware.ClientAddress(r), r.UserAgent())
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, services.ErrShareNotFound):
+			errorhandlers.NotFoundHandler(w, r)
+		case errors.Is(err, services.ErrShareGone):
+			errorhandlers.GoneHandler(w, r, err)
+		case errors.Is(err, services.ErrSharePassword):
+			w.Header().Set("WWW-Authenticate", `Basic realm="share"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(err.Error()))
+		case errors.Is(err, services.ErrIndexDisabled):
+			errorhandlers.ServiceUnavailableHandler(w, r, err)
+		default:
+			errorhandlers.InternalServerErrorHandler(w, r)
+		}
+		return
+	}
+
+	object, objectInfo, err := services.GetObject(share.Bucket, share.Name)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+	defer object.Close()
+
+	w.Header().Set("Content-Type", objectInfo.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(share.Name)}))
+	w.Header().Set("ETag", fmt.Sprintf("%q", objectInfo.ETag))
+	w.Header().Set("Cache-Control", "private, no-store")
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	http.ServeContent(rec, r, share.Name, objectInfo.LastModified, object)
+
+	if kind == services.ShareDownload && (rec.status == http.StatusOK || rec.status == http.StatusPartialContent) {
+		publishEvent(r, webhooks.Event{
+			Type:        webhooks.EventFileDownloaded,
+			Bucket:      share.Bucket,
+			Name:        share.Name,
+			Size:        objectInfo.Size,
+			ETag:        objectInfo.ETag,
+			ContentType: objectInfo.ContentType,
+		})
+	}
+}
+
+// shareAccessKind function    Kind of a request to a share link: HEAD, a range past the start or a download
+func shareAccessKind(r *http.Request) services.ShareAccessKind {
+	if r.Method == http.MethodHead {
+		return services.ShareHead
+	}
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && !strings.HasPrefix(rangeHeader, "bytes=0-") {
+		return services.ShareResume
+	}
+	return services.ShareDownload
+}
+
+// shareErrorHandler function    Response of the share link management: 404 for missing objects and links, 400 for
+// an invalid expiry, 503 with the metadata index disabled
+func shareErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, services.ErrShareNotFound) || services.IsNotFound(err):
+		errorhandlers.NotFoundHandler(w, r)
+	case errors.Is(err, services.ErrShareExpires):
+		errorhandlers.BadRequestHandler(w, r, err)
+	case errors.Is(err, services.ErrIndexDisabled):
+		errorhandlers.ServiceUnavailableHandler(w, r, err)
+	default:
+		errorhandlers.InternalServerErrorHandler(w, r)
+	}
+}
+
+// shareURL function    Public url of a share link, under shares.base-url or the API base path of the configured
+// server address. The Host header is set by the client, it is never used.
+func shareURL(token string) string {
+	baseURL := config.ServerConfigValues.Shares.BaseURL
+	if baseURL == "" {
+		server := config.ServerConfigValues.Server
+		scheme := server.Protocol
+		if scheme == "" {
+			scheme = "http"
+		}
+		host := server.Host
+		if host == "" {
+			host = "localhost"
+		}
+		if server.Port != "" {
+			host = net.JoinHostPort(host, server.Port)
+		}
+		baseURL = scheme + "://" + host + router.BasePath(server.ApiPath, server.ApiVersion)
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/shares/" + token
+}
+
+func (h *SharesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		h.routes = router.New()
+		h.routes.Handle(http.MethodGet, ShareReWithToken, h.DownloadShare)
+		h.routes.Handle(http.MethodHead, ShareReWithToken, h.DownloadShare)
+	})
+
+	h.routes.ServeHTTP(w, r)
+}
+
+// newShareInfo function    Share link as sent to the clients, without its password hash
+func newShareInfo(s index.Share) dto.ShareInfo {
+	info := dto.ShareInfo{
+		ID:           s.ID,
+		BucketName:   s.Bucket,
+		Name:         s.Name,
+		CreatedBy:    s.CreatedBy,
+		CreatedAt:    s.CreatedAt,
+		ExpiresAt:    s.ExpiresAt,
+		Protected:    s.PasswordHash != "",
+		MaxDownloads: s.MaxDownloads,
+		Downloads:    s.Downloads,
+	}
+	if !s.RevokedAt.IsZero() {
+		revokedAt := s.RevokedAt
+		info.RevokedAt = &revokedAt
+	}
+	return info
+}
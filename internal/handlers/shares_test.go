@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/services"
+)
+
+func TestShareAccessKind(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		rangeValue string
+		want       services.ShareAccessKind
+	}{
+		"get":           {method: http.MethodGet, want: services.ShareDownload},
+		"first range":   {method: http.MethodGet, rangeValue: "bytes=0-1023", want: services.ShareDownload},
+		"resumed range": {method: http.MethodGet, rangeValue: "bytes=1024-", want: services.ShareResume},
+		"suffix range":  {method: http.MethodGet, rangeValue: "bytes=-1024", want: services.ShareResume},
+		"head":          {method: http.MethodHead, want: services.ShareHead},
+		"head of range": {method: http.MethodHead, rangeValue: "bytes=0-", want: services.ShareHead},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/shares/token", nil)
+			if test.rangeValue != "" {
+				r.Header.Set("Range", test.rangeValue)
+			}
+			if got := shareAccessKind(r); got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestShareURL(t *testing.T) {
+	server := config.ServerConfigValues.Server
+	shares := config.ServerConfigValues.Shares
+	t.Cleanup(func() {
+		config.ServerConfigValues.Server = server
+		config.ServerConfigValues.Shares = shares
+	})
+	config.ServerConfigValues.Server.ApiPath = "/api"
+	config.ServerConfigValues.Server.ApiVersion = "v1"
+	config.ServerConfigValues.Server.Port = "8080"
+
+	if got, want := shareURL("token"), "http://localhost:8080/api/v1/shares/token"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	config.ServerConfigValues.Shares.BaseURL = "https://files.example.com/api/v1/"
+	if got, want := shareURL("token"), "https://files.example.com/api/v1/shares/token"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
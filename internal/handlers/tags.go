@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/services"
+)
+
+// GetFileTags method    Tags of an object as dto.ObjectTags JSON
+func (h *FilesHandler) GetFileTags(w http.ResponseWriter, r *http.Request) {
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	tags, err := services.GetObjectTagging(bucketName, fileName)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeObjectTags(w, tags)
+}
+
+// PutFileTags method    Replace the tags of an object with the ones of a dto.ObjectTags JSON body
+func (h *FilesHandler) PutFileTags(w http.ResponseWriter, r *http.Request) {
+	var reqBody dto.ObjectTags
+
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("No Request JSON Body")
+		}
+		bodyErrorHandler(w, r, err)
+		return
+	}
+
+	err = reqBody.Validate()
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	err = services.PutObjectTagging(bucketName, fileName, reqBody.Tags)
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeObjectTags(w, reqBody.Tags)
+}
+
+// DeleteFileTags method    Remove all the tags of an object
+func (h *FilesHandler) DeleteFileTags(w http.ResponseWriter, r *http.Request) {
+	fileName, err := fileNameFromRequest(r)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+	bucketName := bucketFromRequest(r)
+
+	// Not every S3 implementation reports missing objects when removing tags
+	_, err = services.StatObject(bucketName, fileName)
+	if err == nil {
+		err = services.RemoveObjectTagging(bucketName, fileName)
+	}
+	if err != nil {
+		log.Println(err)
+		if services.IsNotFound(err) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeObjectTags(w http.ResponseWriter, tags map[string]string) {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	js, err := json.Marshal(dto.ObjectTags{Tags: tags})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+func TestFileTags(t *testing.T) {
+	newTestStorage(t)
+
+	_, err := storage.MinioClient.PutObject(context.Background(), testBucket, "report.txt", strings.NewReader("report"), 6, minio.PutObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The steps share the object, they run in order
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		tags   map[string]string
+	}{
+		{name: "get untagged", method: http.MethodGet, path: "/files/report.txt:tags", status: http.StatusOK, tags: map[string]string{}},
+		{name: "put", method: http.MethodPut, path: "/files/report.txt:tags", body: `{"tags":{"project":"apollo","stage":"raw"}}`, status: http.StatusOK, tags: map[string]string{"project": "apollo", "stage": "raw"}},
+		{name: "get", method: http.MethodGet, path: "/files/report.txt:tags", status: http.StatusOK, tags: map[string]string{"project": "apollo", "stage": "raw"}},
+		{name: "put too many", method: http.MethodPut, path: "/files/report.txt:tags", body: `{"tags":{"a":"1","b":"2","c":"3","d":"4","e":"5","f":"6","g":"7","h":"8","i":"9","j":"10","k":"11"}}`, status: http.StatusBadRequest},
+		{name: "put without body", method: http.MethodPut, path: "/files/report.txt:tags", status: http.StatusBadRequest},
+		{name: "get kept", method: http.MethodGet, path: "/files/report.txt:tags", status: http.StatusOK, tags: map[string]string{"project": "apollo", "stage": "raw"}},
+		{name: "put missing", method: http.MethodPut, path: "/files/missing.txt:tags", body: `{"tags":{"stage":"raw"}}`, status: http.StatusNotFound},
+		{name: "get missing", method: http.MethodGet, path: "/files/missing.txt:tags", status: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, path: "/files/report.txt:tags", status: http.StatusNoContent},
+		{name: "get deleted", method: http.MethodGet, path: "/files/report.txt:tags", status: http.StatusOK, tags: map[string]string{}},
+		{name: "delete missing", method: http.MethodDelete, path: "/files/missing.txt:tags", status: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		r := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		w := httptest.NewRecorder()
+		(&FilesHandler{}).ServeHTTP(w, r)
+
+		if w.Code != step.status {
+			t.Fatalf("%s: got %d %s, want %d", step.name, w.Code, w.Body.String(), step.status)
+		}
+		if step.tags == nil {
+			continue
+		}
+
+		var tags dto.ObjectTags
+		err := json.NewDecoder(w.Body).Decode(&tags)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if !reflect.DeepEqual(tags.Tags, step.tags) {
+			t.Errorf("%s: got %v, want %v", step.name, tags.Tags, step.tags)
+		}
+	}
+}
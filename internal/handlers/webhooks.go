@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/middleware"
+	"github.com/pavva91/file-upload/internal/router"
+	"github.com/pavva91/file-upload/internal/services"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+// WebhooksHandler    Admin endpoints of the webhook delivery queue
+type WebhooksHandler struct {
+	once   sync.Once
+	routes *router.Router
+}
+
+// Routes are relative to the API base path
+var (
+	WebhookReDeliveries     = regexp.MustCompile(`^/webhooks/deliveries$`)
+	WebhookReReplayAll      = regexp.MustCompile(`^/webhooks/deliveries:replay$`)
+	WebhookReReplayDelivery = regexp.MustCompile(`^/webhooks/deliveries/(?P<id>[^/]+):replay$`)
+)
+
+// WebhooksPaths are the paths to mount the WebhooksHandler on, relative to the API base path
+var WebhooksPaths = []string{"/webhooks/"}
+
+// ListWebhookDeliveries method    Deliveries of the ?status= (pending or dead, default dead) queue
+func (h *WebhooksHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = webhooks.StatusDead
+	}
+
+	deliveries, err := services.Webhooks.List(status)
+	if err != nil {
+		errorhandlers.BadRequestHandler(w, r, err)
+		return
+	}
+
+	writeWebhookDeliveries(w, dto.WebhookDeliveriesResponse{Status: status, Deliveries: newWebhookDeliveries(deliveries)})
+}
+
+// ReplayWebhookDelivery method    Queue a dead delivery again, its attempts start over
+func (h *WebhooksHandler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := services.Webhooks.Replay(router.Param(r, "id"))
+	if err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			errorhandlers.NotFoundHandler(w, r)
+			return
+		}
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	js, err := json.Marshal(newWebhookDelivery(delivery))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// ReplayWebhookDeliveries method    Queue all the dead deliveries again
+func (h *WebhooksHandler) ReplayWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := services.Webhooks.ReplayAll()
+	if err != nil {
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeWebhookDeliveries(w, dto.WebhookDeliveriesResponse{Status: webhooks.StatusPending, Deliveries: newWebhookDeliveries(deliveries)})
+}
+
+func writeWebhookDeliveries(w http.ResponseWriter, response dto.WebhookDeliveriesResponse) {
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// newWebhookDelivery function    Delivery as sent to the clients
+func newWebhookDelivery(delivery webhooks.Delivery) dto.WebhookDelivery {
+	event := delivery.Event
+	return dto.WebhookDelivery{
+		ID:      delivery.ID,
+		Webhook: delivery.Webhook,
+		Event: dto.WebhookEvent{
+			ID:           event.ID,
+			Type:         event.Type,
+			Time:         event.Time,
+			Bucket:       event.Bucket,
+			Name:         event.Name,
+			Size:         event.Size,
+			ETag:         event.ETag,
+			ContentType:  event.ContentType,
+			Principal:    event.Principal,
+			SourceBucket: event.SourceBucket,
+			SourceName:   event.SourceName,
+		},
+		Status:      delivery.Status,
+		Attempts:    delivery.Attempts,
+		NextAttempt: delivery.NextAttempt,
+		LastError:   delivery.LastError,
+		CreatedAt:   delivery.CreatedAt,
+	}
+}
+
+func newWebhookDeliveries(deliveries []webhooks.Delivery) []dto.WebhookDelivery {
+	result := make([]dto.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		result = append(result, newWebhookDelivery(delivery))
+	}
+	return result
+}
+
+// ServeHTTP method    Only admins can manage the queue, 503 when no webhook is configured
+func (h *WebhooksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		h.routes = router.New()
+		h.routes.Handle(http.MethodGet, WebhookReDeliveries, h.ListWebhookDeliveries)
+		h.routes.Handle(http.MethodPost, WebhookReReplayAll, h.ReplayWebhookDeliveries)
+		h.routes.Handle(http.MethodPost, WebhookReReplayDelivery, h.ReplayWebhookDelivery)
+	})
+
+	if !middleware.PrincipalFromRequest(r).Admin {
+		errorhandlers.ForbiddenHandler(w, r)
+		return
+	}
+	if services.Webhooks == nil {
+		errorhandlers.ServiceUnavailableHandler(w, r, services.ErrWebhooksDisabled)
+		return
+	}
+	h.routes.ServeHTTP(w, r)
+}
+
+// publishEvent function    Queue the webhook event of a successful operation, on behalf of the request principal
+func publishEvent(r *http.Request, event webhooks.Event) {
+	event.Principal = middleware.PrincipalFromRequest(r).Name
+	services.PublishEvent(event)
+}
+
+// statusRecorder    Keep the status code written by http.ServeContent
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/middleware"
+	"github.com/pavva91/file-upload/internal/services"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+// newDeadWebhooks function    Dispatcher of services.Webhooks with the deliveries of events dead, their endpoint being down
+func newDeadWebhooks(t *testing.T, events ...webhooks.Event) []webhooks.Delivery {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(down.Close)
+
+	d, err := webhooks.NewDispatcher(t.TempDir(), []webhooks.Subscription{{Name: "down", URL: down.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.MaxAttempts = 1
+	for _, event := range events {
+		err = d.Publish(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	var dead []webhooks.Delivery
+	for start := time.Now(); len(dead) < len(events) && time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		dead, err = d.List(webhooks.StatusDead)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Stopped so that the replayed deliveries stay pending
+	cancel()
+	<-done
+	if len(dead) != len(events) {
+		t.Fatalf("got %d dead deliveries, want %d", len(dead), len(events))
+	}
+
+	previous := services.Webhooks
+	services.Webhooks = d
+	t.Cleanup(func() { services.Webhooks = previous })
+	return dead
+}
+
+func TestWebhooksDisabled(t *testing.T) {
+	previous := services.Webhooks
+	services.Webhooks = nil
+	t.Cleanup(func() { services.Webhooks = previous })
+
+	tests := map[string]struct {
+		principal middleware.Principal
+		status    int
+	}{
+		"admin":     {principal: middleware.Principal{Name: "ops", Admin: true}, status: http.StatusServiceUnavailable},
+		"non-admin": {principal: middleware.Principal{Name: "app"}, status: http.StatusForbidden},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			r := middleware.WithPrincipal(httptest.NewRequest(http.MethodGet, "/webhooks/deliveries", nil), test.principal)
+			w := httptest.NewRecorder()
+			(&WebhooksHandler{}).ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("got %d %s, want %d", w.Code, w.Body.String(), test.status)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	dead := newDeadWebhooks(t,
+		webhooks.Event{Type: webhooks.EventFileUploaded, Bucket: testBucket, Name: "a.csv"},
+		webhooks.Event{Type: webhooks.EventFileDeleted, Bucket: testBucket, Name: "a.csv"},
+	)
+	admin := middleware.Principal{Name: "ops", Admin: true}
+
+	// The steps share the queue, they run in order
+	steps := []struct {
+		name       string
+		principal  middleware.Principal
+		method     string
+		path       string
+		status     int
+		deliveries []string
+	}{
+		{name: "non-admin", principal: middleware.Principal{Name: "app"}, method: http.MethodGet, path: "/webhooks/deliveries", status: http.StatusForbidden},
+		{name: "list dead by default", principal: admin, method: http.MethodGet, path: "/webhooks/deliveries", status: http.StatusOK, deliveries: []string{dead[0].ID, dead[1].ID}},
+		{name: "list pending", principal: admin, method: http.MethodGet, path: "/webhooks/deliveries?status=pending", status: http.StatusOK, deliveries: []string{}},
+		{name: "list invalid status", principal: admin, method: http.MethodGet, path: "/webhooks/deliveries?status=delivered", status: http.StatusBadRequest},
+		{name: "replay unknown", principal: admin, method: http.MethodPost, path: "/webhooks/deliveries/0123:replay", status: http.StatusNotFound},
+		{name: "replay invalid id", principal: admin, method: http.MethodPost, path: "/webhooks/deliveries/..:replay", status: http.StatusNotFound},
+		{name: "replay", principal: admin, method: http.MethodPost, path: "/webhooks/deliveries/" + dead[0].ID + ":replay", status: http.StatusOK, deliveries: []string{dead[0].ID}},
+		{name: "replay again", principal: admin, method: http.MethodPost, path: "/webhooks/deliveries/" + dead[0].ID + ":replay", status: http.StatusNotFound},
+		{name: "replay all", principal: admin, method: http.MethodPost, path: "/webhooks/deliveries:replay", status: http.StatusOK, deliveries: []string{dead[1].ID}},
+		{name: "list dead replayed", principal: admin, method: http.MethodGet, path: "/webhooks/deliveries?status=dead", status: http.StatusOK, deliveries: []string{}},
+		{name: "list pending replayed", principal: admin, method: http.MethodGet, path: "/webhooks/deliveries?status=pending", status: http.StatusOK, deliveries: []string{dead[0].ID, dead[1].ID}},
+	}
+
+	for _, step := range steps {
+		r := middleware.WithPrincipal(httptest.NewRequest(step.method, step.path, nil), step.principal)
+		w := httptest.NewRecorder()
+		(&WebhooksHandler{}).ServeHTTP(w, r)
+
+		if w.Code != step.status {
+			t.Fatalf("%s: got %d %s, want %d", step.name, w.Code, w.Body.String(), step.status)
+		}
+		if step.deliveries == nil {
+			continue
+		}
+
+		var deliveries []dto.WebhookDelivery
+		if step.name == "replay" {
+			var delivery dto.WebhookDelivery
+			err := json.NewDecoder(w.Body).Decode(&delivery)
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			deliveries = append(deliveries, delivery)
+		} else {
+			var response dto.WebhookDeliveriesResponse
+			err := json.NewDecoder(w.Body).Decode(&response)
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			deliveries = response.Deliveries
+		}
+
+		ids := map[string]bool{}
+		for _, delivery := range deliveries {
+			ids[delivery.ID] = true
+			if step.method == http.MethodPost && (delivery.Status != webhooks.StatusPending || delivery.Attempts != 0) {
+				t.Errorf("%s: got %+v, want the delivery pending with its attempts reset", step.name, delivery)
+			}
+		}
+		for _, id := range step.deliveries {
+			if !ids[id] {
+				t.Errorf("%s: got %+v, want delivery %s", step.name, deliveries, id)
+			}
+		}
+		if len(deliveries) != len(step.deliveries) {
+			t.Errorf("%s: got %d deliveries, want %d", step.name, len(deliveries), len(step.deliveries))
+		}
+	}
+}
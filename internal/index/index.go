@@ -0,0 +1,372 @@
+// Package index is a local SQLite copy of the object listings, searchable by name, size, date, content type,
+// user metadata and tags without listing the buckets. It also keeps the share links of the objects.
+package index
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Properties are kept as key/value rows for the filters and as JSON for the results
+const schema = `
+CREATE TABLE IF NOT EXISTS objects (
+	bucket        TEXT    NOT NULL,
+	name          TEXT    NOT NULL,
+	size          INTEGER NOT NULL,
+	etag          TEXT    NOT NULL,
+	content_type  TEXT    NOT NULL,
+	last_modified INTEGER NOT NULL,
+	metadata      TEXT    NOT NULL,
+	tags          TEXT    NOT NULL,
+	indexed_at    INTEGER NOT NULL,
+	PRIMARY KEY (bucket, name)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS objects_size ON objects (bucket, size);
+CREATE INDEX IF NOT EXISTS objects_last_modified ON objects (bucket, last_modified);
+CREATE INDEX IF NOT EXISTS objects_content_type ON objects (bucket, content_type);
+
+CREATE TABLE IF NOT EXISTS properties (
+	bucket TEXT NOT NULL,
+	name   TEXT NOT NULL,
+	kind   TEXT NOT NULL,
+	key    TEXT NOT NULL,
+	value  TEXT NOT NULL,
+	PRIMARY KEY (bucket, name, kind, key)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS properties_value ON properties (bucket, kind, key, value);
+`
+
+// Kinds of properties
+const (
+	kindMetadata = "metadata"
+	kindTag      = "tag"
+)
+
+// Sort orders of Search
+const (
+	SortName         = "name"
+	SortSize         = "size"
+	SortLastModified = "lastModified"
+)
+
+var sortColumns = map[string]string{
+	SortName:         "name",
+	SortSize:         "size",
+	SortLastModified: "last_modified",
+}
+
+// Object    Indexed properties of an object
+type Object struct {
+	Bucket       string
+	Name         string
+	Size         int64
+	ETag         string
+	ContentType  string
+	LastModified time.Time
+	Metadata     map[string]string
+	Tags         map[string]string
+}
+
+// Query    Filters of Search, zero values don't filter
+type Query struct {
+	Bucket string
+	Prefix string
+	// Glob is matched against the whole name, * and ? match slashes too
+	Glob           string
+	MinSize        *int64
+	MaxSize        *int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// ContentType is a media type or a type wildcard like image/*
+	ContentType string
+	Metadata    map[string]string
+	Tags        map[string]string
+
+	// Sort is name (default), size or lastModified
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+type Index struct {
+	db *sql.DB
+}
+
+// Open function    Open or create the index database at path
+func Open(path string) (*Index, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, err
+	}
+	// Writes are serialized by SQLite, a single connection avoids SQLITE_BUSY between them
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(schema + sharesSchema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("index schema: %w", err)
+	}
+	return &Index{db: db}, nil
+}
+
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// Put method    Insert or replace objects in one transaction
+func (ix *Index) Put(ctx context.Context, objects ...Object) error {
+	return ix.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now().UnixNano()
+		for _, o := range objects {
+			metadata, err := marshalMap(o.Metadata)
+			if err != nil {
+				return err
+			}
+			tags, err := marshalMap(o.Tags)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO objects
+				(bucket, name, size, etag, content_type, last_modified, metadata, tags, indexed_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				o.Bucket, o.Name, o.Size, o.ETag, o.ContentType, o.LastModified.UnixNano(), metadata, tags, now)
+			if err != nil {
+				return err
+			}
+			err = replaceProperties(ctx, tx, o.Bucket, o.Name, kindMetadata, o.Metadata)
+			if err != nil {
+				return err
+			}
+			err = replaceProperties(ctx, tx, o.Bucket, o.Name, kindTag, o.Tags)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetTags method    Replace the tags of an indexed object, missing objects are ignored
+func (ix *Index) SetTags(ctx context.Context, bucket string, name string, tags map[string]string) error {
+	return ix.inTx(ctx, func(tx *sql.Tx) error {
+		js, err := marshalMap(tags)
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `UPDATE objects SET tags = ? WHERE bucket = ? AND name = ?`, js, bucket, name)
+		if err != nil {
+			return err
+		}
+		if updated, _ := result.RowsAffected(); updated == 0 {
+			return nil
+		}
+		return replaceProperties(ctx, tx, bucket, name, kindTag, tags)
+	})
+}
+
+// Delete method    Remove an object from the index
+func (ix *Index) Delete(ctx context.Context, bucket string, name string) error {
+	return ix.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM objects WHERE bucket = ? AND name = ?`, bucket, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM properties WHERE bucket = ? AND name = ?`, bucket, name)
+		return err
+	})
+}
+
+// RemoveStale method    Remove the objects of bucket indexed before a full scan started, they are gone from the bucket
+func (ix *Index) RemoveStale(ctx context.Context, bucket string, scanStart time.Time) (int64, error) {
+	var removed int64
+	err := ix.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM properties WHERE (bucket, name) IN
+			(SELECT bucket, name FROM objects WHERE bucket = ? AND indexed_at < ?)`, bucket, scanStart.UnixNano())
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM objects WHERE bucket = ? AND indexed_at < ?`, bucket, scanStart.UnixNano())
+		if err != nil {
+			return err
+		}
+		removed, err = result.RowsAffected()
+		return err
+	})
+	return removed, err
+}
+
+// RemoveBucketsExcept method    Remove the objects of the buckets that no longer exist
+func (ix *Index) RemoveBucketsExcept(ctx context.Context, buckets []string) error {
+	return ix.inTx(ctx, func(tx *sql.Tx) error {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(buckets)), ", ")
+		args := make([]interface{}, len(buckets))
+		for i, bucket := range buckets {
+			args[i] = bucket
+		}
+		for _, table := range []string{"objects", "properties"} {
+			query := "DELETE FROM " + table
+			if len(buckets) > 0 {
+				query += " WHERE bucket NOT IN (" + placeholders + ")"
+			}
+			_, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Search method    Objects matching the query in the sort order and the total number of matches
+func (ix *Index) Search(ctx context.Context, q Query) ([]Object, int, error) {
+	where := []string{"bucket = ?"}
+	args := []interface{}{q.Bucket}
+
+	if q.Prefix != "" {
+		// GLOB uses the primary key for a literal prefix
+		where = append(where, "name GLOB ?")
+		args = append(args, escapeGlob(q.Prefix)+"*")
+	}
+	if q.Glob != "" {
+		where = append(where, "name GLOB ?")
+		args = append(args, q.Glob)
+	}
+	if q.MinSize != nil {
+		where = append(where, "size >= ?")
+		args = append(args, *q.MinSize)
+	}
+	if q.MaxSize != nil {
+		where = append(where, "size <= ?")
+		args = append(args, *q.MaxSize)
+	}
+	if !q.ModifiedAfter.IsZero() {
+		where = append(where, "last_modified >= ?")
+		args = append(args, q.ModifiedAfter.UnixNano())
+	}
+	if !q.ModifiedBefore.IsZero() {
+		where = append(where, "last_modified < ?")
+		args = append(args, q.ModifiedBefore.UnixNano())
+	}
+	if mediaType, ok := strings.CutSuffix(q.ContentType, "/*"); ok {
+		where = append(where, "content_type GLOB ?")
+		args = append(args, escapeGlob(mediaType)+"/*")
+	} else if q.ContentType != "" {
+		// Parameters like charset are ignored
+		where = append(where, "(content_type = ? OR content_type GLOB ?)")
+		args = append(args, q.ContentType, escapeGlob(q.ContentType)+";*")
+	}
+	for kind, properties := range map[string]map[string]string{kindMetadata: q.Metadata, kindTag: q.Tags} {
+		for k, v := range properties {
+			where = append(where, `EXISTS (SELECT 1 FROM properties p
+				WHERE p.bucket = objects.bucket AND p.name = objects.name AND p.kind = ? AND p.key = ? AND p.value = ?)`)
+			args = append(args, kind, k, v)
+		}
+	}
+	condition := strings.Join(where, " AND ")
+
+	var total int
+	err := ix.db.QueryRowContext(ctx, "SELECT count(*) FROM objects WHERE "+condition, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortColumn, ok := sortColumns[q.Sort]
+	if !ok {
+		sortColumn = sortColumns[SortName]
+	}
+	direction := "ASC"
+	if q.Descending {
+		direction = "DESC"
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := ix.db.QueryContext(ctx, fmt.Sprintf(`SELECT name, size, etag, content_type, last_modified, metadata, tags
+		FROM objects WHERE %s ORDER BY %s %s, name %s LIMIT ? OFFSET ?`, condition, sortColumn, direction, direction),
+		append(args, limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	objects := []Object{}
+	for rows.Next() {
+		o := Object{Bucket: q.Bucket}
+		var lastModified int64
+		var metadata, tags string
+		err := rows.Scan(&o.Name, &o.Size, &o.ETag, &o.ContentType, &lastModified, &metadata, &tags)
+		if err != nil {
+			return nil, 0, err
+		}
+		o.LastModified = time.Unix(0, lastModified).UTC()
+		err = json.Unmarshal([]byte(metadata), &o.Metadata)
+		if err != nil {
+			return nil, 0, err
+		}
+		err = json.Unmarshal([]byte(tags), &o.Tags)
+		if err != nil {
+			return nil, 0, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, total, rows.Err()
+}
+
+func (ix *Index) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := ix.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = f(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceProperties(ctx context.Context, tx *sql.Tx, bucket string, name string, kind string, properties map[string]string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM properties WHERE bucket = ? AND name = ? AND kind = ?`, bucket, name, kind)
+	if err != nil {
+		return err
+	}
+	for k, v := range properties {
+		_, err := tx.ExecContext(ctx, `INSERT INTO properties (bucket, name, kind, key, value) VALUES (?, ?, ?, ?, ?)`, bucket, name, kind, k, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalMap(m map[string]string) (string, error) {
+	if m == nil {
+		m = map[string]string{}
+	}
+	js, err := json.Marshal(m)
+	return string(js), err
+}
+
+// escapeGlob function    Match the special characters of GLOB literally
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[':
+			b.WriteString("[" + string(c) + "]")
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,181 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	ix, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	ctx := context.Background()
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	err = ix.Put(ctx,
+		Object{Bucket: "data", Name: "raw/a.csv", Size: 100, ContentType: "text/csv", LastModified: day, Tags: map[string]string{"stage": "raw"}},
+		Object{Bucket: "data", Name: "raw/b.json", Size: 2000, ContentType: "application/json", LastModified: day.Add(24 * time.Hour), Metadata: map[string]string{"Project-Id": "42"}},
+		Object{Bucket: "data", Name: "img/c.png", Size: 50000, ContentType: "image/png", LastModified: day.Add(48 * time.Hour), Tags: map[string]string{"stage": "raw"}},
+		Object{Bucket: "data", Name: "img/d[1].jpg", Size: 30, ContentType: "image/jpeg", LastModified: day},
+		Object{Bucket: "other", Name: "raw/a.csv", Size: 1, ContentType: "text/csv; charset=utf-8", LastModified: day},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := func(n int64) *int64 { return &n }
+
+	tests := map[string]struct {
+		query Query
+		names []string
+		total int
+	}{
+		"bucket":             {query: Query{Bucket: "data"}, names: []string{"img/c.png", "img/d[1].jpg", "raw/a.csv", "raw/b.json"}},
+		"prefix":             {query: Query{Bucket: "data", Prefix: "raw/"}, names: []string{"raw/a.csv", "raw/b.json"}},
+		"prefix with [":      {query: Query{Bucket: "data", Prefix: "img/d["}, names: []string{"img/d[1].jpg"}},
+		"glob":               {query: Query{Bucket: "data", Glob: "*.csv"}, names: []string{"raw/a.csv"}},
+		"size range":         {query: Query{Bucket: "data", MinSize: size(100), MaxSize: size(2000)}, names: []string{"raw/a.csv", "raw/b.json"}},
+		"modified after":     {query: Query{Bucket: "data", ModifiedAfter: day.Add(time.Hour)}, names: []string{"img/c.png", "raw/b.json"}},
+		"modified before":    {query: Query{Bucket: "data", ModifiedBefore: day.Add(time.Hour)}, names: []string{"img/d[1].jpg", "raw/a.csv"}},
+		"content type":       {query: Query{Bucket: "other", ContentType: "text/csv"}, names: []string{"raw/a.csv"}},
+		"content type image": {query: Query{Bucket: "data", ContentType: "image/*"}, names: []string{"img/c.png", "img/d[1].jpg"}},
+		"tag":                {query: Query{Bucket: "data", Tags: map[string]string{"stage": "raw"}}, names: []string{"img/c.png", "raw/a.csv"}},
+		"tag and prefix":     {query: Query{Bucket: "data", Prefix: "raw/", Tags: map[string]string{"stage": "raw"}}, names: []string{"raw/a.csv"}},
+		"metadata":           {query: Query{Bucket: "data", Metadata: map[string]string{"Project-Id": "42"}}, names: []string{"raw/b.json"}},
+		"sort by size desc":  {query: Query{Bucket: "data", Sort: SortSize, Descending: true}, names: []string{"img/c.png", "raw/b.json", "raw/a.csv", "img/d[1].jpg"}},
+		"sort by date page": {
+			query: Query{Bucket: "data", Sort: SortLastModified, Limit: 2, Offset: 1},
+			names: []string{"raw/a.csv", "raw/b.json"},
+			total: 4,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			objects, total, err := ix.Search(ctx, test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, o := range objects {
+				names = append(names, o.Name)
+			}
+			if !reflect.DeepEqual(names, test.names) {
+				t.Errorf("got %v, want %v", names, test.names)
+			}
+			wantTotal := test.total
+			if wantTotal == 0 {
+				wantTotal = len(test.names)
+			}
+			if total != wantTotal {
+				t.Errorf("got total %d, want %d", total, wantTotal)
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ix, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	ctx := context.Background()
+
+	err = ix.Put(ctx, Object{Bucket: "data", Name: "gone"}, Object{Bucket: "data", Name: "kept"}, Object{Bucket: "deleted", Name: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanStart := time.Now()
+	err = ix.Put(ctx, Object{Bucket: "data", Name: "kept", Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ix.SetTags(ctx, "data", "kept", map[string]string{"stage": "raw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := ix.RemoveStale(ctx, "data", scanStart)
+	if err != nil || removed != 1 {
+		t.Errorf("got %d removed (%v), want 1", removed, err)
+	}
+	err = ix.RemoveBucketsExcept(ctx, []string{"data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects, _, err := ix.Search(ctx, Query{Bucket: "data", Tags: map[string]string{"stage": "raw"}})
+	if err != nil || len(objects) != 1 || objects[0].Name != "kept" || objects[0].Size != 1 {
+		t.Errorf("got %v (%v), want kept", objects, err)
+	}
+	if objects, _, _ := ix.Search(ctx, Query{Bucket: "deleted"}); len(objects) != 0 {
+		t.Errorf("got %v, want the objects of the deleted bucket removed", objects)
+	}
+}
+
+func TestShares(t *testing.T) {
+	ix, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	ctx := context.Background()
+
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	share := Share{ID: "a1", TokenHash: "hash", Bucket: "data", Name: "raw/a.csv", CreatedBy: "tester", CreatedAt: created, ExpiresAt: created.Add(time.Hour), MaxDownloads: 2}
+	err = ix.PutShare(ctx, share)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ix.ShareByToken(ctx, "hash")
+	if err != nil || !reflect.DeepEqual(got, share) {
+		t.Errorf("got %+v (%v), want %+v", got, err, share)
+	}
+	if _, err := ix.ShareByToken(ctx, "other"); err != ErrShareNotFound {
+		t.Errorf("got %v, want ErrShareNotFound", err)
+	}
+
+	for i, want := range []bool{true, true, false} {
+		counted, err := ix.CountShareDownload(ctx, "a1")
+		if err != nil || counted != want {
+			t.Errorf("download %d: got %t (%v), want %t", i, counted, err, want)
+		}
+	}
+
+	revoked := created.Add(time.Minute)
+	for _, at := range []time.Time{revoked, revoked.Add(time.Minute)} {
+		err = ix.RevokeShare(ctx, "a1", at)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ix.RevokeShare(ctx, "missing", revoked); err != ErrShareNotFound {
+		t.Errorf("got %v, want ErrShareNotFound", err)
+	}
+
+	shares, err := ix.Shares(ctx, "data", "raw/a.csv")
+	if err != nil || len(shares) != 1 || shares[0].Downloads != 2 || !shares[0].RevokedAt.Equal(revoked) {
+		t.Errorf("got %+v (%v), want 2 downloads and the first revocation", shares, err)
+	}
+
+	for _, result := range []string{"downloaded", "revoked"} {
+		err = ix.LogShareAccess(ctx, ShareAccess{ShareID: "a1", At: revoked, RemoteAddr: "127.0.0.1", Result: result})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	accesses, err := ix.ShareAccesses(ctx, "a1")
+	if err != nil || len(accesses) != 2 || accesses[0].Result != "downloaded" || accesses[1].Result != "revoked" {
+		t.Errorf("got %+v (%v), want the accesses in order", accesses, err)
+	}
+}
@@ -0,0 +1,186 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Share links of the objects and the log of their accesses, the tokens are stored as hashes
+const sharesSchema = `
+CREATE TABLE IF NOT EXISTS shares (
+	id            TEXT    NOT NULL PRIMARY KEY,
+	token_hash    TEXT    NOT NULL UNIQUE,
+	bucket        TEXT    NOT NULL,
+	name          TEXT    NOT NULL,
+	created_by    TEXT    NOT NULL,
+	created_at    INTEGER NOT NULL,
+	expires_at    INTEGER NOT NULL,
+	password_hash TEXT    NOT NULL,
+	max_downloads INTEGER NOT NULL,
+	downloads     INTEGER NOT NULL,
+	revoked_at    INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS shares_object ON shares (bucket, name, created_at);
+
+CREATE TABLE IF NOT EXISTS share_accesses (
+	share_id    TEXT    NOT NULL,
+	at          INTEGER NOT NULL,
+	remote_addr TEXT    NOT NULL,
+	user_agent  TEXT    NOT NULL,
+	result      TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS share_accesses_share ON share_accesses (share_id, at);
+`
+
+var ErrShareNotFound = errors.New("share not found")
+
+// Share    Share link of an object
+type Share struct {
+	ID        string
+	TokenHash string
+	Bucket    string
+	Name      string
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// PasswordHash is empty when the link has no password
+	PasswordHash string
+	// MaxDownloads 0 doesn't limit the downloads
+	MaxDownloads int
+	Downloads    int
+	// RevokedAt is zero while the link is not revoked
+	RevokedAt time.Time
+}
+
+// ShareAccess    Access to a share link and its result
+type ShareAccess struct {
+	ShareID    string
+	At         time.Time
+	RemoteAddr string
+	UserAgent  string
+	Result     string
+}
+
+const shareColumns = `id, token_hash, bucket, name, created_by, created_at, expires_at, password_hash, max_downloads, downloads, revoked_at`
+
+// PutShare method    Insert a new share link
+func (ix *Index) PutShare(ctx context.Context, s Share) error {
+	_, err := ix.db.ExecContext(ctx, `INSERT INTO shares (`+shareColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.TokenHash, s.Bucket, s.Name, s.CreatedBy, s.CreatedAt.UnixNano(), s.ExpiresAt.UnixNano(), s.PasswordHash,
+		s.MaxDownloads, s.Downloads, unixNano(s.RevokedAt))
+	return err
+}
+
+// Share method    Share link by id, ErrShareNotFound when missing
+func (ix *Index) Share(ctx context.Context, id string) (Share, error) {
+	return scanShare(ix.db.QueryRowContext(ctx, `SELECT `+shareColumns+` FROM shares WHERE id = ?`, id))
+}
+
+// ShareByToken method    Share link by the hash of its token, ErrShareNotFound when missing
+func (ix *Index) ShareByToken(ctx context.Context, tokenHash string) (Share, error) {
+	return scanShare(ix.db.QueryRowContext(ctx, `SELECT `+shareColumns+` FROM shares WHERE token_hash = ?`, tokenHash))
+}
+
+// Shares method    Share links of an object, oldest first
+func (ix *Index) Shares(ctx context.Context, bucket string, name string) ([]Share, error) {
+	rows, err := ix.db.QueryContext(ctx, `SELECT `+shareColumns+` FROM shares WHERE bucket = ? AND name = ? ORDER BY created_at, id`, bucket, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []Share{}
+	for rows.Next() {
+		s, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
+// RevokeShare method    Revoke a share link at a time, revoking it again keeps the first time
+func (ix *Index) RevokeShare(ctx context.Context, id string, at time.Time) error {
+	result, err := ix.db.ExecContext(ctx, `UPDATE shares SET revoked_at = ? WHERE id = ? AND revoked_at = 0`, at.UnixNano(), id)
+	if err != nil {
+		return err
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		_, err = ix.Share(ctx, id)
+		return err
+	}
+	return nil
+}
+
+// CountShareDownload method    Add a download to a share link, false when it reached its max downloads
+func (ix *Index) CountShareDownload(ctx context.Context, id string) (bool, error) {
+	result, err := ix.db.ExecContext(ctx, `UPDATE shares SET downloads = downloads + 1
+		WHERE id = ? AND (max_downloads = 0 OR downloads < max_downloads)`, id)
+	if err != nil {
+		return false, err
+	}
+	counted, err := result.RowsAffected()
+	return counted > 0, err
+}
+
+// LogShareAccess method    Record an access to a share link
+func (ix *Index) LogShareAccess(ctx context.Context, a ShareAccess) error {
+	_, err := ix.db.ExecContext(ctx, `INSERT INTO share_accesses (share_id, at, remote_addr, user_agent, result) VALUES (?, ?, ?, ?, ?)`,
+		a.ShareID, a.At.UnixNano(), a.RemoteAddr, a.UserAgent, a.Result)
+	return err
+}
+
+// ShareAccesses method    Accesses to a share link, oldest first
+func (ix *Index) ShareAccesses(ctx context.Context, id string) ([]ShareAccess, error) {
+	rows, err := ix.db.QueryContext(ctx, `SELECT at, remote_addr, user_agent, result FROM share_accesses WHERE share_id = ? ORDER BY at, rowid`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accesses := []ShareAccess{}
+	for rows.Next() {
+		a := ShareAccess{ShareID: id}
+		var at int64
+		err := rows.Scan(&at, &a.RemoteAddr, &a.UserAgent, &a.Result)
+		if err != nil {
+			return nil, err
+		}
+		a.At = time.Unix(0, at).UTC()
+		accesses = append(accesses, a)
+	}
+	return accesses, rows.Err()
+}
+
+// scanShare function    Share of a row with the shareColumns, sql.ErrNoRows becomes ErrShareNotFound
+func scanShare(row interface {
+	Scan(dest ...interface{}) error
+}) (Share, error) {
+	var s Share
+	var createdAt, expiresAt, revokedAt int64
+	err := row.Scan(&s.ID, &s.TokenHash, &s.Bucket, &s.Name, &s.CreatedBy, &createdAt, &expiresAt, &s.PasswordHash,
+		&s.MaxDownloads, &s.Downloads, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Share{}, ErrShareNotFound
+	}
+	if err != nil {
+		return Share{}, err
+	}
+	s.CreatedAt = time.Unix(0, createdAt).UTC()
+	s.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	if revokedAt != 0 {
+		s.RevokedAt = time.Unix(0, revokedAt).UTC()
+	}
+	return s, nil
+}
+
+// unixNano function    Nanoseconds of t, 0 for the zero time
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
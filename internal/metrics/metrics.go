@@ -0,0 +1,188 @@
+// Package metrics keeps counters and gauges of the service, served in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter = "counter"
+	typeGauge   = "gauge"
+)
+
+var registry = struct {
+	sync.Mutex
+	metrics map[string]*metric
+}{metrics: map[string]*metric{}}
+
+// metric    Values of a metric by label values, joined with \xff
+type metric struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Counter    Value that only goes up, e.g. number of events
+type Counter struct {
+	m *metric
+}
+
+// Gauge    Value that goes up and down, e.g. queue length
+type Gauge struct {
+	m *metric
+}
+
+// NewCounter function    Register a counter, the same name returns the same counter
+func NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{m: register(name, help, typeCounter, labels)}
+}
+
+// NewGauge function    Register a gauge, the same name returns the same gauge
+func NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{m: register(name, help, typeGauge, labels)}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.m.add(1, labelValues)
+}
+
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counters can't decrease")
+	}
+	c.m.add(v, labelValues)
+}
+
+// Value method    Current value, mostly for tests
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.m.value(labelValues)
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.m.set(v, labelValues)
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.m.add(1, labelValues)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.m.add(-1, labelValues)
+}
+
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.m.value(labelValues)
+}
+
+func register(name string, help string, kind string, labels []string) *metric {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if m, ok := registry.metrics[name]; ok {
+		if m.kind != kind || len(m.labels) != len(labels) {
+			panic(fmt.Sprintf("metrics: %s registered twice with different types or labels", name))
+		}
+		return m
+	}
+	m := &metric{name: name, help: help, kind: kind, labels: labels, values: map[string]float64{}}
+	registry.metrics[name] = m
+	return m
+}
+
+func (m *metric) key(labelValues []string) string {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metrics: %s has labels %v, got values %v", m.name, m.labels, labelValues))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (m *metric) add(v float64, labelValues []string) {
+	key := m.key(labelValues)
+	m.mu.Lock()
+	m.values[key] += v
+	m.mu.Unlock()
+}
+
+func (m *metric) set(v float64, labelValues []string) {
+	key := m.key(labelValues)
+	m.mu.Lock()
+	m.values[key] = v
+	m.mu.Unlock()
+}
+
+func (m *metric) value(labelValues []string) float64 {
+	key := m.key(labelValues)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key]
+}
+
+// write method    Text exposition of the metric, series sorted by label values
+func (m *metric) write(b *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", m.name, escapeHelp(m.help), m.name, m.kind)
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		b.WriteString(m.name)
+		if len(m.labels) > 0 {
+			b.WriteString("{")
+			for i, value := range strings.Split(key, "\xff") {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				fmt.Fprintf(b, `%s="%s"`, m.labels[i], escapeLabelValue(value))
+			}
+			b.WriteString("}")
+		}
+		b.WriteString(" " + strconv.FormatFloat(m.values[key], 'g', -1, 64) + "\n")
+	}
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Handler function    Serve all the metrics in the Prometheus text format, sorted by name
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry.Lock()
+		names := make([]string, 0, len(registry.metrics))
+		for name := range registry.metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		metrics := make([]*metric, 0, len(names))
+		for _, name := range names {
+			metrics = append(metrics, registry.metrics[name])
+		}
+		registry.Unlock()
+
+		var b strings.Builder
+		for _, m := range metrics {
+			m.write(&b)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(b.String()))
+	})
+}
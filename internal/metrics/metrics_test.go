@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	events := NewCounter("test_events_total", "Events by bucket", "bucket", "event")
+	queued := NewGauge("test_queued", "Queued jobs")
+
+	events.Inc("data", "s3:ObjectCreated:Put")
+	events.Add(2, "data", "s3:ObjectCreated:Put")
+	events.Inc(`quoted"bucket`, "s3:ObjectRemoved:Delete")
+	queued.Set(5)
+	queued.Dec()
+
+	if same := NewCounter("test_events_total", "Events by bucket", "bucket", "event"); same.Value("data", "s3:ObjectCreated:Put") != 3 {
+		t.Errorf("got a new counter for a registered name")
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+
+	tests := map[string]struct {
+		line string
+	}{
+		"help":          {line: "# HELP test_events_total Events by bucket"},
+		"type":          {line: "# TYPE test_queued gauge"},
+		"labels":        {line: `test_events_total{bucket="data",event="s3:ObjectCreated:Put"} 3`},
+		"escaped label": {line: `test_events_total{bucket="quoted\"bucket",event="s3:ObjectRemoved:Delete"} 1`},
+		"gauge":         {line: "test_queued 4"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if !strings.Contains(string(body), test.line+"\n") {
+				t.Errorf("missing %q in\n%s", test.line, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+)
+
+const AnonymousPrincipal = "anonymous"
+
+type principalKey struct{}
+
+// Principal    Identity of the client performing the request
+type Principal struct {
+	Name  string
+	Admin bool
+}
+
+// Auth middleware    Authenticate requests with an API key sent as "Authorization: Bearer <key>" or "X-API-Key: <key>".
+// When no api-keys are configured authentication is disabled and every request runs as anonymous, not an admin unless
+// auth.allow-anonymous-admin is set.
+func Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys := config.ServerConfigValues.Auth.ApiKeys
+		if len(apiKeys) == 0 {
+			anonymous := Principal{Name: AnonymousPrincipal, Admin: config.ServerConfigValues.Auth.AllowAnonymousAdmin}
+			next.ServeHTTP(w, WithPrincipal(r, anonymous))
+			return
+		}
+
+		key := r.Header.Get("X-API-Key")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			key = strings.TrimSpace(bearer)
+		}
+		if key == "" {
+			errorhandlers.UnauthorizedHandler(w, r)
+			return
+		}
+
+		for _, apiKey := range apiKeys {
+			if subtle.ConstantTimeCompare([]byte(apiKey.Key), []byte(key)) == 1 {
+				next.ServeHTTP(w, WithPrincipal(r, Principal{Name: apiKey.Principal, Admin: apiKey.Admin}))
+				return
+			}
+		}
+
+		errorhandlers.UnauthorizedHandler(w, r)
+	})
+}
+
+func WithPrincipal(r *http.Request, principal Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+}
+
+// PrincipalFromRequest function    Return the authenticated principal, anonymous if the request did not go through Auth
+func PrincipalFromRequest(r *http.Request) Principal {
+	principal, ok := r.Context().Value(principalKey{}).(Principal)
+	if !ok {
+		return Principal{Name: AnonymousPrincipal}
+	}
+	return principal
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+func TestAuth(t *testing.T) {
+	var principal Principal
+	handler := Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = PrincipalFromRequest(r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	apiKeys := []config.ApiKey{
+		{Key: "user-key", Principal: "alice"},
+		{Key: "admin-key", Principal: "ops", Admin: true},
+	}
+
+	tests := map[string]struct {
+		apiKeys        []config.ApiKey
+		anonymousAdmin bool
+		header         string
+		value          string
+		status         int
+		want           Principal
+	}{
+		"missing key":           {apiKeys: apiKeys, status: http.StatusUnauthorized},
+		"bad key":               {apiKeys: apiKeys, header: "X-API-Key", value: "guess", status: http.StatusUnauthorized},
+		"bad bearer":            {apiKeys: apiKeys, header: "Authorization", value: "Bearer guess", status: http.StatusUnauthorized},
+		"key of another scheme": {apiKeys: apiKeys, header: "Authorization", value: "Basic user-key", status: http.StatusUnauthorized},
+		"valid non-admin key":   {apiKeys: apiKeys, header: "X-API-Key", value: "user-key", status: http.StatusOK, want: Principal{Name: "alice"}},
+		"valid bearer":          {apiKeys: apiKeys, header: "Authorization", value: "Bearer user-key", status: http.StatusOK, want: Principal{Name: "alice"}},
+		"admin key":             {apiKeys: apiKeys, header: "X-API-Key", value: "admin-key", status: http.StatusOK, want: Principal{Name: "ops", Admin: true}},
+		"no keys configured":    {status: http.StatusOK, want: Principal{Name: AnonymousPrincipal}},
+		"anonymous admin":       {anonymousAdmin: true, status: http.StatusOK, want: Principal{Name: AnonymousPrincipal, Admin: true}},
+		"anonymous admin with keys": {
+			apiKeys: apiKeys, anonymousAdmin: true, status: http.StatusUnauthorized,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			config.ServerConfigValues.Auth.ApiKeys = test.apiKeys
+			config.ServerConfigValues.Auth.AllowAnonymousAdmin = test.anonymousAdmin
+			t.Cleanup(func() {
+				config.ServerConfigValues.Auth.ApiKeys = nil
+				config.ServerConfigValues.Auth.AllowAnonymousAdmin = false
+			})
+			principal = Principal{}
+
+			r := httptest.NewRequest(http.MethodGet, "/files", nil)
+			if test.header != "" {
+				r.Header.Set(test.header, test.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Fatalf("got %d, want %d", w.Code, test.status)
+			}
+			if principal != test.want {
+				t.Errorf("got principal %+v, want %+v", principal, test.want)
+			}
+		})
+	}
+}
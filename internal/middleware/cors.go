@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+var (
+	CorsAllowedMethods = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodOptions,
+	}
+	CorsAllowedHeaders = []string{
+		"Authorization",
+		"Content-Type",
+		"Content-Range",
+		"Upload-Offset",
+		"Content-MD5",
+		"X-Checksum-Sha256",
+		"X-Checksum-Crc32c",
+		"X-Tags",
+		"X-Expires-After",
+		"X-Share-Password",
+	}
+	CorsExposedHeaders = []string{
+		"ETag",
+		"Content-Range",
+		"Upload-Offset",
+		"Location",
+		"X-Checksum-Md5",
+		"X-Checksum-Sha256",
+		"X-Checksum-Crc32c",
+		"Retry-After",
+		"Content-Disposition",
+	}
+)
+
+// Cors middleware    Add CORS headers for the origins listed in cors-allowed-clients and answer preflight requests
+func Cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		serverConfig := config.ServerConfigValues.Server
+		allowed, ok := matchOrigin(origin, serverConfig.CorsAllowedClients)
+		if !ok {
+			if isPreflight(r) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A "*" match never carries credentials, otherwise any site could make credentialed requests
+		if allowed == "*" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if serverConfig.CorsAllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if !isPreflight(r) {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(CorsExposedHeaders, ", "))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(CorsAllowedMethods, ", "))
+
+		// Echo back the requested headers so that custom headers (e.g. x-meta-*) are accepted as well
+		requestedHeaders := r.Header.Get("Access-Control-Request-Headers")
+		if requestedHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", requestedHeaders)
+		} else {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(CorsAllowedHeaders, ", "))
+		}
+
+		if serverConfig.CorsMaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(serverConfig.CorsMaxAge))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// IsOriginAllowed function    Check origin against the allowed clients, which can contain wildcards (e.g. "*" or "https://*.example.com")
+func IsOriginAllowed(origin string, allowedClients []string) bool {
+	_, ok := matchOrigin(origin, allowedClients)
+	return ok
+}
+
+// matchOrigin function    First entry of the allowed clients matching origin
+func matchOrigin(origin string, allowedClients []string) (string, bool) {
+	for _, allowed := range allowedClients {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return allowed, true
+		}
+		matched, err := path.Match(strings.ToLower(allowed), strings.ToLower(origin))
+		if err == nil && matched {
+			return allowed, true
+		}
+	}
+	return "", false
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
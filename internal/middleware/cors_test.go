@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+func TestCors(t *testing.T) {
+	config.ServerConfigValues.Server.CorsAllowedClients = []string{"http://localhost:3000", "https://*.example.com"}
+	config.ServerConfigValues.Server.CorsAllowCredentials = true
+	config.ServerConfigValues.Server.CorsMaxAge = 600
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Cors(next)
+
+	newreq := func(method, origin, requestMethod string) *http.Request {
+		r := httptest.NewRequest(method, "/files", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			r.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		return r
+	}
+
+	tests := map[string]struct {
+		request     *http.Request
+		status      int
+		allowOrigin string
+		maxAge      string
+		expose      string
+	}{
+		"GET without origin": {
+			request: newreq("GET", "", ""),
+			status:  200,
+		},
+		"GET allowed origin": {
+			request:     newreq("GET", "http://localhost:3000", ""),
+			status:      200,
+			allowOrigin: "http://localhost:3000",
+			expose:      "ETag, Content-Range, Upload-Offset, Location, X-Checksum-Md5, X-Checksum-Sha256, X-Checksum-Crc32c, Retry-After, Content-Disposition",
+		},
+		"GET wildcard origin": {
+			request:     newreq("GET", "https://app.example.com", ""),
+			status:      200,
+			allowOrigin: "https://app.example.com",
+			expose:      "ETag, Content-Range, Upload-Offset, Location, X-Checksum-Md5, X-Checksum-Sha256, X-Checksum-Crc32c, Retry-After, Content-Disposition",
+		},
+		"GET not allowed origin": {
+			request: newreq("GET", "https://evil.com", ""),
+			status:  200,
+		},
+		"OPTIONS preflight allowed origin": {
+			request:     newreq("OPTIONS", "http://localhost:3000", "POST"),
+			status:      204,
+			allowOrigin: "http://localhost:3000",
+			maxAge:      "600",
+		},
+		"OPTIONS preflight not allowed origin": {
+			request: newreq("OPTIONS", "https://evil.com", "POST"),
+			status:  403,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, test.request)
+
+			if w.Code != test.status {
+				t.Errorf("got %d, want %d", w.Code, test.status)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != test.allowOrigin {
+				t.Errorf("got Access-Control-Allow-Origin %q, want %q", got, test.allowOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != test.maxAge {
+				t.Errorf("got Access-Control-Max-Age %q, want %q", got, test.maxAge)
+			}
+			if got := w.Header().Get("Access-Control-Expose-Headers"); got != test.expose {
+				t.Errorf("got Access-Control-Expose-Headers %q, want %q", got, test.expose)
+			}
+		})
+	}
+}
+
+func TestCorsWildcardCredentials(t *testing.T) {
+	config.ServerConfigValues.Server.CorsAllowedClients = []string{"http://localhost:3000", "*"}
+	config.ServerConfigValues.Server.CorsAllowCredentials = true
+
+	handler := Cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := map[string]struct {
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		"listed origin": {
+			origin:      "http://localhost:3000",
+			allowOrigin: "http://localhost:3000",
+			credentials: "true",
+		},
+		"wildcard origin": {
+			origin:      "https://evil.com",
+			allowOrigin: "*",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/files", nil)
+			r.Header.Set("Origin", test.origin)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != test.allowOrigin {
+				t.Errorf("got Access-Control-Allow-Origin %q, want %q", got, test.allowOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != test.credentials {
+				t.Errorf("got Access-Control-Allow-Credentials %q, want %q", got, test.credentials)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+	"github.com/pavva91/file-upload/internal/metrics"
+	"github.com/pavva91/file-upload/internal/ratelimit"
+)
+
+// Label of the limits in the metrics: the default of the principals without their own limits, and the global bandwidth
+const (
+	defaultLimit = "default"
+	globalLimit  = "global"
+)
+
+var (
+	requestRateLimit = metrics.NewGauge("file_upload_rate_limit_requests",
+		"Requests per second allowed to a principal, 0 for no limit", "principal")
+	bandwidthLimit = metrics.NewGauge("file_upload_rate_limit_bandwidth_bytes",
+		"Bytes per second of the uploads and of the downloads allowed to a principal (global for all of them together), 0 for no limit", "principal")
+	rateLimitedTotal = metrics.NewCounter("file_upload_rate_limited_requests_total",
+		"Requests refused with 429 Too Many Requests by principal", "principal")
+	throttledSeconds = metrics.NewCounter("file_upload_throttled_seconds_total",
+		"Seconds the uploads and downloads waited for the bandwidth limits by principal and direction: upload or download", "principal", "direction")
+)
+
+// RateLimiter    Request rate and bandwidth limits of the rate-limit section of the config, by principal.
+// The anonymous requests are limited by client address.
+type RateLimiter struct {
+	requests        *ratelimit.Buckets
+	uploads         *ratelimit.Buckets
+	downloads       *ratelimit.Buckets
+	globalUploads   *ratelimit.Bucket
+	globalDownloads *ratelimit.Bucket
+}
+
+// NewRateLimiter function    One rate limiter is shared by all the handlers, so that the limits apply to the whole API
+func NewRateLimiter() *RateLimiter {
+	rateLimit := config.ServerConfigValues.RateLimit
+	l := &RateLimiter{
+		requests:  ratelimit.NewBuckets(),
+		uploads:   ratelimit.NewBuckets(),
+		downloads: ratelimit.NewBuckets(),
+	}
+	if globalBandwidth := rateLimit.GlobalBandwidth; globalBandwidth > 0 {
+		l.globalUploads = ratelimit.NewBucket(float64(globalBandwidth), int(globalBandwidth))
+		l.globalDownloads = ratelimit.NewBucket(float64(globalBandwidth), int(globalBandwidth))
+	}
+
+	requestRateLimit.Set(max(0, rateLimit.Requests), defaultLimit)
+	bandwidthLimit.Set(float64(max(0, rateLimit.Bandwidth)), defaultLimit)
+	bandwidthLimit.Set(float64(max(0, rateLimit.GlobalBandwidth)), globalLimit)
+	for _, principalLimit := range rateLimit.Principals {
+		requests, _, bandwidth := principalLimits(principalLimit.Principal)
+		requestRateLimit.Set(max(0, requests), principalLimit.Principal)
+		bandwidthLimit.Set(float64(max(0, bandwidth)), principalLimit.Principal)
+	}
+	return l
+}
+
+// Handler method    Refuse the requests above the rate with 429 Too Many Requests and throttle the request and response bodies.
+// It goes after Auth, which sets the principal.
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := PrincipalFromRequest(r).Name
+		key := principal
+		if principal == AnonymousPrincipal {
+			key = principal + " " + ClientAddress(r)
+		}
+		requests, burst, bandwidth := principalLimits(principal)
+
+		if requests > 0 {
+			bucket := l.requests.Get(key, func() *ratelimit.Bucket {
+				return ratelimit.NewBucket(requests, burst)
+			})
+			if ok, retryAfter := bucket.Allow(); !ok {
+				rateLimitedTotal.Inc(principal)
+				errorhandlers.TooManyRequestsHandler(w, r, retryAfter)
+				return
+			}
+		}
+
+		var uploadBuckets, downloadBuckets []*ratelimit.Bucket
+		if bandwidth > 0 {
+			newBucket := func() *ratelimit.Bucket {
+				return ratelimit.NewBucket(float64(bandwidth), int(bandwidth))
+			}
+			uploadBuckets = append(uploadBuckets, l.uploads.Get(key, newBucket))
+			downloadBuckets = append(downloadBuckets, l.downloads.Get(key, newBucket))
+		}
+		if l.globalUploads != nil {
+			uploadBuckets = append(uploadBuckets, l.globalUploads)
+			downloadBuckets = append(downloadBuckets, l.globalDownloads)
+		}
+		if len(uploadBuckets) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			throttle := &ratelimit.Throttle{Context: r.Context(), Buckets: uploadBuckets, OnWait: func(d time.Duration) {
+				throttledSeconds.Add(d.Seconds(), principal, "upload")
+			}}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{ratelimit.NewReader(r.Body, throttle), r.Body}
+		}
+		throttle := &ratelimit.Throttle{Context: r.Context(), Buckets: downloadBuckets, OnWait: func(d time.Duration) {
+			throttledSeconds.Add(d.Seconds(), principal, "download")
+		}}
+		next.ServeHTTP(&throttledWriter{ResponseWriter: w, writer: ratelimit.NewWriter(w, throttle)}, r)
+	})
+}
+
+// principalLimits function    Requests per second, burst and bytes per second of a principal, not positive for no limit
+func principalLimits(principal string) (float64, int, int64) {
+	rateLimit := config.ServerConfigValues.RateLimit
+	requests, burst, bandwidth := rateLimit.Requests, rateLimit.Burst, rateLimit.Bandwidth
+	for _, principalLimit := range rateLimit.Principals {
+		if principalLimit.Principal != principal {
+			continue
+		}
+		if principalLimit.Requests != 0 {
+			requests = principalLimit.Requests
+		}
+		if principalLimit.Burst != 0 {
+			burst = principalLimit.Burst
+		}
+		if principalLimit.Bandwidth != 0 {
+			bandwidth = principalLimit.Bandwidth
+		}
+	}
+	if burst <= 0 {
+		burst = max(1, int(math.Ceil(requests)))
+	}
+	return requests, burst, bandwidth
+}
+
+// ClientAddress function    IP address of the client, proxies are not trusted
+func ClientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// throttledWriter    Response writer throttling the body, Unwrap keeps http.ResponseController working
+type throttledWriter struct {
+	http.ResponseWriter
+	writer io.Writer
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	previous := config.ServerConfigValues.RateLimit
+	t.Cleanup(func() { config.ServerConfigValues.RateLimit = previous })
+	config.ServerConfigValues.RateLimit.Requests = 0.5
+	config.ServerConfigValues.RateLimit.Burst = 0
+	config.ServerConfigValues.RateLimit.Bandwidth = 0
+	config.ServerConfigValues.RateLimit.GlobalBandwidth = 0
+	config.ServerConfigValues.RateLimit.Principals = []config.PrincipalRateLimit{
+		{Principal: "batch", Requests: 100, Burst: 3},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := NewRateLimiter().Handler(next)
+
+	// The steps share the buckets of the limiter, they run in order
+	steps := []struct {
+		name       string
+		principal  string
+		remoteAddr string
+		status     int
+		retryAfter string
+	}{
+		{name: "first request", principal: "alice", remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "above the rate", principal: "alice", remoteAddr: "10.0.0.1:1234", status: http.StatusTooManyRequests, retryAfter: "2"},
+		{name: "from another address", principal: "alice", remoteAddr: "10.0.0.2:1234", status: http.StatusTooManyRequests, retryAfter: "2"},
+		{name: "another principal", principal: "bob", remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "anonymous", principal: AnonymousPrincipal, remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "anonymous above the rate", principal: AnonymousPrincipal, remoteAddr: "10.0.0.1:5678", status: http.StatusTooManyRequests, retryAfter: "2"},
+		{name: "anonymous from another address", principal: AnonymousPrincipal, remoteAddr: "10.0.0.2:1234", status: http.StatusOK},
+		{name: "own burst 1", principal: "batch", remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "own burst 2", principal: "batch", remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "own burst 3", principal: "batch", remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "above the own burst", principal: "batch", remoteAddr: "10.0.0.1:1234", status: http.StatusTooManyRequests, retryAfter: "1"},
+	}
+
+	for _, step := range steps {
+		r := httptest.NewRequest(http.MethodGet, "/files", nil)
+		r.RemoteAddr = step.remoteAddr
+		r = WithPrincipal(r, Principal{Name: step.principal})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != step.status {
+			t.Errorf("%s: got %d, want %d", step.name, w.Code, step.status)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != step.retryAfter {
+			t.Errorf("%s: got Retry-After %q, want %q", step.name, retryAfter, step.retryAfter)
+		}
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	previous := config.ServerConfigValues.RateLimit
+	t.Cleanup(func() { config.ServerConfigValues.RateLimit = previous })
+	config.ServerConfigValues.RateLimit.Requests = 0
+	config.ServerConfigValues.RateLimit.Bandwidth = 0
+	config.ServerConfigValues.RateLimit.GlobalBandwidth = 0
+	config.ServerConfigValues.RateLimit.Principals = nil
+
+	var served *http.Request
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := NewRateLimiter().Handler(next)
+
+	for i := 0; i < 10; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/files", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want 200 without limits", i, w.Code)
+		}
+		if served != r {
+			t.Fatalf("request %d: got the request wrapped, want it passed through", i)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package notifications consumes the MinIO bucket notifications of the managed buckets and fans the object events
+// out to the internal consumers, so that changes made outside the API are picked up as well.
+package notifications
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/pavva91/file-upload/internal/metrics"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+// Events listened to
+var Events = []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"}
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute
+	defaultQueueSize  = 1000
+)
+
+var (
+	eventsTotal = metrics.NewCounter("file_upload_notification_events_total",
+		"Bucket notification events received", "bucket", "event")
+	reconnectsTotal = metrics.NewCounter("file_upload_notification_reconnects_total",
+		"Reconnections to the bucket notifications", "bucket")
+	droppedTotal = metrics.NewCounter("file_upload_notification_events_dropped_total",
+		"Events dropped because the queue of a consumer was full", "consumer")
+	consumedTotal = metrics.NewCounter("file_upload_notification_events_consumed_total",
+		"Events handled by a consumer", "consumer")
+)
+
+// Event    Object event of a bucket notification
+type Event struct {
+	// Name is the S3 event name, e.g. s3:ObjectCreated:Put or s3:ObjectRemoved:Delete
+	Name         string
+	Bucket       string
+	Key          string
+	Size         int64
+	ETag         string
+	ContentType  string
+	UserMetadata map[string]string
+	VersionID    string
+	Time         time.Time
+	// Principal is the access key of the request that made the change
+	Principal string
+}
+
+func (e Event) IsCreated() bool {
+	return strings.HasPrefix(e.Name, "s3:ObjectCreated:")
+}
+
+func (e Event) IsRemoved() bool {
+	return strings.HasPrefix(e.Name, "s3:ObjectRemoved:")
+}
+
+// Consumer    Handler of the events, called from a goroutine of its own in the order of the events
+type Consumer func(ctx context.Context, event Event)
+
+// ListenFunc    Notifications of a bucket, the channel is closed when the connection is lost
+type ListenFunc func(ctx context.Context, bucket string, events []string) <-chan notification.Info
+
+type consumerQueue struct {
+	name    string
+	consume Consumer
+	queue   chan Event
+}
+
+// Subscriber    One long-lived listener per bucket, reconnecting with exponential backoff
+type Subscriber struct {
+	Buckets []string
+	// Listen defaults to the ListenBucketNotification of storage.MinioClient
+	Listen     ListenFunc
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// QueueSize is the number of events buffered per consumer, events are dropped when it is full
+	QueueSize int
+
+	consumers []*consumerQueue
+}
+
+func NewSubscriber(buckets []string) *Subscriber {
+	return &Subscriber{
+		Buckets: buckets,
+		Listen: func(ctx context.Context, bucket string, events []string) <-chan notification.Info {
+			return storage.MinioClient.ListenBucketNotification(ctx, bucket, "", "", events)
+		},
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+		QueueSize:  defaultQueueSize,
+	}
+}
+
+// Subscribe method    Add a consumer of the events, before Run
+func (s *Subscriber) Subscribe(name string, consume Consumer) {
+	s.consumers = append(s.consumers, &consumerQueue{name: name, consume: consume})
+}
+
+// HasConsumers method    Whether a consumer subscribed, there is no point in listening otherwise
+func (s *Subscriber) HasConsumers() bool {
+	return len(s.consumers) > 0
+}
+
+// Run method    Listen to the buckets and dispatch the events until ctx is done, returns at once without consumers
+func (s *Subscriber) Run(ctx context.Context) {
+	if !s.HasConsumers() {
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for _, c := range s.consumers {
+		c.queue = make(chan Event, s.QueueSize)
+		wg.Add(1)
+		go func(c *consumerQueue) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-c.queue:
+					c.consume(ctx, event)
+					consumedTotal.Inc(c.name)
+				}
+			}
+		}(c)
+	}
+
+	for _, bucket := range s.Buckets {
+		wg.Add(1)
+		go func(bucket string) {
+			defer wg.Done()
+			s.listen(ctx, bucket)
+		}(bucket)
+	}
+
+	wg.Wait()
+}
+
+// listen method    Listen to a bucket, reconnecting when the connection is lost.
+// The backoff is reset by a connection that delivered events or lasted longer than the maximum backoff.
+func (s *Subscriber) listen(ctx context.Context, bucket string) {
+	backoff := s.MinBackoff
+	for {
+		connected := time.Now()
+		received := false
+		for info := range s.Listen(ctx, bucket, Events) {
+			if info.Err != nil {
+				log.Printf("notifications of bucket %s: %v", bucket, info.Err)
+				continue
+			}
+			for _, record := range info.Records {
+				received = true
+				s.dispatch(newEvent(record))
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if received || time.Since(connected) > s.MaxBackoff {
+			backoff = s.MinBackoff
+		}
+		reconnectsTotal.Inc(bucket)
+		log.Printf("notifications of bucket %s disconnected, reconnecting in %s", bucket, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, s.MaxBackoff)
+	}
+}
+
+// dispatch method    Queue the event to every consumer without waiting for the slow ones
+func (s *Subscriber) dispatch(event Event) {
+	eventsTotal.Inc(event.Bucket, event.Name)
+	for _, c := range s.consumers {
+		select {
+		case c.queue <- event:
+		default:
+			droppedTotal.Inc(c.name)
+			log.Printf("notifications: queue of %s full, dropped %s of %s", c.name, event.Name, event.Key)
+		}
+	}
+}
+
+// newEvent function    Event of a notification record, object keys are URL encoded in the records
+func newEvent(record notification.Event) Event {
+	key, err := url.QueryUnescape(record.S3.Object.Key)
+	if err != nil {
+		key = record.S3.Object.Key
+	}
+	eventTime, _ := time.Parse(time.RFC3339Nano, record.EventTime)
+
+	return Event{
+		Name:         record.EventName,
+		Bucket:       record.S3.Bucket.Name,
+		Key:          key,
+		Size:         record.S3.Object.Size,
+		ETag:         record.S3.Object.ETag,
+		ContentType:  record.S3.Object.ContentType,
+		UserMetadata: record.S3.Object.UserMetadata,
+		VersionID:    record.S3.Object.VersionID,
+		Time:         eventTime,
+		Principal:    record.UserIdentity.PrincipalID,
+	}
+}
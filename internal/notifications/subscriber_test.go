@@ -0,0 +1,146 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/notification"
+)
+
+func record(name string, bucket string, key string) notification.Event {
+	var e notification.Event
+	e.EventName = name
+	e.EventTime = "2024-03-01T10:00:00.000Z"
+	e.S3.Bucket.Name = bucket
+	e.S3.Object.Key = key
+	return e
+}
+
+func withPrincipal(e notification.Event, principal string) notification.Event {
+	e.UserIdentity.PrincipalID = principal
+	return e
+}
+
+func TestSubscriber(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	connections := 0
+
+	s := NewSubscriber([]string{"data"})
+	s.MinBackoff = time.Millisecond
+	s.MaxBackoff = 4 * time.Millisecond
+	s.Listen = func(ctx context.Context, bucket string, events []string) <-chan notification.Info {
+		mu.Lock()
+		connections++
+		connection := connections
+		mu.Unlock()
+
+		ch := make(chan notification.Info, 2)
+		switch connection {
+		case 1:
+			// Lost before any event
+			ch <- notification.Info{Err: errors.New("connection reset")}
+			close(ch)
+		case 2:
+			ch <- notification.Info{Records: []notification.Event{
+				withPrincipal(record("s3:ObjectCreated:Put", bucket, "reports/2024+Q1.csv"), "uploader"),
+				record("s3:ObjectRemoved:Delete", bucket, "reports%2Fold.csv"),
+			}}
+			close(ch)
+		default:
+			go func() {
+				<-ctx.Done()
+				close(ch)
+			}()
+		}
+		return ch
+	}
+
+	received := make(chan Event, 10)
+	s.Subscribe("test", func(ctx context.Context, event Event) {
+		received <- event
+	})
+
+	reconnects := reconnectsTotal.Value("data")
+	consumed := consumedTotal.Value("test")
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	var events []Event
+	for len(events) < 2 {
+		select {
+		case event := <-received:
+			events = append(events, event)
+		case <-ctx.Done():
+			t.Fatalf("got %d events, want 2", len(events))
+		}
+	}
+	// Wait for the third connection, listening until ctx is done
+	for {
+		mu.Lock()
+		n := connections
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	tests := map[string]struct {
+		got  interface{}
+		want interface{}
+	}{
+		"keys unescaped": {got: []string{events[0].Key, events[1].Key}, want: []string{"reports/2024 Q1.csv", "reports/old.csv"}},
+		"created":        {got: events[0].IsCreated() && !events[0].IsRemoved(), want: true},
+		"removed":        {got: events[1].IsRemoved(), want: true},
+		"time":           {got: events[0].Time, want: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		"reconnects":     {got: reconnectsTotal.Value("data") - reconnects, want: float64(2)},
+		"events counted": {got: eventsTotal.Value("data", "s3:ObjectCreated:Put") >= 1, want: true},
+		"principal":      {got: events[0].Principal, want: "uploader"},
+		"consumed":       {got: consumedTotal.Value("test")-consumed >= 1, want: true},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.got, test.want) {
+				t.Errorf("got %v, want %v", test.got, test.want)
+			}
+		})
+	}
+}
+
+func TestSubscriberWithoutConsumers(t *testing.T) {
+	s := NewSubscriber([]string{"data"})
+	s.Listen = func(ctx context.Context, bucket string, events []string) <-chan notification.Info {
+		t.Error("listened without consumers")
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return without consumers")
+	}
+	if s.HasConsumers() {
+		t.Error("got consumers, want none")
+	}
+}
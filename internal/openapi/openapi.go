@@ -0,0 +1,72 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"log"
+	"net/http"
+
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+// Swagger UI 4.15.5 dist files (Apache License 2.0), served under /docs/ so that the page doesn't depend on a CDN
+//
+//go:embed swagger-ui
+var swaggerUIAssets embed.FS
+
+// SpecHandler    Serve the OpenAPI document with the server url set to the API base path
+type SpecHandler struct {
+	BasePath string
+}
+
+func (h *SpecHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var spec map[string]interface{}
+	err := json.Unmarshal(Spec, &spec)
+	if err != nil {
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	serverURL := h.BasePath
+	if serverURL == "" {
+		serverURL = "/"
+	}
+	spec["servers"] = []map[string]string{{"url": serverURL}}
+
+	js, err := json.Marshal(spec)
+	if err != nil {
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// SwaggerUIHandler    Serve a Swagger UI page rendering /openapi.json at /docs and its assets under /docs/
+type SwaggerUIHandler struct{}
+
+func (h *SwaggerUIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/docs" || r.URL.Path == "/docs/" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(swaggerUI)
+		return
+	}
+
+	assets, err := fs.Sub(swaggerUIAssets, "swagger-ui")
+	if err != nil {
+		log.Println(err)
+		errorhandlers.InternalServerErrorHandler(w, r)
+		return
+	}
+	http.StripPrefix("/docs/", http.FileServer(http.FS(assets))).ServeHTTP(w, r)
+}
@@ -0,0 +1,63 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSwaggerUIHandler(t *testing.T) {
+	tests := map[string]struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		"page": {
+			path:        "/docs",
+			status:      http.StatusOK,
+			contentType: "text/html",
+		},
+		"page with trailing slash": {
+			path:        "/docs/",
+			status:      http.StatusOK,
+			contentType: "text/html",
+		},
+		"bundle": {
+			path:        "/docs/swagger-ui-bundle.js",
+			status:      http.StatusOK,
+			contentType: "javascript",
+		},
+		"stylesheet": {
+			path:        "/docs/swagger-ui.css",
+			status:      http.StatusOK,
+			contentType: "text/css",
+		},
+		"missing asset": {
+			path:   "/docs/missing.js",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			(&SwaggerUIHandler{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			if w.Code != test.status {
+				t.Errorf("got %d, want %d", w.Code, test.status)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.Contains(got, test.contentType) {
+				t.Errorf("got Content-Type %q, want %q", got, test.contentType)
+			}
+		})
+	}
+}
+
+func TestSwaggerUIPageIsSelfHosted(t *testing.T) {
+	if strings.Contains(string(swaggerUI), "https://") {
+		t.Error("the Swagger UI page loads assets from another origin")
+	}
+}
@@ -0,0 +1,79 @@
+package processing
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Register("digest", newDigest)
+}
+
+var digestAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// digest    Record the hex digests of the content in the job result, e.g. for integrity audits
+type digest struct {
+	algorithms []string
+}
+
+// newDigest function    Options: algorithms, comma separated among md5, sha1 and sha256 (default sha256)
+func newDigest(options map[string]string) (Processor, error) {
+	d := &digest{algorithms: []string{"sha256"}}
+	if algorithms := options["algorithms"]; algorithms != "" {
+		d.algorithms = strings.Split(algorithms, ",")
+	}
+	for i, algorithm := range d.algorithms {
+		algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+		if _, ok := digestAlgorithms[algorithm]; !ok {
+			return nil, fmt.Errorf("digest: unknown algorithm %s", algorithm)
+		}
+		d.algorithms[i] = algorithm
+	}
+	sort.Strings(d.algorithms)
+	return d, nil
+}
+
+func (d *digest) Name() string {
+	return "digest"
+}
+
+func (d *digest) Accepts(object Object) bool {
+	return true
+}
+
+func (d *digest) Process(ctx context.Context, storage Storage, object Object) (Result, error) {
+	content, err := storage.Open(ctx, object.Bucket, object.Name)
+	if err != nil {
+		return Result{}, err
+	}
+	defer content.Close()
+
+	hashes := make([]hash.Hash, len(d.algorithms))
+	writers := make([]io.Writer, len(d.algorithms))
+	for i, algorithm := range d.algorithms {
+		hashes[i] = digestAlgorithms[algorithm]()
+		writers[i] = hashes[i]
+	}
+	_, err = io.Copy(io.MultiWriter(writers...), content)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Metadata: make(map[string]string, len(d.algorithms))}
+	for i, algorithm := range d.algorithms {
+		result.Metadata[algorithm] = hex.EncodeToString(hashes[i].Sum(nil))
+	}
+	return result, nil
+}
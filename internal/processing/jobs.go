@@ -0,0 +1,220 @@
+package processing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const jobsSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	bucket       TEXT    NOT NULL,
+	name         TEXT    NOT NULL,
+	etag         TEXT    NOT NULL,
+	size         INTEGER NOT NULL,
+	content_type TEXT    NOT NULL,
+	processor    TEXT    NOT NULL,
+	status       TEXT    NOT NULL,
+	attempts     INTEGER NOT NULL,
+	error        TEXT    NOT NULL,
+	result       TEXT    NOT NULL,
+	created_at   INTEGER NOT NULL,
+	updated_at   INTEGER NOT NULL,
+	next_attempt INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS jobs_object ON jobs (bucket, name);
+CREATE INDEX IF NOT EXISTS jobs_queue ON jobs (status, next_attempt);
+`
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job    Run of a processor on a version (ETag) of an object
+type Job struct {
+	ID          int64      `json:"id"`
+	Bucket      string     `json:"bucket"`
+	Name        string     `json:"name"`
+	ETag        string     `json:"etag"`
+	Processor   string     `json:"processor"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	Result      *Result    `json:"result,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	NextAttempt *time.Time `json:"nextAttempt,omitempty"`
+
+	size        int64
+	contentType string
+}
+
+// Object method    Object the job processes
+func (j Job) Object() Object {
+	return Object{Bucket: j.Bucket, Name: j.Name, ETag: j.ETag, Size: j.size, ContentType: j.contentType}
+}
+
+// Jobs    SQLite store of the jobs, they survive restarts
+type Jobs struct {
+	db *sql.DB
+}
+
+// OpenJobs function    Open or create the jobs database at path
+func OpenJobs(path string) (*Jobs, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, err
+	}
+	// Writes are serialized by SQLite, a single connection avoids SQLITE_BUSY between them
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(jobsSchema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("jobs schema: %w", err)
+	}
+	return &Jobs{db: db}, nil
+}
+
+func (js *Jobs) Close() error {
+	return js.db.Close()
+}
+
+// Create method    Queue a job per processor for an object
+func (js *Jobs) Create(ctx context.Context, object Object, processors ...string) error {
+	tx, err := js.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	for _, processor := range processors {
+		_, err := tx.ExecContext(ctx, `INSERT INTO jobs
+			(bucket, name, etag, size, content_type, processor, status, attempts, error, result, created_at, updated_at, next_attempt)
+			VALUES (?, ?, ?, ?, ?, ?, ?, 0, '', '', ?, ?, ?)`,
+			object.Bucket, object.Name, object.ETag, object.Size, object.ContentType, processor, StatusQueued, now, now, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Claim method    Mark the oldest due queued job as running, ok is false when no job is due
+func (js *Jobs) Claim(ctx context.Context) (job Job, ok bool, err error) {
+	now := time.Now().UnixNano()
+	row := js.db.QueryRowContext(ctx, `UPDATE jobs SET status = ?, attempts = attempts + 1, updated_at = ?
+		WHERE id = (SELECT id FROM jobs WHERE status = ? AND next_attempt <= ? ORDER BY next_attempt, id LIMIT 1)
+		RETURNING `+jobColumns, StatusRunning, now, StatusQueued, now)
+	job, err = scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, false, nil
+	}
+	if err != nil {
+		return Job{}, false, err
+	}
+	return job, true, nil
+}
+
+// NextAttempt method    When the next queued job is due, zero when the queue is empty
+func (js *Jobs) NextAttempt(ctx context.Context) (time.Time, error) {
+	var next sql.NullInt64
+	err := js.db.QueryRowContext(ctx, `SELECT MIN(next_attempt) FROM jobs WHERE status = ?`, StatusQueued).Scan(&next)
+	if err != nil || !next.Valid {
+		return time.Time{}, err
+	}
+	return time.Unix(0, next.Int64), nil
+}
+
+// Succeed method    Record the result of a job
+func (js *Jobs) Succeed(ctx context.Context, id int64, result Result) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = js.db.ExecContext(ctx, `UPDATE jobs SET status = ?, error = '', result = ?, updated_at = ? WHERE id = ?`,
+		StatusSucceeded, string(resultJSON), time.Now().UnixNano(), id)
+	return err
+}
+
+// Fail method    Queue a failed job again at retryAt, or mark it failed when retryAt is zero
+func (js *Jobs) Fail(ctx context.Context, id int64, jobErr error, retryAt time.Time) error {
+	status := StatusFailed
+	var next int64
+	if !retryAt.IsZero() {
+		status = StatusQueued
+		next = retryAt.UnixNano()
+	}
+	_, err := js.db.ExecContext(ctx, `UPDATE jobs SET status = ?, error = ?, updated_at = ?, next_attempt = ? WHERE id = ?`,
+		status, jobErr.Error(), time.Now().UnixNano(), next, id)
+	return err
+}
+
+// Requeue method    Queue again the jobs left running by a stop of the server
+func (js *Jobs) Requeue(ctx context.Context) (int64, error) {
+	now := time.Now().UnixNano()
+	result, err := js.db.ExecContext(ctx, `UPDATE jobs SET status = ?, updated_at = ?, next_attempt = ? WHERE status = ?`,
+		StatusQueued, now, now, StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// List method    Jobs of an object, newest first
+func (js *Jobs) List(ctx context.Context, bucket string, name string) ([]Job, error) {
+	rows, err := js.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE bucket = ? AND name = ? ORDER BY id DESC`, bucket, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+const jobColumns = `id, bucket, name, etag, size, content_type, processor, status, attempts, error, result, created_at, updated_at, next_attempt`
+
+func scanJob(row interface{ Scan(...any) error }) (Job, error) {
+	var job Job
+	var result string
+	var createdAt, updatedAt, nextAttempt int64
+	err := row.Scan(&job.ID, &job.Bucket, &job.Name, &job.ETag, &job.size, &job.contentType, &job.Processor, &job.Status,
+		&job.Attempts, &job.Error, &result, &createdAt, &updatedAt, &nextAttempt)
+	if err != nil {
+		return Job{}, err
+	}
+
+	if result != "" {
+		job.Result = &Result{}
+		err = json.Unmarshal([]byte(result), job.Result)
+		if err != nil {
+			return Job{}, err
+		}
+	}
+	job.CreatedAt = time.Unix(0, createdAt).UTC()
+	job.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	if job.Status == StatusQueued {
+		next := time.Unix(0, nextAttempt).UTC()
+		job.NextAttempt = &next
+	}
+	return job, nil
+}
@@ -0,0 +1,265 @@
+// Package processing runs processors on the uploaded objects in the background.
+// Every upload queues a job per interested processor, the jobs are kept in SQLite and run
+// by a bounded pool of workers, failed jobs are retried with exponential backoff.
+package processing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavva91/file-upload/internal/metrics"
+)
+
+const (
+	defaultWorkers      = 2
+	defaultMaxAttempts  = 3
+	defaultMinBackoff   = 10 * time.Second
+	defaultMaxBackoff   = 10 * time.Minute
+	defaultPollInterval = time.Minute
+
+	// Pending wakeups of the idle workers, more than the workers would only cause empty looks at the queue
+	maxWakeups = 64
+)
+
+var jobsTotal = metrics.NewCounter("file_upload_processing_jobs_total",
+	"Processing job runs by result: succeeded, retried or failed", "processor", "result")
+
+// Object    Uploaded object handed to the processors
+type Object struct {
+	Bucket      string
+	Name        string
+	ETag        string
+	Size        int64
+	ContentType string
+}
+
+// Result    Output of a processor, stored with its job
+type Result struct {
+	// Outputs are the names of the objects written by the processor, e.g. thumbnails
+	Outputs  []string          `json:"outputs,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Storage    Objects as seen by the processors
+type Storage interface {
+	// Open reads the content of an object
+	Open(ctx context.Context, bucket string, name string) (io.ReadCloser, error)
+	// Put writes a derived object of source in its bucket, encrypted like source. Derived objects are not processed themselves.
+	Put(ctx context.Context, source Object, name string, content io.Reader, size int64, contentType string) error
+}
+
+// Processor    Step run after an upload, it can read the object and write derived objects or metadata
+type Processor interface {
+	// Name identifies the processor in the jobs, it must not change between restarts
+	Name() string
+	// Accepts reports whether the processor runs on an object, e.g. only on images
+	Accepts(object Object) bool
+	Process(ctx context.Context, storage Storage, object Object) (Result, error)
+}
+
+// Factory    Build a processor from the options of its config entry
+type Factory func(options map[string]string) (Processor, error)
+
+var factories = map[string]Factory{}
+
+// Register function    Make a processor available to the config under name, called from init functions
+func Register(name string, factory Factory) {
+	if _, ok := factories[name]; ok {
+		panic("processing: processor registered twice: " + name)
+	}
+	factories[name] = factory
+}
+
+// New function    Processor registered under name
+func New(name string, options map[string]string) (Processor, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown processor %s, available: %v", name, Available())
+	}
+	return factory(options)
+}
+
+// Available function    Names of the registered processors
+func Available() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Pipeline    Queue of the jobs and the workers running them
+type Pipeline struct {
+	Jobs       *Jobs
+	Storage    Storage
+	Processors []Processor
+	Workers    int
+	// MaxAttempts of a job before it is marked failed
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	// PollInterval bounds the wait of an idle worker between two looks at the queue
+	PollInterval time.Duration
+
+	wake chan struct{}
+}
+
+func NewPipeline(jobs *Jobs, storage Storage, processors []Processor) *Pipeline {
+	return &Pipeline{
+		Jobs:         jobs,
+		Storage:      storage,
+		Processors:   processors,
+		Workers:      defaultWorkers,
+		MaxAttempts:  defaultMaxAttempts,
+		MinBackoff:   defaultMinBackoff,
+		MaxBackoff:   defaultMaxBackoff,
+		PollInterval: defaultPollInterval,
+		wake:         make(chan struct{}, maxWakeups),
+	}
+}
+
+// Enqueue method    Queue a job for every processor accepting the object
+func (p *Pipeline) Enqueue(ctx context.Context, object Object) error {
+	var names []string
+	for _, processor := range p.Processors {
+		if processor.Accepts(object) {
+			names = append(names, processor.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	err := p.Jobs.Create(ctx, object, names...)
+	if err != nil {
+		return err
+	}
+	p.notify(len(names))
+	return nil
+}
+
+// Run method    Queue again the jobs interrupted by the last stop and run the workers until ctx is done
+func (p *Pipeline) Run(ctx context.Context) {
+	requeued, err := p.Jobs.Requeue(ctx)
+	if err != nil {
+		log.Println("processing:", err)
+	}
+	if requeued > 0 {
+		log.Printf("processing: %d interrupted jobs queued again", requeued)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(p.Workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work method    Run the due jobs one at a time, waiting for new ones when the queue is empty
+func (p *Pipeline) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, ok, err := p.Jobs.Claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Println("processing:", err)
+		}
+		if ok {
+			p.run(ctx, job)
+			continue
+		}
+
+		wait := p.PollInterval
+		next, err := p.Jobs.NextAttempt(ctx)
+		if err == nil && !next.IsZero() {
+			wait = min(wait, max(time.Until(next), 0))
+		}
+		select {
+		case <-ctx.Done():
+		case <-p.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// run method    Process a claimed job and record its outcome
+func (p *Pipeline) run(ctx context.Context, job Job) {
+	var processor Processor
+	for _, candidate := range p.Processors {
+		if candidate.Name() == job.Processor {
+			processor = candidate
+		}
+	}
+
+	var result Result
+	var err error
+	if processor == nil {
+		err = fmt.Errorf("processor %s is not configured", job.Processor)
+	} else {
+		result, err = process(ctx, processor, p.Storage, job.Object())
+	}
+	// Jobs interrupted by the stop are queued again by the next Run
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		jobsTotal.Inc(job.Processor, "succeeded")
+		err = p.Jobs.Succeed(ctx, job.ID, result)
+		if err != nil {
+			log.Println("processing:", err)
+		}
+		return
+	}
+
+	var retryAt time.Time
+	if processor != nil && job.Attempts < p.MaxAttempts {
+		retryAt = time.Now().Add(p.backoff(job.Attempts))
+		jobsTotal.Inc(job.Processor, "retried")
+	} else {
+		jobsTotal.Inc(job.Processor, "failed")
+		log.Printf("processing: %s of %s failed after %d attempts: %v", job.Processor, job.Name, job.Attempts, err)
+	}
+	err = p.Jobs.Fail(ctx, job.ID, err, retryAt)
+	if err != nil {
+		log.Println("processing:", err)
+	}
+}
+
+// process function    Run a processor, a panic fails the job instead of the server
+func process(ctx context.Context, processor Processor, storage Storage, object Object) (result Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("processor %s panicked: %v", processor.Name(), r)
+		}
+	}()
+	return processor.Process(ctx, storage, object)
+}
+
+// backoff method    Wait before the next attempt, doubling from MinBackoff up to MaxBackoff
+func (p *Pipeline) backoff(attempts int) time.Duration {
+	backoff := p.MinBackoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.MaxBackoff)
+}
+
+// notify method    Wake up to n idle workers
+func (p *Pipeline) notify(n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStorage    Storage of the objects in a map by bucket/name
+type memoryStorage struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (s *memoryStorage) Open(ctx context.Context, bucket string, name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.objects[bucket+"/"+name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func (s *memoryStorage) Put(ctx context.Context, source Object, name string, content io.Reader, size int64, contentType string) error {
+	var buf bytes.Buffer
+	_, err := io.Copy(&buf, content)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[source.Bucket+"/"+name] = buf.String()
+	return err
+}
+
+// upperProcessor    Write an upper case copy of text objects, failing the first failures runs
+type upperProcessor struct {
+	mu       sync.Mutex
+	failures int
+}
+
+func (p *upperProcessor) Name() string { return "upper" }
+
+func (p *upperProcessor) Accepts(object Object) bool {
+	return strings.HasPrefix(object.ContentType, "text/")
+}
+
+func (p *upperProcessor) Process(ctx context.Context, storage Storage, object Object) (Result, error) {
+	p.mu.Lock()
+	if p.failures > 0 {
+		p.failures--
+		p.mu.Unlock()
+		return Result{}, errors.New("temporary failure")
+	}
+	p.mu.Unlock()
+
+	content, err := storage.Open(ctx, object.Bucket, object.Name)
+	if err != nil {
+		return Result{}, err
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return Result{}, err
+	}
+
+	output := object.Name + ".upper"
+	err = storage.Put(ctx, object, output, strings.NewReader(strings.ToUpper(string(data))), int64(len(data)), object.ContentType)
+	return Result{Outputs: []string{output}}, err
+}
+
+type panicProcessor struct{}
+
+func (panicProcessor) Name() string               { return "panic" }
+func (panicProcessor) Accepts(object Object) bool { return true }
+func (panicProcessor) Process(ctx context.Context, storage Storage, object Object) (Result, error) {
+	panic("boom")
+}
+
+func openTestJobs(t *testing.T) *Jobs {
+	jobs, err := OpenJobs(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { jobs.Close() })
+	return jobs
+}
+
+// waitJobs function    Jobs of an object once none of them is queued or running
+func waitJobs(t *testing.T, jobs *Jobs, bucket string, name string) map[string]Job {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		list, err := jobs.List(context.Background(), bucket, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := map[string]Job{}
+		for _, job := range list {
+			if job.Status == StatusSucceeded || job.Status == StatusFailed {
+				done[job.Processor] = job
+			}
+		}
+		if len(done) == len(list) {
+			return done
+		}
+	}
+	t.Fatalf("jobs of %s still pending", name)
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := &memoryStorage{objects: map[string]string{"data/a.txt": "hello", "data/b.bin": "\x00\x01"}}
+	digestProcessor, err := New("digest", map[string]string{"algorithms": "sha256, md5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := openTestJobs(t)
+	pipeline := NewPipeline(jobs, storage, []Processor{&upperProcessor{failures: 1}, panicProcessor{}, digestProcessor})
+	pipeline.MinBackoff = time.Millisecond
+	pipeline.MaxBackoff = time.Millisecond
+	go pipeline.Run(ctx)
+
+	err = pipeline.Enqueue(ctx, Object{Bucket: "data", Name: "a.txt", ContentType: "text/plain", Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pipeline.Enqueue(ctx, Object{Bucket: "data", Name: "b.bin", ContentType: "application/octet-stream", Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		name      string
+		processor string
+		status    string
+		attempts  int
+		check     func(t *testing.T, job Job)
+	}{
+		"retried until succeeded": {
+			name: "a.txt", processor: "upper", status: StatusSucceeded, attempts: 2,
+			check: func(t *testing.T, job Job) {
+				if job.Result == nil || len(job.Result.Outputs) != 1 || storage.objects["data/a.txt.upper"] != "HELLO" {
+					t.Errorf("got %+v and %q, want the a.txt.upper output", job.Result, storage.objects["data/a.txt.upper"])
+				}
+			},
+		},
+		"panic fails the job": {
+			name: "a.txt", processor: "panic", status: StatusFailed, attempts: 3,
+			check: func(t *testing.T, job Job) {
+				if !strings.Contains(job.Error, "panicked: boom") {
+					t.Errorf("got error %q, want the panic", job.Error)
+				}
+			},
+		},
+		"metadata result": {
+			name: "a.txt", processor: "digest", status: StatusSucceeded, attempts: 1,
+			check: func(t *testing.T, job Job) {
+				want := map[string]string{
+					"md5":    "5d41402abc4b2a76b9719d911017c592",
+					"sha256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				}
+				if job.Result == nil || len(job.Result.Metadata) != 2 || job.Result.Metadata["md5"] != want["md5"] || job.Result.Metadata["sha256"] != want["sha256"] {
+					t.Errorf("got %+v, want %v", job.Result, want)
+				}
+			},
+		},
+		"not accepted": {name: "b.bin", processor: "upper"},
+	}
+
+	done := map[string]map[string]Job{
+		"a.txt": waitJobs(t, jobs, "data", "a.txt"),
+		"b.bin": waitJobs(t, jobs, "data", "b.bin"),
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			job, ok := done[test.name][test.processor]
+			if test.status == "" {
+				if ok {
+					t.Errorf("got %+v, want no job", job)
+				}
+				return
+			}
+			if !ok || job.Status != test.status || job.Attempts != test.attempts {
+				t.Fatalf("got %+v, want %s after %d attempts", job, test.status, test.attempts)
+			}
+			if test.check != nil {
+				test.check(t, job)
+			}
+		})
+	}
+}
+
+func TestJobsRequeue(t *testing.T) {
+	ctx := context.Background()
+	jobs := openTestJobs(t)
+
+	err := jobs.Create(ctx, Object{Bucket: "data", Name: "a.txt", ETag: "e1"}, "digest", "upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed, ok, err := jobs.Claim(ctx)
+	if err != nil || !ok || claimed.Status != StatusRunning || claimed.Attempts != 1 {
+		t.Fatalf("got %+v, %v (%v), want a running job", claimed, ok, err)
+	}
+
+	// Stopped while running, the next start queues it again
+	requeued, err := jobs.Requeue(ctx)
+	if err != nil || requeued != 1 {
+		t.Errorf("got %d requeued (%v), want 1", requeued, err)
+	}
+
+	list, err := jobs.List(ctx, "data", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range list {
+		if job.Status != StatusQueued || job.NextAttempt == nil || job.ETag != "e1" {
+			t.Errorf("got %+v, want queued", job)
+		}
+	}
+
+	err = jobs.Fail(ctx, claimed.ID, errors.New("later"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := jobs.NextAttempt(ctx)
+	if err != nil || next.IsZero() || next.After(time.Now().Add(time.Minute)) {
+		t.Errorf("got next attempt %v (%v), want the other job due now", next, err)
+	}
+}
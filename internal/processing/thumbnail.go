@@ -0,0 +1,198 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // GIF decoder
+	"image/jpeg"
+	"image/png"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pavva91/file-upload/internal/dto"
+)
+
+func init() {
+	Register("thumbnail", newThumbnail)
+}
+
+const (
+	defaultThumbnailSizes   = "256"
+	defaultThumbnailQuality = jpeg.DefaultQuality
+	// Decoded images take 4 bytes per pixel, larger images are refused before decoding
+	defaultThumbnailMaxPixels = 40_000_000
+)
+
+// VariantPrefixThumbnail    Variants of the thumbnails, thumb-{size}
+const VariantPrefixThumbnail = "thumb-"
+
+var validVariant = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidVariant function    Variant names are lower case letters, digits and dashes, e.g. thumb-256
+func ValidVariant(variant string) bool {
+	return validVariant.MatchString(variant)
+}
+
+// VariantName function    Name of the derived object of a variant, under the reserved dto.VariantPrefix so that it can't
+// collide with the uploads, e.g. .variants/photos/cat.jpg.thumb-256
+func VariantName(name string, variant string) string {
+	return dto.VariantPrefix + name + "." + variant
+}
+
+// thumbnail    Scale images down to fit squares of the configured sizes, JPEG images get JPEG thumbnails and the others PNG ones to keep their transparency
+type thumbnail struct {
+	sizes     []int
+	quality   int
+	maxPixels int
+}
+
+// newThumbnail function    Options: sizes, comma separated pixels (default 256), quality of the JPEG thumbnails (default 75)
+// and max-pixels of the source images (default 40000000)
+func newThumbnail(options map[string]string) (Processor, error) {
+	t := &thumbnail{quality: defaultThumbnailQuality, maxPixels: defaultThumbnailMaxPixels}
+
+	sizes := options["sizes"]
+	if sizes == "" {
+		sizes = defaultThumbnailSizes
+	}
+	for _, s := range strings.Split(sizes, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("thumbnail: invalid size %q", s)
+		}
+		t.sizes = append(t.sizes, size)
+	}
+	sort.Ints(t.sizes)
+
+	if quality := options["quality"]; quality != "" {
+		q, err := strconv.Atoi(quality)
+		if err != nil || q < 1 || q > 100 {
+			return nil, fmt.Errorf("thumbnail: invalid quality %q, 1 to 100", quality)
+		}
+		t.quality = q
+	}
+	if maxPixels := options["max-pixels"]; maxPixels != "" {
+		m, err := strconv.Atoi(maxPixels)
+		if err != nil || m <= 0 {
+			return nil, fmt.Errorf("thumbnail: invalid max-pixels %q", maxPixels)
+		}
+		t.maxPixels = m
+	}
+	return t, nil
+}
+
+func (t *thumbnail) Name() string {
+	return "thumbnail"
+}
+
+func (t *thumbnail) Accepts(object Object) bool {
+	mediaType, _, _ := strings.Cut(object.ContentType, ";")
+	switch strings.TrimSpace(strings.ToLower(mediaType)) {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+func (t *thumbnail) Process(ctx context.Context, storage Storage, object Object) (Result, error) {
+	// The dimensions are checked on a first read, so that huge images are not decoded
+	content, err := storage.Open(ctx, object.Bucket, object.Name)
+	if err != nil {
+		return Result{}, err
+	}
+	cfg, _, err := image.DecodeConfig(content)
+	content.Close()
+	if err != nil {
+		return Result{}, fmt.Errorf("thumbnail: %w", err)
+	}
+	if cfg.Width*cfg.Height > t.maxPixels {
+		return Result{}, fmt.Errorf("thumbnail: image of %dx%d pixels exceeds %d pixels", cfg.Width, cfg.Height, t.maxPixels)
+	}
+
+	content, err = storage.Open(ctx, object.Bucket, object.Name)
+	if err != nil {
+		return Result{}, err
+	}
+	defer content.Close()
+	src, format, err := image.Decode(content)
+	if err != nil {
+		return Result{}, fmt.Errorf("thumbnail: %w", err)
+	}
+
+	result := Result{}
+	for _, size := range t.sizes {
+		thumb := resize(src, size)
+
+		var buf bytes.Buffer
+		contentType := "image/jpeg"
+		if format == "jpeg" {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: t.quality})
+		} else {
+			contentType = "image/png"
+			err = png.Encode(&buf, thumb)
+		}
+		if err != nil {
+			return Result{}, err
+		}
+
+		name := VariantName(object.Name, VariantPrefixThumbnail+strconv.Itoa(size))
+		err = storage.Put(ctx, object, name, &buf, int64(buf.Len()), contentType)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Outputs = append(result.Outputs, name)
+	}
+	return result, nil
+}
+
+// resize function    Fit the image in a size x size square keeping its aspect ratio, each pixel is the average
+// of the source pixels it covers. Images already fitting are only converted.
+func resize(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	// Premultiplied alpha, so that transparent pixels don't bleed their color
+	rgba := image.NewRGBA(image.Rect(0, 0, srcWidth, srcHeight))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	if srcWidth <= size && srcHeight <= size {
+		return rgba
+	}
+
+	width, height := size, size
+	if srcWidth > srcHeight {
+		height = max(srcHeight*size/srcWidth, 1)
+	} else {
+		width = max(srcWidth*size/srcHeight, 1)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
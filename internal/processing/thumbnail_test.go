@@ -0,0 +1,108 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodeImage(t *testing.T, format string, width int, height int) string {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestThumbnail(t *testing.T) {
+	processor, err := New("thumbnail", map[string]string{"sizes": "256, 64", "max-pixels": "200000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &memoryStorage{objects: map[string]string{
+		"data/wide.png":  encodeImage(t, "png", 600, 300),
+		"data/small.jpg": encodeImage(t, "jpeg", 100, 50),
+		"data/huge.png":  encodeImage(t, "png", 1000, 250),
+		"data/fake.png":  "not an image",
+	}}
+
+	tests := map[string]struct {
+		name        string
+		contentType string
+		accepted    bool
+		// sizes of the thumb-64 and thumb-256 variants
+		sizes  [2]image.Point
+		format string
+		err    string
+	}{
+		"png scaled down": {
+			name: "wide.png", contentType: "image/png", accepted: true,
+			sizes: [2]image.Point{{64, 32}, {256, 128}}, format: "png",
+		},
+		"small jpeg not scaled up": {
+			name: "small.jpg", contentType: "image/jpeg; charset=binary", accepted: true,
+			sizes: [2]image.Point{{64, 32}, {100, 50}}, format: "jpeg",
+		},
+		"too many pixels": {name: "huge.png", contentType: "image/png", accepted: true, err: "exceeds 200000 pixels"},
+		"not an image":    {name: "fake.png", contentType: "image/png", accepted: true, err: "thumbnail: image: unknown format"},
+		"not accepted":    {name: "doc.pdf", contentType: "application/pdf"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			object := Object{Bucket: "data", Name: test.name, ContentType: test.contentType}
+			if got := processor.Accepts(object); got != test.accepted {
+				t.Fatalf("got accepted %v, want %v", got, test.accepted)
+			}
+			if !test.accepted {
+				return
+			}
+
+			result, err := processor.Process(context.Background(), storage, object)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got %v, want %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantOutputs := []string{".variants/" + test.name + ".thumb-64", ".variants/" + test.name + ".thumb-256"}
+			if len(result.Outputs) != 2 || result.Outputs[0] != wantOutputs[0] || result.Outputs[1] != wantOutputs[1] {
+				t.Fatalf("got %v, want %v", result.Outputs, wantOutputs)
+			}
+			for i, output := range result.Outputs {
+				cfg, format, err := image.DecodeConfig(strings.NewReader(storage.objects["data/"+output]))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if format != test.format || cfg.Width != test.sizes[i].X || cfg.Height != test.sizes[i].Y {
+					t.Errorf("got %s of %dx%d, want %s of %v", format, cfg.Width, cfg.Height, test.format, test.sizes[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package ratelimit throttles the clients with token buckets, counting requests or the bytes of the request and
+// response bodies. Buckets refill continuously at their rate up to their burst.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepSize    Number of keyed buckets above which the idle ones are dropped
+const sweepSize = 1024
+
+var now = time.Now
+
+// Bucket    Token bucket refilled at rate tokens per second up to burst tokens, safe for concurrent use
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket function    Full bucket of rate tokens per second, holding at most burst tokens
+func NewBucket(rate float64, burst int) *Bucket {
+	return &Bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now()}
+}
+
+func (b *Bucket) refill(t time.Time) {
+	if elapsed := t.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.last = t
+}
+
+// Allow method    Take a token if there is one, otherwise return how long until there is
+func (b *Bucket) Allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now())
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, b.delay(1 - b.tokens)
+}
+
+// Reserve method    Take n tokens, going into debt when there are not enough, and return how long to wait before using them
+func (b *Bucket) Reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now())
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return b.delay(-b.tokens)
+}
+
+func (b *Bucket) delay(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
+
+// idle method    Whether the bucket is full, so that dropping it loses nothing
+func (b *Bucket) idle(t time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(t)
+	return b.tokens >= b.burst
+}
+
+// Buckets    Buckets by key (e.g. principal), created on first use and dropped once idle
+type Buckets struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+func NewBuckets() *Buckets {
+	return &Buckets{buckets: map[string]*Bucket{}}
+}
+
+// Get method    Bucket of a key, created with newBucket the first time
+func (b *Buckets) Get(key string, newBucket func() *Bucket) *Bucket {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if bucket, ok := b.buckets[key]; ok {
+		return bucket
+	}
+	if len(b.buckets) >= sweepSize {
+		b.sweep()
+	}
+	bucket := newBucket()
+	b.buckets[key] = bucket
+	return bucket
+}
+
+// Len method    Number of buckets kept
+func (b *Buckets) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buckets)
+}
+
+func (b *Buckets) sweep() {
+	t := now()
+	for key, bucket := range b.buckets {
+		if bucket.idle(t) {
+			delete(b.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setNow function    Freeze the clock of the buckets at the returned time, moved by the tests
+func setNow(t *testing.T) *time.Time {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+	return &clock
+}
+
+func TestBucketAllow(t *testing.T) {
+	clock := setNow(t)
+	bucket := NewBucket(2, 3)
+
+	tests := []struct {
+		name       string
+		elapsed    time.Duration
+		allowed    bool
+		retryAfter time.Duration
+	}{
+		{name: "burst 1", allowed: true},
+		{name: "burst 2", allowed: true},
+		{name: "burst 3", allowed: true},
+		{name: "empty", allowed: false, retryAfter: 500 * time.Millisecond},
+		{name: "half a token", elapsed: 250 * time.Millisecond, allowed: false, retryAfter: 250 * time.Millisecond},
+		{name: "refilled", elapsed: 250 * time.Millisecond, allowed: true},
+		{name: "refilled up to the burst", elapsed: time.Hour, allowed: true},
+		{name: "burst again 2", allowed: true},
+		{name: "burst again 3", allowed: true},
+		{name: "empty again", allowed: false, retryAfter: 500 * time.Millisecond},
+	}
+
+	// In order, the bucket keeps its tokens between the steps
+	for _, test := range tests {
+		*clock = clock.Add(test.elapsed)
+		allowed, retryAfter := bucket.Allow()
+		if allowed != test.allowed || retryAfter != test.retryAfter {
+			t.Errorf("%s: got %v %v, want %v %v", test.name, allowed, retryAfter, test.allowed, test.retryAfter)
+		}
+	}
+}
+
+func TestBucketReserve(t *testing.T) {
+	clock := setNow(t)
+	bucket := NewBucket(1000, 1000)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		n       int
+		delay   time.Duration
+	}{
+		{name: "within the burst", n: 600},
+		{name: "debt", n: 900, delay: 500 * time.Millisecond},
+		{name: "repaid in part", elapsed: 200 * time.Millisecond, n: 100, delay: 400 * time.Millisecond},
+		{name: "repaid", elapsed: time.Second, n: 500},
+	}
+
+	for _, test := range tests {
+		*clock = clock.Add(test.elapsed)
+		delay := bucket.Reserve(test.n)
+		if delay != test.delay {
+			t.Errorf("%s: got delay %v, want %v", test.name, delay, test.delay)
+		}
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+	clock := setNow(t)
+	buckets := NewBuckets()
+	newBucket := func() *Bucket { return NewBucket(1, 1) }
+
+	busy := buckets.Get("busy", newBucket)
+	busy.Allow()
+	for i := 0; i < sweepSize; i++ {
+		buckets.Get(strings.Repeat("x", i+1), newBucket)
+	}
+	if buckets.Len() != 2 {
+		t.Errorf("got %d buckets, want only the busy bucket and the new one", buckets.Len())
+	}
+	if buckets.Get("busy", newBucket) != busy {
+		t.Errorf("got a new bucket for a key in use")
+	}
+
+	*clock = clock.Add(time.Second)
+	buckets.sweep()
+	if buckets.Len() != 0 {
+		t.Errorf("got %d buckets, want the idle buckets dropped", buckets.Len())
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 3*chunkSize)
+	var waited time.Duration
+	newThrottle := func(ctx context.Context) *Throttle {
+		// One chunk of burst and one chunk per 10ms
+		bucket := NewBucket(float64(chunkSize)*100, chunkSize)
+		return &Throttle{Context: ctx, Buckets: []*Bucket{bucket, nil}, OnWait: func(d time.Duration) { waited += d }}
+	}
+
+	start := time.Now()
+	read, err := io.ReadAll(NewReader(bytes.NewReader(content), newThrottle(context.Background())))
+	if err != nil || !bytes.Equal(read, content) {
+		t.Fatalf("got %d bytes, %v, want %d bytes", len(read), err, len(content))
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond || waited < 15*time.Millisecond {
+		t.Errorf("read in %v waiting %v, want about 20ms", elapsed, waited)
+	}
+
+	var written bytes.Buffer
+	n, err := NewWriter(&written, newThrottle(context.Background())).Write(content)
+	if err != nil || n != len(content) || !bytes.Equal(written.Bytes(), content) {
+		t.Errorf("wrote %d bytes, %v, want %d bytes", n, err, len(content))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewWriter(io.Discard, newThrottle(ctx)).Write(content)
+	if err != context.Canceled {
+		t.Errorf("got %v, want the error of the canceled context", err)
+	}
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// chunkSize    Bytes read or written between two waits, keeping the throughput smooth
+const chunkSize = 32 * 1024
+
+// Throttle    Bandwidth limit of a stream, every byte takes a token of each bucket (nil buckets are ignored)
+type Throttle struct {
+	Context context.Context
+	Buckets []*Bucket
+	// OnWait is called with the time waited for the buckets, e.g. for metrics
+	OnWait func(time.Duration)
+}
+
+// Wait method    Take n tokens of every bucket and wait until they can all be used, or the context is done
+func (t *Throttle) Wait(n int) error {
+	var delay time.Duration
+	for _, bucket := range t.Buckets {
+		if bucket != nil {
+			delay = max(delay, bucket.Reserve(n))
+		}
+	}
+	if delay <= 0 {
+		return nil
+	}
+	if t.OnWait != nil {
+		t.OnWait(delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.Context.Done():
+		return t.Context.Err()
+	}
+}
+
+type reader struct {
+	r        io.Reader
+	throttle *Throttle
+}
+
+// NewReader function    Reader waiting for the throttle after every read
+func NewReader(r io.Reader, throttle *Throttle) io.Reader {
+	return &reader{r: r, throttle: throttle}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.throttle.Wait(n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+type writer struct {
+	w        io.Writer
+	throttle *Throttle
+}
+
+// NewWriter function    Writer waiting for the throttle before every chunk it writes
+func NewWriter(w io.Writer, throttle *Throttle) io.Writer {
+	return &writer{w: w, throttle: throttle}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), chunkSize)]
+		err := w.throttle.Wait(len(chunk))
+		if err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pavva91/file-upload/internal/errorhandlers"
+)
+
+type paramsKey struct{}
+
+type Route struct {
+	Method  string
+	Pattern *regexp.Regexp
+	Handler http.HandlerFunc
+}
+
+// Router    Dispatch requests to the first route matching both method and path.
+// Paths matching a route with another method get a 405 with the Allow header.
+type Router struct {
+	routes []Route
+}
+
+func New() *Router {
+	return &Router{}
+}
+
+// Handle method    Register a handler, named groups of the pattern (e.g. (?P<name>.+)) are available with Param
+func (rt *Router) Handle(method string, pattern *regexp.Regexp, handler http.HandlerFunc) {
+	rt.routes = append(rt.routes, Route{Method: method, Pattern: pattern, Handler: handler})
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+	var getRoute *Route
+	var getMatch []string
+
+	for i := range rt.routes {
+		route := &rt.routes[i]
+		match := route.Pattern.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			continue
+		}
+		if route.Method == r.Method {
+			route.Handler(w, withParams(r, route.Pattern, match))
+			return
+		}
+		// HEAD is served by the GET handler unless registered explicitly
+		if route.Method == http.MethodGet && r.Method == http.MethodHead && getRoute == nil {
+			getRoute = route
+			getMatch = match
+		}
+		allowed = append(allowed, route.Method)
+	}
+
+	if getRoute != nil {
+		getRoute.Handler(w, withParams(r, getRoute.Pattern, getMatch))
+		return
+	}
+
+	if len(allowed) == 0 {
+		errorhandlers.NotFoundHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", allowHeader(allowed))
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	errorhandlers.MethodNotAllowedHandler(w, r)
+}
+
+// Param function    Return the value of a named group of the matched route pattern
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// BasePath function    Build the path the API is mounted on, e.g. "/api" and "v1" become "/api/v1"
+func BasePath(apiPath string, apiVersion string) string {
+	basePath := path.Join("/", apiPath, apiVersion)
+	if basePath == "/" {
+		return ""
+	}
+	return basePath
+}
+
+func withParams(r *http.Request, pattern *regexp.Regexp, match []string) *http.Request {
+	params := make(map[string]string)
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			params[name] = match[i]
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+}
+
+func allowHeader(methods []string) string {
+	set := map[string]bool{http.MethodOptions: true}
+	for _, method := range methods {
+		set[method] = true
+		if method == http.MethodGet {
+			set[http.MethodHead] = true
+		}
+	}
+
+	allowed := make([]string, 0, len(set))
+	for method := range set {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	return strings.Join(allowed, ", ")
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	rt := New()
+	rt.Handle(http.MethodGet, regexp.MustCompile(`^/files/*$`), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("list"))
+	})
+	rt.Handle(http.MethodPost, regexp.MustCompile(`^/files/*$`), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("upload"))
+	})
+	rt.Handle(http.MethodGet, regexp.MustCompile(`^/files/(?P<name>.+)$`), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Param(r, "name")))
+	})
+
+	tests := map[string]struct {
+		method   string
+		path     string
+		response string
+		status   int
+		allow    string
+	}{
+		"GET /files": {
+			method:   "GET",
+			path:     "/files",
+			response: "list",
+			status:   200,
+		},
+		"POST /files/": {
+			method:   "POST",
+			path:     "/files/",
+			response: "upload",
+			status:   200,
+		},
+		"GET /files/{name} with slashes": {
+			method:   "GET",
+			path:     "/files/dir/sub/object.txt",
+			response: "dir/sub/object.txt",
+			status:   200,
+		},
+		"HEAD /files/{name} served by GET": {
+			method: "HEAD",
+			path:   "/files/object",
+			status: 200,
+		},
+		"DELETE /files not allowed": {
+			method: "DELETE",
+			path:   "/files",
+			status: 405,
+			allow:  "GET, HEAD, OPTIONS, POST",
+		},
+		"OPTIONS /files/{name}": {
+			method: "OPTIONS",
+			path:   "/files/object",
+			status: 204,
+			allow:  "GET, HEAD, OPTIONS",
+		},
+		"GET unknown path": {
+			method: "GET",
+			path:   "/unknown",
+			status: 404,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rt.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+
+			if w.Code != test.status {
+				t.Errorf("got %d, want %d", w.Code, test.status)
+			}
+			if test.response != "" && w.Body.String() != test.response {
+				t.Errorf("got %s, want %s", w.Body.String(), test.response)
+			}
+			if got := w.Header().Get("Allow"); got != test.allow {
+				t.Errorf("got Allow %q, want %q", got, test.allow)
+			}
+		})
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	tests := map[string]struct {
+		apiPath    string
+		apiVersion string
+		want       string
+	}{
+		"empty":            {"", "", ""},
+		"path and version": {"/api", "v1", "/api/v1"},
+		"trailing slashes": {"api/", "/v2/", "/api/v2"},
+		"only version":     {"", "v1", "/v1"},
+	}
+
+	for name, test := range tests {
+		if got := BasePath(test.apiPath, test.apiVersion); got != test.want {
+			t.Errorf("%s: got %q, want %q", name, got, test.want)
+		}
+	}
+}
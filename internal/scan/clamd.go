@@ -0,0 +1,110 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	defaultClamdTimeout   = time.Minute
+	defaultClamdChunkSize = 64 * 1024
+)
+
+// Clamd    Scanner sending the content to a clamd daemon with the INSTREAM command
+type Clamd struct {
+	// Network is tcp or unix
+	Network string
+	Address string
+	// Timeout of a whole scan, clamd holds the reply until the end of the content
+	Timeout   time.Duration
+	ChunkSize int
+}
+
+// NewClamd function    Clamd scanner at address: tcp://host:port, unix:///path/to/clamd.sock or host:port
+func NewClamd(address string) (*Clamd, error) {
+	network, addr := "tcp", address
+	if scheme, rest, ok := strings.Cut(address, "://"); ok {
+		network, addr = scheme, rest
+	}
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("clamd: unsupported network %s in %s, tcp or unix", network, address)
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("clamd: missing address")
+	}
+	return &Clamd{Network: network, Address: addr, Timeout: defaultClamdTimeout, ChunkSize: defaultClamdChunkSize}, nil
+}
+
+// Scan method    Stream the content in chunks prefixed by their length, a zero length chunk ends it.
+// clamd replies "stream: OK", "stream: {signature} FOUND" or "{message} ERROR".
+func (c *Clamd) Scan(ctx context.Context, content io.Reader) (Verdict, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The reply comes early when clamd refuses the stream (e.g. StreamMaxLength), it is read after a failed write
+	_, err = io.WriteString(conn, "zINSTREAM\x00")
+	if err == nil {
+		err = c.send(conn, content)
+	}
+	reply, readErr := bufio.NewReader(conn).ReadString(0)
+	if (readErr != nil && !errors.Is(readErr, io.EOF)) || reply == "" {
+		if err == nil {
+			err = readErr
+		}
+		return Verdict{}, fmt.Errorf("clamd: %w", err)
+	}
+	return parseReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+func (c *Clamd) send(conn net.Conn, content io.Reader) error {
+	chunk := make([]byte, 4+max(c.ChunkSize, 1))
+	for {
+		n, err := io.ReadFull(content, chunk[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(chunk, uint32(n))
+			_, writeErr := conn.Write(chunk[:4+n])
+			if writeErr != nil {
+				return writeErr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+func parseReply(reply string) (Verdict, error) {
+	_, result, ok := strings.Cut(reply, ": ")
+	if !ok {
+		result = reply
+	}
+	switch {
+	case result == "OK":
+		return Verdict{Status: StatusClean}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Status: StatusInfected, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	}
+	return Verdict{}, fmt.Errorf("clamd: %s", reply)
+}
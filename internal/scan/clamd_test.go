@@ -0,0 +1,93 @@
+package scan
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pavva91/file-upload/internal/testutil/fakeclamd"
+)
+
+func TestNewClamd(t *testing.T) {
+	tests := map[string]struct {
+		address string
+		network string
+		addr    string
+		err     bool
+	}{
+		"tcp":         {address: "tcp://clamav:3310", network: "tcp", addr: "clamav:3310"},
+		"unix":        {address: "unix:///run/clamav/clamd.sock", network: "unix", addr: "/run/clamav/clamd.sock"},
+		"host":        {address: "localhost:3310", network: "tcp", addr: "localhost:3310"},
+		"unsupported": {address: "http://clamav:3310", err: true},
+		"empty":       {address: "tcp://", err: true},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			c, err := NewClamd(test.address)
+			if test.err {
+				if err == nil {
+					t.Errorf("got %+v, want an error", c)
+				}
+				return
+			}
+			if err != nil || c.Network != test.network || c.Address != test.addr {
+				t.Errorf("got %+v (%v), want %s %s", c, err, test.network, test.addr)
+			}
+		})
+	}
+}
+
+func TestClamdScan(t *testing.T) {
+	server, err := fakeclamd.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.MaxLength = 1024
+
+	clamd, err := NewClamd(server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The EICAR string spans two chunks
+	clamd.ChunkSize = 16
+
+	tests := map[string]struct {
+		content string
+		want    Verdict
+		err     bool
+	}{
+		"clean":          {content: "hello world", want: Verdict{Status: StatusClean}},
+		"empty":          {content: "", want: Verdict{Status: StatusClean}},
+		"infected":       {content: "prefix " + fakeclamd.EICAR, want: Verdict{Status: StatusInfected, Signature: fakeclamd.Signature}},
+		"size limit":     {content: strings.Repeat("x", 4096), err: true},
+		"at size limit":  {content: strings.Repeat("x", 1024), want: Verdict{Status: StatusClean}},
+		"infected chunk": {content: strings.Repeat("x", 10) + fakeclamd.EICAR, want: Verdict{Status: StatusInfected, Signature: fakeclamd.Signature}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			verdict, err := clamd.Scan(context.Background(), strings.NewReader(test.content))
+			if test.err {
+				if err == nil {
+					t.Errorf("got %+v, want an error", verdict)
+				}
+				return
+			}
+			if err != nil || verdict != test.want {
+				t.Errorf("got %+v (%v), want %+v", verdict, err, test.want)
+			}
+		})
+	}
+
+	server.Close()
+	_, err = clamd.Scan(context.Background(), strings.NewReader("hello"))
+	if err == nil {
+		t.Error("got no error, want the daemon unreachable")
+	}
+}
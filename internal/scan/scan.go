@@ -0,0 +1,33 @@
+// Package scan checks the uploaded content for malware before it is stored.
+// Scanners get the whole content and return a verdict, clamd is supported through its INSTREAM command.
+package scan
+
+import (
+	"context"
+	"io"
+)
+
+// Verdict statuses, stored in the Scan-Status metadata of the objects
+const (
+	StatusClean    = "clean"
+	StatusInfected = "infected"
+	// StatusUnscanned is stored when the scanner failed and the uploads fail open
+	StatusUnscanned = "unscanned"
+)
+
+// Verdict    Outcome of a scan
+type Verdict struct {
+	Status string
+	// Signature is the name of the malware found, e.g. Eicar-Test-Signature
+	Signature string
+}
+
+// Infected method    Whether malware was found
+func (v Verdict) Infected() bool {
+	return v.Status == StatusInfected
+}
+
+// Scanner    Malware scanner, an error means the content could not be scanned (not that it is infected)
+type Scanner interface {
+	Scan(ctx context.Context, content io.Reader) (Verdict, error)
+}
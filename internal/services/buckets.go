@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/sse"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+// Default encryptions of the objects of a bucket
+const (
+	EncryptionNone  = "NONE"
+	EncryptionSSES3 = "SSE-S3"
+	EncryptionKMS   = "SSE-KMS"
+)
+
+var (
+	ErrBucketExists   = errors.New("bucket already exists")
+	ErrBucketNotEmpty = errors.New("bucket not empty")
+	// ErrBucketInUse    The bucket is part of the config, e.g. the default or the quarantine bucket
+	ErrBucketInUse = errors.New("bucket used by the service")
+)
+
+// BucketOptions    Configuration of a bucket
+type BucketOptions struct {
+	// Region defaults to the region of the config
+	Region        string
+	ObjectLocking bool
+	Versioning    bool
+	// Encryption of the objects by default: EncryptionNone, EncryptionSSES3 or EncryptionKMS, default the one of the config
+	Encryption string
+	// EncryptionKeyID of EncryptionKMS, default the key of the config
+	EncryptionKeyID string
+	Tags            map[string]string
+	Lifecycle       []LifecycleRule
+}
+
+// Bucket    Bucket and its configuration
+type Bucket struct {
+	Name         string
+	CreationDate time.Time
+	BucketOptions
+}
+
+// MakeBucket function    Create a bucket and apply its configuration, ErrBucketExists when it already exists.
+// A bucket whose configuration fails is removed again.
+func MakeBucket(bucketName string, opts BucketOptions) error {
+	ctx := context.Background()
+
+	found, err := storage.MinioClient.BucketExists(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%w: %s", ErrBucketExists, bucketName)
+	}
+
+	// Refused before the bucket is created
+	err = checkDedupRules(opts.Lifecycle)
+	if err != nil {
+		return err
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = config.ServerConfigValues.Minio.Region
+	}
+	err = storage.MinioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: region, ObjectLocking: opts.ObjectLocking})
+	switch minio.ToErrorResponse(err).Code {
+	case "BucketAlreadyExists", "BucketAlreadyOwnedByYou":
+		return fmt.Errorf("%w: %s", ErrBucketExists, bucketName)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = configureBucket(ctx, bucketName, opts)
+	if err != nil {
+		removeErr := storage.MinioClient.RemoveBucket(ctx, bucketName)
+		if removeErr != nil {
+			log.Println(removeErr)
+		}
+		return err
+	}
+	log.Println("Successfully created bucket:", bucketName)
+	return nil
+}
+
+func configureBucket(ctx context.Context, bucketName string, opts BucketOptions) error {
+	if opts.Versioning {
+		err := storage.MinioClient.EnableVersioning(ctx, bucketName)
+		if err != nil {
+			return err
+		}
+	}
+
+	encryption, err := bucketEncryption(opts)
+	if err != nil {
+		return err
+	}
+	if encryption != nil {
+		err = storage.MinioClient.SetBucketEncryption(ctx, bucketName, encryption)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Tags) > 0 {
+		bucketTags, err := tags.NewTags(opts.Tags, false)
+		if err != nil {
+			return err
+		}
+		err = storage.MinioClient.SetBucketTagging(ctx, bucketName, bucketTags)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Lifecycle) > 0 || len(config.ServerConfigValues.Lifecycle.ExpiresAfter) > 0 {
+		err = PutLifecycle(bucketName, opts.Lifecycle)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bucketEncryption function    Default encryption configuration of opts, nil for none
+func bucketEncryption(opts BucketOptions) (*sse.Configuration, error) {
+	encryption := opts.Encryption
+	if encryption == "" {
+		encryption = config.ServerConfigValues.Minio.BucketEncryption
+	}
+
+	switch strings.ToUpper(encryption) {
+	case "", EncryptionNone:
+		return nil, nil
+	case EncryptionSSES3:
+		return sse.NewConfigurationSSES3(), nil
+	case EncryptionKMS:
+		keyID := opts.EncryptionKeyID
+		if keyID == "" {
+			keyID = config.ServerConfigValues.Minio.EncryptionKeyID
+		}
+		return sse.NewConfigurationSSEKMS(keyID), nil
+	}
+	return nil, fmt.Errorf("unknown bucket encryption %s", encryption)
+}
+
+// ListBuckets function    Name and creation date of all the buckets
+func ListBuckets() ([]Bucket, error) {
+	bucketInfos, err := storage.MinioClient.ListBuckets(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]Bucket, 0, len(bucketInfos))
+	for _, bucketInfo := range bucketInfos {
+		buckets = append(buckets, Bucket{Name: bucketInfo.Name, CreationDate: bucketInfo.CreationDate})
+	}
+	return buckets, nil
+}
+
+// GetBucket function    Configuration of a bucket, the settings the S3 implementation doesn't report are left empty
+func GetBucket(bucketName string) (Bucket, error) {
+	ctx := context.Background()
+	bucket := Bucket{Name: bucketName}
+
+	region, err := storage.MinioClient.GetBucketLocation(ctx, bucketName)
+	if err != nil {
+		return Bucket{}, err
+	}
+	bucket.Region = region
+
+	objectLockEnabled, _, _, _, err := storage.MinioClient.GetObjectLockConfig(ctx, bucketName)
+	if err != nil && !notConfigured(err) {
+		return Bucket{}, err
+	}
+	bucket.ObjectLocking = objectLockEnabled == "Enabled"
+
+	versioning, err := storage.MinioClient.GetBucketVersioning(ctx, bucketName)
+	if err != nil && !notConfigured(err) {
+		return Bucket{}, err
+	}
+	bucket.Versioning = versioning.Enabled()
+
+	encryption, err := storage.MinioClient.GetBucketEncryption(ctx, bucketName)
+	if err != nil && !notConfigured(err) {
+		return Bucket{}, err
+	}
+	bucket.Encryption = EncryptionNone
+	if encryption != nil && len(encryption.Rules) > 0 {
+		bucket.Encryption = EncryptionSSES3
+		if apply := encryption.Rules[0].Apply; apply.SSEAlgorithm == "aws:kms" {
+			bucket.Encryption = EncryptionKMS
+			bucket.EncryptionKeyID = apply.KmsMasterKeyID
+		}
+	}
+
+	bucketTags, err := storage.MinioClient.GetBucketTagging(ctx, bucketName)
+	if err != nil && !notConfigured(err) {
+		return Bucket{}, err
+	}
+	if bucketTags != nil {
+		bucket.Tags = bucketTags.ToMap()
+	}
+
+	bucket.Lifecycle, err = GetLifecycle(bucketName)
+	if err != nil {
+		return Bucket{}, err
+	}
+	return bucket, nil
+}
+
+// RemoveBucket function    Remove an empty bucket, ErrBucketNotEmpty otherwise. The buckets of the config are kept.
+func RemoveBucket(bucketName string) error {
+	if bucketName == config.ServerConfigValues.Minio.Bucket || bucketName == config.ServerConfigValues.Scan.QuarantineBucket {
+		return fmt.Errorf("%w: %s is a bucket of the config", ErrBucketInUse, bucketName)
+	}
+
+	err := storage.MinioClient.RemoveBucket(context.Background(), bucketName)
+	if minio.ToErrorResponse(err).Code == "BucketNotEmpty" {
+		return fmt.Errorf("%w: %s", ErrBucketNotEmpty, bucketName)
+	}
+	if err != nil {
+		return err
+	}
+	log.Println("Successfully removed bucket:", bucketName)
+	return nil
+}
+
+// notConfigured function    Whether err reports a bucket setting that was never set
+func notConfigured(err error) bool {
+	return minio.ToErrorResponse(err).StatusCode == http.StatusNotFound && !IsNotFound(err)
+}
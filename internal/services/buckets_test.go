@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+func TestBucketEncryption(t *testing.T) {
+	config.ServerConfigValues.Minio.EncryptionKeyID = "config-key"
+	t.Cleanup(func() {
+		config.ServerConfigValues.Minio.EncryptionKeyID = ""
+		config.ServerConfigValues.Minio.BucketEncryption = ""
+	})
+
+	tests := map[string]struct {
+		configured string
+		opts       BucketOptions
+		algorithm  string
+		keyID      string
+		err        bool
+	}{
+		"none":                  {},
+		"explicitly none":       {configured: EncryptionKMS, opts: BucketOptions{Encryption: EncryptionNone}},
+		"SSE-S3":                {opts: BucketOptions{Encryption: EncryptionSSES3}, algorithm: "AES256"},
+		"SSE-KMS with a key":    {opts: BucketOptions{Encryption: EncryptionKMS, EncryptionKeyID: "bucket-key"}, algorithm: "aws:kms", keyID: "bucket-key"},
+		"SSE-KMS of the config": {configured: "sse-kms", algorithm: "aws:kms", keyID: "config-key"},
+		"unknown":               {opts: BucketOptions{Encryption: "AES"}, err: true},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			config.ServerConfigValues.Minio.BucketEncryption = test.configured
+
+			encryption, err := bucketEncryption(test.opts)
+			if (err != nil) != test.err {
+				t.Fatalf("got %v, want error %t", err, test.err)
+			}
+			if test.algorithm == "" {
+				if encryption != nil {
+					t.Errorf("got %+v, want no encryption", encryption)
+				}
+				return
+			}
+			if encryption == nil || len(encryption.Rules) != 1 {
+				t.Fatalf("got %+v, want one rule", encryption)
+			}
+			if apply := encryption.Rules[0].Apply; apply.SSEAlgorithm != test.algorithm || apply.KmsMasterKeyID != test.keyID {
+				t.Errorf("got %+v, want %s with key %q", apply, test.algorithm, test.keyID)
+			}
+		})
+	}
+}
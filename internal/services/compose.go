@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/contenttype"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+// MinComposePartSize    Minimum size of every source of a compose but the last one, the minimum part size of S3
+const MinComposePartSize = 5 * 1024 * 1024
+
+var (
+	// ErrPartTooSmall    A source of a compose, not the last one, is below MinComposePartSize
+	ErrPartTooSmall = errors.New("compose source below the 5MiB minimum part size")
+	ErrInvalidRange = errors.New("byte range out of the source")
+)
+
+// ComposeSource    Object, or byte range of an object, concatenated by ComposeObject
+type ComposeSource struct {
+	// Bucket defaults to the bucket of the composed object
+	Bucket string
+	Name   string
+	// Offset and Length select a byte range, Length 0 reads up to the end
+	Offset int64
+	Length int64
+}
+
+// ComposeOptions    Properties of a composed object
+type ComposeOptions struct {
+	// ContentType defaults to the content type of the first source
+	ContentType  string
+	UserMetadata map[string]string
+	Tags         map[string]string
+	// EncryptionKeyID encrypts the object with another KMS key, default the key of the config
+	EncryptionKeyID string
+}
+
+// ComposeObject function    Concatenate sources into objectName server-side with ComposeObject, every source but the
+// last one must have MinComposePartSize at least. The sources are decrypted by the server, the composed object is
+// encrypted with SSE-KMS. Checksums and scan verdicts of the sources don't hold for the concatenation and are not kept.
+func ComposeObject(bucketName string, objectName string, sources []ComposeSource, opts ComposeOptions) (UploadInfo, error) {
+	ctx := context.Background()
+
+	srcs := make([]minio.CopySrcOptions, 0, len(sources))
+	var size int64
+	contentType := opts.ContentType
+	for i, source := range sources {
+		sourceBucket := source.Bucket
+		if sourceBucket == "" {
+			sourceBucket = bucketName
+		}
+
+		contentName, info, err := resolveObject(ctx, sourceBucket, source.Name)
+		if err != nil {
+			return UploadInfo{}, err
+		}
+		if contentType == "" {
+			contentType = info.ContentType
+		}
+
+		length := source.Length
+		if length == 0 {
+			length = info.Size - source.Offset
+		}
+		if source.Offset+length > info.Size || length < 0 {
+			return UploadInfo{}, fmt.Errorf("%w: %s has %d bytes", ErrInvalidRange, source.Name, info.Size)
+		}
+		if length < MinComposePartSize && i < len(sources)-1 {
+			return UploadInfo{}, fmt.Errorf("%w: source %d (%s) has %d bytes, all the sources but the last one must have %d at least",
+				ErrPartTooSmall, i, source.Name, length, MinComposePartSize)
+		}
+
+		src := minio.CopySrcOptions{Bucket: sourceBucket, Object: contentName, MatchETag: info.ETag}
+		if length < info.Size {
+			src.MatchRange = true
+			src.Start = source.Offset
+			src.End = source.Offset + length - 1
+		}
+		srcs = append(srcs, src)
+		size += length
+	}
+
+	// The limits of the bucket apply like on upload
+	err := checkObjectSize(bucketName, size)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	allow, deny := contentTypePolicy(bucketName)
+	if contenttype.Match(deny, contentType) || (len(allow) > 0 && !contenttype.Match(allow, contentType)) {
+		return UploadInfo{}, fmt.Errorf("%w in bucket %s: %s", ErrContentTypeNotAllowed, bucketName, contenttype.MediaType(contentType))
+	}
+
+	keyID := opts.EncryptionKeyID
+	if keyID == "" {
+		keyID = config.ServerConfigValues.Minio.EncryptionKeyID
+	}
+	encryption, err := encrypt.NewSSEKMS(keyID, ctx)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+
+	uploadInfo, err := storage.MinioClient.ComposeObject(ctx, minio.CopyDestOptions{
+		Bucket:          bucketName,
+		Object:          objectName,
+		Encryption:      encryption,
+		ReplaceMetadata: true,
+		UserMetadata:    withContentType(opts.UserMetadata, contentType),
+		ReplaceTags:     true,
+		UserTags:        opts.Tags,
+	}, srcs...)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	uploadInfo.Bucket = bucketName
+	uploadInfo.Key = objectName
+	uploadInfo.Size = size
+
+	putOpts := minio.PutObjectOptions{ContentType: contentType, UserMetadata: opts.UserMetadata, UserTags: opts.Tags}
+	indexUpload(ctx, bucketName, uploadInfo, putOpts)
+	enqueueProcessing(ctx, bucketName, uploadInfo, putOpts)
+
+	log.Printf("Successfully composed %s of bucket %s from %d sources of %d bytes", objectName, bucketName, len(sources), size)
+	return UploadInfo{UploadInfo: uploadInfo, ContentType: contentType}, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestComposeObject(t *testing.T) {
+	newDedupStorage(t)
+	head := strings.Repeat("h", MinComposePartSize)
+
+	// The sources are deduplicated references, composed from their blobs
+	for name, content := range map[string]string{"head.bin": head, "tail.bin": "tail", "small.bin": "small"} {
+		_, err := EncryptAndUploadStream(name, strings.NewReader(content), -1, testBucket, UploadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		sources []ComposeSource
+		want    string
+		err     error
+	}{
+		"whole sources":     {sources: []ComposeSource{{Name: "head.bin"}, {Name: "tail.bin"}}, want: head + "tail"},
+		"range of the last": {sources: []ComposeSource{{Name: "head.bin"}, {Name: "tail.bin", Offset: 1, Length: 2}}, want: head + "ai"},
+		"single source":     {sources: []ComposeSource{{Name: "small.bin"}}, want: "small"},
+		"small first part":  {sources: []ComposeSource{{Name: "small.bin"}, {Name: "tail.bin"}}, err: ErrPartTooSmall},
+		"range out":         {sources: []ComposeSource{{Name: "tail.bin", Offset: 2, Length: 3}}, err: ErrInvalidRange},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			composed, err := ComposeObject(testBucket, "composed.bin", test.sources, ComposeOptions{})
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("got %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if composed.Size != int64(len(test.want)) {
+				t.Errorf("got size %d, want %d", composed.Size, len(test.want))
+			}
+
+			object, _, err := GetObject(testBucket, "composed.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(object)
+			object.Close()
+			if string(data) != test.want {
+				t.Errorf("got %d bytes, want %d bytes of the sources", len(data), len(test.want))
+			}
+		})
+	}
+}
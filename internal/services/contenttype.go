@@ -0,0 +1,62 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/contenttype"
+)
+
+var ErrContentTypeNotAllowed = errors.New("content type not allowed")
+
+// UploadInfo    Result of an upload and the content type stored on the object
+type UploadInfo struct {
+	minio.UploadInfo
+	ContentType string
+}
+
+// ContentType function    Type of an upload from the first bytes of its content, the declared type and the extension
+// of objectName, ErrContentTypeNotAllowed when the allow and deny lists of the bucket refuse it.
+// Both the detected and the resolved type are checked against the deny lists.
+func ContentType(bucketName string, objectName string, head []byte, declared string) (string, error) {
+	detected := contenttype.Detect(head)
+	resolved := contenttype.Resolve(detected, declared, objectName)
+
+	allow, deny := contentTypePolicy(bucketName)
+	if contenttype.Match(deny, resolved) || contenttype.Match(deny, detected) || (len(allow) > 0 && !contenttype.Match(allow, resolved)) {
+		return "", fmt.Errorf("%w in bucket %s: %s", ErrContentTypeNotAllowed, bucketName, contenttype.MediaType(resolved))
+	}
+	return resolved, nil
+}
+
+// contentTypePolicy function    Allow and deny lists of a bucket, the allow list is empty when every type is allowed
+func contentTypePolicy(bucketName string) (allow []string, deny []string) {
+	policy := config.ServerConfigValues.ContentTypes
+	allow = policy.Allow
+	deny = policy.Deny
+	for _, bucket := range policy.Buckets {
+		if bucket.Bucket != bucketName {
+			continue
+		}
+		if len(bucket.Allow) > 0 {
+			allow = bucket.Allow
+		}
+		deny = append(append([]string{}, deny...), bucket.Deny...)
+	}
+	return allow, deny
+}
+
+// sniff function    First bytes of reader to detect its type and a reader of the whole content
+func sniff(reader io.Reader) ([]byte, io.Reader, error) {
+	head := make([]byte, contenttype.SniffLen)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, err
+	}
+	head = head[:n]
+	return head, io.MultiReader(bytes.NewReader(head), reader), nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+func setContentTypes(t *testing.T, allow []string, deny []string, buckets []config.ContentTypePolicy) {
+	policy := &config.ServerConfigValues.ContentTypes
+	previous := *policy
+	policy.Allow = allow
+	policy.Deny = deny
+	policy.Buckets = buckets
+	t.Cleanup(func() { *policy = previous })
+}
+
+func TestContentTypePolicy(t *testing.T) {
+	// Room left in the global deny list, so that appending the bucket denies in place would go unnoticed
+	globalDeny := make([]string, 1, 4)
+	globalDeny[0] = "application/x-executable"
+
+	setContentTypes(t, []string{"image/*", "text/*"}, globalDeny, []config.ContentTypePolicy{
+		{Bucket: "photos", Allow: []string{"image/*"}, Deny: []string{"image/svg+xml"}},
+		{Bucket: "logs", Deny: []string{"text/html"}},
+		{Bucket: "logs", Deny: []string{"text/x-shellscript"}},
+		{Bucket: "scans", Allow: []string{"image/tiff"}},
+		{Bucket: "scans", Allow: []string{"application/pdf"}},
+	})
+
+	tests := map[string]struct {
+		bucket string
+		allow  []string
+		deny   []string
+	}{
+		"global":                 {bucket: "other", allow: []string{"image/*", "text/*"}, deny: []string{"application/x-executable"}},
+		"bucket allow replaces":  {bucket: "photos", allow: []string{"image/*"}, deny: []string{"application/x-executable", "image/svg+xml"}},
+		"bucket denies add up":   {bucket: "logs", allow: []string{"image/*", "text/*"}, deny: []string{"application/x-executable", "text/html", "text/x-shellscript"}},
+		"last bucket allow wins": {bucket: "scans", allow: []string{"application/pdf"}, deny: []string{"application/x-executable"}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			allow, deny := contentTypePolicy(test.bucket)
+			if !reflect.DeepEqual(allow, test.allow) || !reflect.DeepEqual(deny, test.deny) {
+				t.Errorf("got allow %v deny %v, want allow %v deny %v", allow, deny, test.allow, test.deny)
+			}
+		})
+	}
+
+	_, logs := contentTypePolicy("logs")
+	contentTypePolicy("photos")
+	if logs[1] != "text/html" {
+		t.Errorf("got deny %v after the policy of another bucket, want the lists not to share the global one", logs)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	setContentTypes(t, nil, []string{"application/vnd.microsoft.portable-executable"}, []config.ContentTypePolicy{
+		{Bucket: "photos", Allow: []string{"image/*"}, Deny: []string{"image/svg+xml"}},
+	})
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	exe := []byte("MZ\x90\x00\x03\x00\x00\x00")
+
+	tests := map[string]struct {
+		bucket   string
+		name     string
+		head     []byte
+		declared string
+		want     string
+	}{
+		"any type":                    {bucket: "docs", name: "notes.txt", head: []byte("notes"), want: "text/plain; charset=utf-8"},
+		"image in photos":             {bucket: "photos", name: "a.png", head: png, declared: "image/png", want: "image/png"},
+		"text in photos":              {bucket: "photos", name: "notes.txt", head: []byte("notes")},
+		"denied in photos":            {bucket: "photos", name: "a.svg", head: []byte("<svg></svg>"), declared: "image/svg+xml"},
+		"globally denied":             {bucket: "docs", name: "setup.exe", head: exe},
+		"denied disguised as png":     {bucket: "docs", name: "a.png", head: exe, declared: "image/png"},
+		"detected over the extension": {bucket: "photos", name: "a.txt", head: png, want: "image/png"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			got, err := ContentType(test.bucket, test.name, test.head, test.declared)
+			if test.want == "" {
+				if !errors.Is(err, ErrContentTypeNotAllowed) {
+					t.Errorf("got %q (%v), want ErrContentTypeNotAllowed", got, err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("got %q (%v), want %q", got, err, test.want)
+			}
+		})
+	}
+}
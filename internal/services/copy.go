@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/contenttype"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+var (
+	// ErrObjectLocked    The retention or legal hold of an object forbids the operation
+	ErrObjectLocked   = errors.New("object locked")
+	ErrMoveOntoItself = errors.New("an object can't be moved onto itself")
+	// ErrSourceNotRemoved    A move copied the object but couldn't remove the source, both are kept
+	ErrSourceNotRemoved = errors.New("source not removed")
+)
+
+// CopyOptions    Destination of a copy and what changes on it, the zero value keeps the metadata, tags and key of the config
+type CopyOptions struct {
+	// DestinationBucket defaults to the source bucket
+	DestinationBucket string
+	DestinationName   string
+	// ReplaceMetadata sets UserMetadata instead of the user metadata of the source
+	ReplaceMetadata bool
+	UserMetadata    map[string]string
+	// ReplaceTags sets Tags instead of the tags of the source
+	ReplaceTags bool
+	Tags        map[string]string
+	// EncryptionKeyID re-encrypts the copy with another KMS key, default the key of the config.
+	// The copy of a deduplicated object under an EncryptionKeyID is a plain object instead of a reference.
+	EncryptionKeyID string
+}
+
+// objectLock    Retention and legal hold of an object
+type objectLock struct {
+	mode        minio.RetentionMode
+	retainUntil time.Time
+	legalHold   bool
+}
+
+// lockOf function    Object lock of an object, from the headers of its info
+func lockOf(info minio.ObjectInfo) objectLock {
+	lock := objectLock{
+		mode:      minio.RetentionMode(info.Metadata.Get("X-Amz-Object-Lock-Mode")),
+		legalHold: info.Metadata.Get("X-Amz-Object-Lock-Legal-Hold") == string(minio.LegalHoldEnabled),
+	}
+	lock.retainUntil, _ = time.Parse(time.RFC3339, info.Metadata.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	return lock
+}
+
+// retained method    Whether the retention is in force
+func (l objectLock) retained() bool {
+	return l.mode.IsValid() && l.retainUntil.After(time.Now())
+}
+
+func (l objectLock) active() bool {
+	return l.retained() || l.legalHold
+}
+
+// CopyObject function    Server-side copy of an object, without going through the server. ComposeObject switches to
+// a multipart copy for the sources above 5GiB. The copy keeps the retention and legal hold of the source, the destination
+// bucket must have object lock enabled then. Copies of deduplicated objects only add a reference to their blob, unless they
+// are re-encrypted with another key.
+func CopyObject(bucketName string, objectName string, opts CopyOptions) (UploadInfo, error) {
+	ctx := context.Background()
+
+	destinationBucket := opts.DestinationBucket
+	if destinationBucket == "" {
+		destinationBucket = bucketName
+	}
+
+	contentName, info, err := resolveObject(ctx, bucketName, objectName)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+
+	// The limits of the destination bucket apply like on upload
+	err = checkObjectSize(destinationBucket, info.Size)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	allow, deny := contentTypePolicy(destinationBucket)
+	if contenttype.Match(deny, info.ContentType) || (len(allow) > 0 && !contenttype.Match(allow, info.ContentType)) {
+		return UploadInfo{}, fmt.Errorf("%w in bucket %s: %s", ErrContentTypeNotAllowed, destinationBucket, contenttype.MediaType(info.ContentType))
+	}
+
+	keyID := opts.EncryptionKeyID
+	if keyID == "" {
+		keyID = config.ServerConfigValues.Minio.EncryptionKeyID
+	}
+	encryption, err := encrypt.NewSSEKMS(keyID, ctx)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+
+	userMetadata := copyMetadata(info.UserMetadata, opts)
+	userTags := opts.Tags
+	if !opts.ReplaceTags {
+		userTags, err = GetObjectTagging(bucketName, objectName)
+		if err != nil {
+			return UploadInfo{}, err
+		}
+	}
+
+	dst := minio.CopyDestOptions{
+		Bucket:     destinationBucket,
+		Object:     opts.DestinationName,
+		Encryption: encryption,
+		// The metadata is always set, the multipart copy would lose the content type otherwise
+		ReplaceMetadata: true,
+		UserMetadata:    withContentType(userMetadata, info.ContentType),
+		ReplaceTags:     true,
+		UserTags:        userTags,
+	}
+
+	lock := lockOf(info)
+	if lock.active() {
+		objectLockEnabled, _, _, _, err := storage.MinioClient.GetObjectLockConfig(ctx, destinationBucket)
+		if err != nil && minio.ToErrorResponse(err).StatusCode != http.StatusNotFound {
+			return UploadInfo{}, err
+		}
+		if objectLockEnabled != "Enabled" {
+			return UploadInfo{}, fmt.Errorf("%w: bucket %s has no object lock to keep the retention of %s", ErrObjectLocked, destinationBucket, objectName)
+		}
+		if lock.retained() {
+			dst.Mode = lock.mode
+			dst.RetainUntilDate = lock.retainUntil
+		}
+		if lock.legalHold {
+			dst.LegalHold = minio.LegalHoldEnabled
+		}
+	}
+
+	putOpts := minio.PutObjectOptions{ContentType: info.ContentType, UserMetadata: userMetadata, UserTags: userTags}
+
+	var uploadInfo minio.UploadInfo
+	// A blob keeps the key it was stored with, the copy under a requested key is a plain object of the content
+	digest := metadataValue(info.UserMetadata, MetadataDedupBlob)
+	if digest != "" && config.ServerConfigValues.Minio.EnableDedup && opts.EncryptionKeyID == "" {
+		// The copy of a reference record is another reference to the same blob
+		referenceOpts := putOpts
+		referenceOpts.ServerSideEncryption = encryption
+		referenceOpts.Mode = dst.Mode
+		referenceOpts.RetainUntilDate = dst.RetainUntilDate
+		referenceOpts.LegalHold = dst.LegalHold
+		uploadInfo, err = copyReference(ctx, bucketName, destinationBucket, opts.DestinationName, digest, info.Size, referenceOpts)
+	} else {
+		uploadInfo, err = storage.MinioClient.ComposeObject(ctx, dst, minio.CopySrcOptions{Bucket: bucketName, Object: contentName})
+	}
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	uploadInfo.Bucket = destinationBucket
+	uploadInfo.Key = opts.DestinationName
+	uploadInfo.Size = info.Size
+
+	indexUpload(ctx, destinationBucket, uploadInfo, putOpts)
+	enqueueProcessing(ctx, destinationBucket, uploadInfo, putOpts)
+
+	log.Printf("Successfully copied %s of bucket %s to %s of bucket %s", objectName, bucketName, opts.DestinationName, destinationBucket)
+	return UploadInfo{UploadInfo: uploadInfo, ContentType: info.ContentType}, nil
+}
+
+// MoveObject function    CopyObject then remove the source, ErrObjectLocked before anything is copied when the retention
+// or legal hold of the source forbids removing it. ErrSourceNotRemoved with the copy when the source removal fails afterwards.
+func MoveObject(bucketName string, objectName string, opts CopyOptions) (UploadInfo, error) {
+	destinationBucket := opts.DestinationBucket
+	if destinationBucket == "" {
+		destinationBucket = bucketName
+	}
+	if destinationBucket == bucketName && opts.DestinationName == objectName {
+		return UploadInfo{}, ErrMoveOntoItself
+	}
+
+	info, err := storage.MinioClient.StatObject(context.Background(), bucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	lock := lockOf(info)
+	if lock.legalHold {
+		return UploadInfo{}, fmt.Errorf("%w: %s is under legal hold", ErrObjectLocked, objectName)
+	}
+	if lock.retained() {
+		return UploadInfo{}, fmt.Errorf("%w: %s is retained until %s", ErrObjectLocked, objectName, lock.retainUntil.Format(time.RFC3339))
+	}
+
+	uploadInfo, err := CopyObject(bucketName, objectName, opts)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	err = RemoveObject(objectName, bucketName)
+	if err != nil {
+		return uploadInfo, fmt.Errorf("%w: %s copied to %s but kept: %v", ErrSourceNotRemoved, objectName, opts.DestinationName, err)
+	}
+	return uploadInfo, nil
+}
+
+// copyMetadata function    User metadata of a copy: the one of the source or the replacement, with the checksums and
+// scan verdict of the source that describe the same content. Dedup references are not copied.
+func copyMetadata(source map[string]string, opts CopyOptions) map[string]string {
+	metadata := map[string]string{}
+	for k, v := range source {
+		switch {
+		case strings.HasPrefix(k, "Dedup-"):
+		case strings.HasPrefix(k, "Checksum-"), strings.HasPrefix(k, "Scan-"):
+			metadata[k] = v
+		case !opts.ReplaceMetadata:
+			metadata[k] = v
+		}
+	}
+	if opts.ReplaceMetadata {
+		for k, v := range opts.UserMetadata {
+			metadata[k] = v
+		}
+	}
+	return metadata
+}
+
+func withContentType(metadata map[string]string, contentType string) map[string]string {
+	withType := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		withType[k] = v
+	}
+	if contentType != "" {
+		withType["Content-Type"] = contentType
+	}
+	return withType
+}
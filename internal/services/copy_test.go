@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+func TestCopyDeduplicated(t *testing.T) {
+	s3 := newDedupStorage(t)
+	ctx := context.Background()
+	content := strings.Repeat("artifact", 1000)
+	digest := sha256Hex(content)
+
+	err := storage.MinioClient.MakeBucket(ctx, "archive", minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := EncryptAndUploadStream("app.bin", strings.NewReader(content), -1, testBucket, UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		bucket     string
+		references int
+	}{
+		"same bucket":  {bucket: testBucket, references: 2},
+		"other bucket": {bucket: "archive", references: 1},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			copied, err := CopyObject(testBucket, "app.bin", CopyOptions{DestinationBucket: test.bucket, DestinationName: "copy.bin"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if copied.Size != int64(len(content)) {
+				t.Errorf("got size %d, want %d", copied.Size, len(content))
+			}
+
+			if record := s3.Object(test.bucket, "copy.bin"); record == nil || len(record.Data) != 0 {
+				t.Errorf("copy.bin isn't a reference record")
+			}
+			if blob := s3.Object(test.bucket, blobObjectName(digest)); blob == nil || string(blob.Data) != content {
+				t.Errorf("blob of %s not stored in %s", digest, test.bucket)
+			}
+			if count, _ := ReferenceCount(ctx, test.bucket, digest); count != test.references {
+				t.Errorf("got %d references, want %d", count, test.references)
+			}
+
+			object, info, err := GetObject(test.bucket, "copy.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(object)
+			object.Close()
+			if string(data) != content || info.ContentType != source.ContentType {
+				t.Errorf("got %d bytes (%s), want the content of app.bin (%s)", len(data), info.ContentType, source.ContentType)
+			}
+		})
+	}
+}
+
+func TestCopyDeduplicatedWithKey(t *testing.T) {
+	s3 := newDedupStorage(t)
+	ctx := context.Background()
+	content := strings.Repeat("artifact", 1000)
+	digest := sha256Hex(content)
+
+	_, err := EncryptAndUploadStream("app.bin", strings.NewReader(content), -1, testBucket, UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CopyObject(testBucket, "app.bin", CopyOptions{DestinationName: "copy.bin", EncryptionKeyID: "archive-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The blob of the bucket is encrypted with the key of the config, the copy is a plain object under the requested key
+	copied := s3.Object(testBucket, "copy.bin")
+	if copied == nil || string(copied.Data) != content {
+		t.Fatalf("copy.bin isn't a plain object with the content of app.bin")
+	}
+	if keyID := copied.Header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); keyID != "archive-key" {
+		t.Errorf("got key %q, want archive-key", keyID)
+	}
+	if blob := copied.Header.Get("X-Amz-Meta-" + MetadataDedupBlob); blob != "" {
+		t.Errorf("copy.bin points to blob %s", blob)
+	}
+	if count, _ := ReferenceCount(ctx, testBucket, digest); count != 1 {
+		t.Errorf("got %d references, want 1", count)
+	}
+
+	object, _, err := GetObject(testBucket, "copy.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(object)
+	object.Close()
+	if string(data) != content {
+		t.Errorf("got %d bytes, want the content of app.bin", len(data))
+	}
+}
+
+func TestMoveSourceNotRemoved(t *testing.T) {
+	s3 := newDedupStorage(t)
+	_, err := EncryptAndUploadStream("report.txt", strings.NewReader("report"), -1, testBucket, UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s3.DenyRequest = func(r *http.Request) bool {
+		return r.Method == http.MethodDelete && r.URL.Path == "/"+testBucket+"/report.txt"
+	}
+	moved, err := MoveObject(testBucket, "report.txt", CopyOptions{DestinationName: "moved.txt"})
+	if !errors.Is(err, ErrSourceNotRemoved) {
+		t.Fatalf("got %v, want %v", err, ErrSourceNotRemoved)
+	}
+	if moved.Key != "moved.txt" {
+		t.Errorf("got %+v, want the info of the copy", moved)
+	}
+	for _, objectName := range []string{"report.txt", "moved.txt"} {
+		if s3.Object(testBucket, objectName) == nil {
+			t.Errorf("%s not kept", objectName)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+// With dedup enabled the content of an upload is stored once per bucket as a blob named after its SHA-256,
+// the object name becomes an empty reference record pointing to the blob:
+//
+//	{prefix}blobs/sha256/{digest}            content, encrypted like any other object
+//	{prefix}refs/{digest}/{escaped object}   one empty marker per reference, the blob is removed with the last one
+//	{object}                                 reference record, user metadata Dedup-Blob, Dedup-Size and Dedup-Etag
+const (
+	MetadataDedupBlob = "Dedup-Blob"
+	MetadataDedupSize = "Dedup-Size"
+	MetadataDedupETag = "Dedup-Etag"
+)
+
+// DedupPrefix function    Prefix of the blobs and reference markers, hidden from the listings and reserved in the object names
+func DedupPrefix() string {
+	return dto.DedupPrefix()
+}
+
+// putObject function    PutObject, through the content-addressed store when dedup is enabled
+func putObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	if config.ServerConfigValues.Minio.EnableDedup {
+		return putDeduplicated(ctx, bucketName, objectName, reader, size, opts)
+	}
+	return storage.MinioClient.PutObject(ctx, bucketName, objectName, reader, size, opts)
+}
+
+// putDeduplicated function    Hash the content, upload it to the blob of its digest only when missing and point objectName
+// to the blob. The encryption of opts applies to the blob, the reference record and the marker, the user metadata and
+// tags to the reference record only.
+func putDeduplicated(ctx context.Context, bucketName string, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	content, digest, size, err := hashContent(reader)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	defer content.Close()
+
+	storeBlob := func(blobName string, blobOpts minio.PutObjectOptions) (minio.UploadInfo, error) {
+		return storage.MinioClient.PutObject(ctx, bucketName, blobName, content, size, blobOpts)
+	}
+	return replaceReference(ctx, bucketName, objectName, digest, size, opts, storeBlob)
+}
+
+// copyReference function    Point objectName of bucketName to the blob of digest of sourceBucket, the blob is only
+// copied server-side when bucketName doesn't store it yet
+func copyReference(ctx context.Context, sourceBucket string, bucketName string, objectName string, digest string, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	storeBlob := func(blobName string, blobOpts minio.PutObjectOptions) (minio.UploadInfo, error) {
+		dst := minio.CopyDestOptions{
+			Bucket:          bucketName,
+			Object:          blobName,
+			Encryption:      blobOpts.ServerSideEncryption,
+			ReplaceMetadata: true,
+			UserMetadata:    withContentType(nil, blobOpts.ContentType),
+		}
+		return storage.MinioClient.ComposeObject(ctx, dst, minio.CopySrcOptions{Bucket: sourceBucket, Object: blobObjectName(digest)})
+	}
+	return replaceReference(ctx, bucketName, objectName, digest, size, opts, storeBlob)
+}
+
+// storeBlobFunc    Store the missing blob blobName of a reference with blobOpts
+type storeBlobFunc func(blobName string, blobOpts minio.PutObjectOptions) (minio.UploadInfo, error)
+
+// replaceReference function    referenceBlob, then release the blob objectName pointed to before
+func replaceReference(ctx context.Context, bucketName string, objectName string, digest string, size int64, opts minio.PutObjectOptions, storeBlob storeBlobFunc) (minio.UploadInfo, error) {
+	previous, _ := referencedBlob(ctx, bucketName, objectName)
+
+	uploadInfo, err := referenceBlob(ctx, bucketName, objectName, digest, size, opts, storeBlob)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	// objectName was a reference to other content, which loses a reference
+	if previous != "" && previous != digest {
+		err := releaseBlob(ctx, bucketName, previous, objectName)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	return uploadInfo, nil
+}
+
+// referenceBlob function    Store the blob of digest with storeBlob when missing, then the marker and the reference record of objectName
+func referenceBlob(ctx context.Context, bucketName string, objectName string, digest string, size int64, opts minio.PutObjectOptions, storeBlob storeBlobFunc) (minio.UploadInfo, error) {
+	unlock := lockDigest(bucketName, digest)
+	defer unlock()
+
+	blobName := blobObjectName(digest)
+
+	// The marker goes first, a release of the same blob by another instance then sees the new reference and keeps the blob
+	markerOpts := minio.PutObjectOptions{ServerSideEncryption: opts.ServerSideEncryption}
+	_, err := storage.MinioClient.PutObject(ctx, bucketName, referenceMarkerName(digest, objectName), strings.NewReader(""), 0, markerOpts)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	blob, err := storage.MinioClient.StatObject(ctx, bucketName, blobName, minio.StatObjectOptions{})
+	switch {
+	case IsNotFound(err):
+		// The retention and legal hold apply to the reference record, the blob is removed with its last reference
+		blobOpts := opts
+		blobOpts.UserMetadata = nil
+		blobOpts.UserTags = nil
+		blobOpts.Mode = ""
+		blobOpts.RetainUntilDate = time.Time{}
+		blobOpts.LegalHold = ""
+		uploadInfo, err := storeBlob(blobName, blobOpts)
+		if err != nil {
+			return minio.UploadInfo{}, err
+		}
+		blob = minio.ObjectInfo{Key: blobName, Size: uploadInfo.Size, ETag: uploadInfo.ETag}
+		log.Printf("Stored new blob %s of size %d Bytes", blobName, uploadInfo.Size)
+	case err != nil:
+		return minio.UploadInfo{}, err
+	default:
+		log.Printf("Deduplicated %s, blob %s already stored", objectName, blobName)
+	}
+
+	recordOpts := minio.PutObjectOptions{
+		ServerSideEncryption: opts.ServerSideEncryption,
+		ContentType:          opts.ContentType,
+		UserMetadata:         make(map[string]string, len(opts.UserMetadata)+3),
+		UserTags:             opts.UserTags,
+		Mode:                 opts.Mode,
+		RetainUntilDate:      opts.RetainUntilDate,
+		LegalHold:            opts.LegalHold,
+	}
+	for k, v := range opts.UserMetadata {
+		recordOpts.UserMetadata[k] = v
+	}
+	recordOpts.UserMetadata[MetadataDedupBlob] = digest
+	recordOpts.UserMetadata[MetadataDedupSize] = strconv.FormatInt(size, 10)
+	recordOpts.UserMetadata[MetadataDedupETag] = blob.ETag
+
+	record, err := storage.MinioClient.PutObject(ctx, bucketName, objectName, strings.NewReader(""), 0, recordOpts)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	return minio.UploadInfo{
+		Bucket:    bucketName,
+		Key:       objectName,
+		ETag:      blob.ETag,
+		Size:      size,
+		VersionID: record.VersionID,
+	}, nil
+}
+
+// hashContent function    SHA-256 of the content and the content to upload from the start. Files are hashed in place,
+// other readers are spooled to a temporary file, so that the content is only uploaded once its blob is known to be missing.
+func hashContent(reader io.Reader) (io.ReadCloser, string, int64, error) {
+	hash := sha256.New()
+
+	if file, ok := reader.(*os.File); ok {
+		start, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		size, err := io.Copy(hash, file)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		_, err = file.Seek(start, io.SeekStart)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		// The caller owns the file
+		return io.NopCloser(file), hex.EncodeToString(hash.Sum(nil)), size, nil
+	}
+
+	spool, err := os.CreateTemp("", "dedup-")
+	if err != nil {
+		return nil, "", 0, err
+	}
+	content := &spoolFile{File: spool}
+	size, err := io.Copy(spool, io.TeeReader(reader, hash))
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		content.Close()
+		return nil, "", 0, err
+	}
+	return content, hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// spoolFile    Temporary file removed on Close
+type spoolFile struct {
+	*os.File
+}
+
+func (f *spoolFile) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); removeErr != nil {
+		log.Println(removeErr)
+	}
+	return err
+}
+
+// digestLocks    Serialize the references and releases of a blob in this process, otherwise a release counting no
+// reference could remove the blob while an upload of the same content adds one
+var digestLocks = struct {
+	sync.Mutex
+	locks map[string]*digestLock
+}{locks: map[string]*digestLock{}}
+
+type digestLock struct {
+	sync.Mutex
+	users int
+}
+
+// lockDigest function    Lock the blob of digest in bucketName, the returned function unlocks it
+func lockDigest(bucketName string, digest string) func() {
+	key := bucketName + "/" + digest
+
+	digestLocks.Lock()
+	lock, ok := digestLocks.locks[key]
+	if !ok {
+		lock = &digestLock{}
+		digestLocks.locks[key] = lock
+	}
+	lock.users++
+	digestLocks.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		digestLocks.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(digestLocks.locks, key)
+		}
+		digestLocks.Unlock()
+	}
+}
+
+// removeReference function    Remove the reference record objectName, returns false when objectName is not a reference
+func removeReference(ctx context.Context, bucketName string, objectName string, opts minio.RemoveObjectOptions) (bool, error) {
+	digest, err := referencedBlob(ctx, bucketName, objectName)
+	if err != nil || digest == "" {
+		return false, err
+	}
+
+	err = storage.MinioClient.RemoveObject(ctx, bucketName, objectName, opts)
+	if err != nil {
+		return true, err
+	}
+	return true, releaseBlob(ctx, bucketName, digest, objectName)
+}
+
+// releaseBlob function    Drop the reference marker of objectName, the blob is removed when it was the last reference
+func releaseBlob(ctx context.Context, bucketName string, digest string, objectName string) error {
+	unlock := lockDigest(bucketName, digest)
+	defer unlock()
+	return releaseLockedBlob(ctx, bucketName, digest, objectName)
+}
+
+// releaseLockedBlob function    releaseBlob with the digest already locked
+func releaseLockedBlob(ctx context.Context, bucketName string, digest string, objectName string) error {
+	err := storage.MinioClient.RemoveObject(ctx, bucketName, referenceMarkerName(digest, objectName), minio.RemoveObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	references, err := ReferenceCount(ctx, bucketName, digest)
+	if err != nil || references > 0 {
+		return err
+	}
+
+	err = storage.MinioClient.RemoveObject(ctx, bucketName, blobObjectName(digest), minio.RemoveObjectOptions{})
+	if err != nil {
+		return err
+	}
+	log.Printf("Removed blob %s, no references left", digest)
+	return nil
+}
+
+// ReferenceCount function    Number of objects pointing to the blob of a digest
+func ReferenceCount(ctx context.Context, bucketName string, digest string) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	count := 0
+	for o := range storage.MinioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: DedupPrefix() + "refs/" + digest + "/", Recursive: true}) {
+		if o.Err != nil {
+			return 0, o.Err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// StartDedupSweeper function    Sweep the dedup references of every bucket now and then every interval (only now when 0) until ctx is done
+func StartDedupSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		for {
+			err := SweepDedup(ctx)
+			if err != nil {
+				log.Println("dedup sweep:", err)
+			}
+			if interval <= 0 {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// SweepDedup function    Release the references whose record was removed or overwritten behind the service, e.g. expired by a
+// lifecycle rule, so that their blobs don't leak
+func SweepDedup(ctx context.Context) error {
+	buckets, err := storage.MinioClient.ListBuckets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		released, err := sweepBucket(ctx, bucket.Name)
+		if err != nil {
+			return err
+		}
+		if released > 0 {
+			log.Printf("Released %d stale dedup references in %s", released, bucket.Name)
+		}
+	}
+	return nil
+}
+
+func sweepBucket(ctx context.Context, bucketName string) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Collect the markers first, releasing them removes objects of the listing
+	type reference struct{ digest, objectName string }
+	var references []reference
+	refsPrefix := DedupPrefix() + "refs/"
+	for o := range storage.MinioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: refsPrefix, Recursive: true}) {
+		if o.Err != nil {
+			return 0, o.Err
+		}
+		digest, escapedName, ok := strings.Cut(strings.TrimPrefix(o.Key, refsPrefix), "/")
+		objectName, err := url.PathUnescape(escapedName)
+		if !ok || err != nil {
+			log.Println("dedup sweep: invalid reference marker", o.Key)
+			continue
+		}
+		references = append(references, reference{digest: digest, objectName: objectName})
+	}
+
+	released := 0
+	for _, ref := range references {
+		stale, err := releaseStaleReference(ctx, bucketName, ref.digest, ref.objectName)
+		if err != nil {
+			return released, err
+		}
+		if stale {
+			released++
+		}
+	}
+	return released, nil
+}
+
+// releaseStaleReference function    Release the reference of objectName to digest when objectName doesn't point to it anymore
+func releaseStaleReference(ctx context.Context, bucketName string, digest string, objectName string) (bool, error) {
+	// Uploads hold the lock from the marker to the record, a reference being added isn't stale
+	unlock := lockDigest(bucketName, digest)
+	defer unlock()
+
+	current, err := referencedBlob(ctx, bucketName, objectName)
+	if err != nil || current == digest {
+		return false, err
+	}
+	return true, releaseLockedBlob(ctx, bucketName, digest, objectName)
+}
+
+// resolveObject function    Object holding the content of objectName, the blob when objectName is a reference record.
+// The returned info describes objectName with the size and ETag of the content.
+func resolveObject(ctx context.Context, bucketName string, objectName string) (string, minio.ObjectInfo, error) {
+	info, err := storage.MinioClient.StatObject(ctx, bucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return "", minio.ObjectInfo{}, err
+	}
+	digest := metadataValue(info.UserMetadata, MetadataDedupBlob)
+	if digest == "" {
+		return objectName, info, nil
+	}
+
+	blob, err := storage.MinioClient.StatObject(ctx, bucketName, blobObjectName(digest), minio.StatObjectOptions{})
+	if err != nil {
+		return "", minio.ObjectInfo{}, err
+	}
+	info.Size = blob.Size
+	info.ETag = blob.ETag
+	return blob.Key, info, nil
+}
+
+// referencedBlob function    Digest of the blob objectName points to, empty when objectName is a plain object or missing
+func referencedBlob(ctx context.Context, bucketName string, objectName string) (string, error) {
+	info, err := storage.MinioClient.StatObject(ctx, bucketName, objectName, minio.StatObjectOptions{})
+	if IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return metadataValue(info.UserMetadata, MetadataDedupBlob), nil
+}
+
+// dedupListing function    Hide the dedup objects and the variants from a listing and report the size and ETag of the content of reference records
+func dedupListing(o minio.ObjectInfo) (minio.ObjectInfo, bool) {
+	if dto.ServiceObject(o.Key) {
+		return o, false
+	}
+	if metadataValue(o.UserMetadata, MetadataDedupBlob) != "" {
+		o.Size, _ = strconv.ParseInt(metadataValue(o.UserMetadata, MetadataDedupSize), 10, 64)
+		o.ETag = metadataValue(o.UserMetadata, MetadataDedupETag)
+	}
+	return o, true
+}
+
+// metadataValue function    User metadata value, listings with metadata keep the X-Amz-Meta- prefix of the keys
+func metadataValue(userMetadata map[string]string, key string) string {
+	if value, ok := userMetadata[key]; ok {
+		return value
+	}
+	return userMetadata["X-Amz-Meta-"+key]
+}
+
+func blobObjectName(digest string) string {
+	return DedupPrefix() + "blobs/sha256/" + digest
+}
+
+func referenceMarkerName(digest string, objectName string) string {
+	return DedupPrefix() + "refs/" + digest + "/" + url.PathEscape(objectName)
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/storage"
+	"github.com/pavva91/file-upload/internal/testutil/fakes3"
+)
+
+const testBucket = "testbucket"
+
+func newDedupStorage(t *testing.T) *fakes3.Server {
+	s3 := fakes3.New()
+	t.Cleanup(s3.Close)
+
+	minioClient, err := s3.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MinioClient = minioClient
+	err = minioClient.MakeBucket(context.Background(), testBucket, minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.ServerConfigValues.Minio.EnableDedup = true
+	config.ServerConfigValues.Minio.EnableMultipartUpload = true
+	config.ServerConfigValues.Minio.FileChunkSize = 5
+	t.Cleanup(func() {
+		config.ServerConfigValues.Minio.EnableDedup = false
+	})
+	return s3
+}
+
+func TestDedup(t *testing.T) {
+	s3 := newDedupStorage(t)
+	ctx := context.Background()
+	content := strings.Repeat("artifact", 1000)
+	digest := sha256Hex(content)
+
+	upload := func(objectName string, content string) UploadInfo {
+		uploadInfo, err := EncryptAndUploadStream(objectName, strings.NewReader(content), -1, testBucket, UploadOptions{ContentType: "text/plain"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uploadInfo
+	}
+
+	first := upload("builds/1/app.bin", content)
+	second := upload("builds/2/app.bin", content)
+	if first.Size != int64(len(content)) || first.ETag != second.ETag {
+		t.Errorf("got %+v and %+v, want the size and ETag of the same content", first, second)
+	}
+
+	blobs, err := ListObjects(ctx, testBucket, DedupPrefix(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 0 {
+		t.Errorf("got %d dedup objects in the listing, want them hidden", len(blobs))
+	}
+	if blob := s3.Object(testBucket, blobObjectName(digest)); blob == nil || string(blob.Data) != content {
+		t.Fatalf("blob of %s not stored", digest)
+	}
+	if count, _ := ReferenceCount(ctx, testBucket, digest); count != 2 {
+		t.Errorf("got %d references, want 2", count)
+	}
+
+	objects, err := ListObjects(ctx, testBucket, "builds/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range objects {
+		if o.Size != int64(len(content)) || o.ETag != first.ETag {
+			t.Errorf("%s: got size %d ETag %s, want %d %s", o.Key, o.Size, o.ETag, len(content), first.ETag)
+		}
+	}
+
+	object, info, err := GetObject(testBucket, "builds/2/app.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(object)
+	object.Close()
+	if string(data) != content || info.ContentType != "text/plain; charset=utf-8" || info.Key != "builds/2/app.bin" {
+		t.Errorf("got %d bytes of %s (%s), want the content of builds/2/app.bin", len(data), info.Key, info.ContentType)
+	}
+
+	// Overwriting a reference with other content releases the old blob only when unused
+	upload("builds/2/app.bin", "other content")
+	if count, _ := ReferenceCount(ctx, testBucket, digest); count != 1 {
+		t.Errorf("got %d references after the overwrite, want 1", count)
+	}
+
+	err = RemoveObject("builds/1/app.bin", testBucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s3.Object(testBucket, blobObjectName(digest)) != nil {
+		t.Errorf("blob still stored after the last reference was removed")
+	}
+	if s3.Object(testBucket, blobObjectName(sha256Hex("other content"))) == nil {
+		t.Errorf("blob of builds/2/app.bin removed")
+	}
+	for o := range storage.MinioClient.ListObjects(ctx, testBucket, minio.ListObjectsOptions{Prefix: DedupPrefix() + "staging/", Recursive: true}) {
+		t.Errorf("staging object %s left behind", o.Key)
+	}
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDedupConcurrentReferences(t *testing.T) {
+	s3 := newDedupStorage(t)
+	ctx := context.Background()
+	content := "shared content"
+	digest := sha256Hex(content)
+
+	_, err := EncryptAndUploadStream("old.txt", strings.NewReader(content), -1, testBucket, UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hold the removal of the blob, released with its last reference, while the same content is uploaded again
+	deleting := make(chan struct{})
+	uploaded := make(chan struct{})
+	var once sync.Once
+	s3.BeforeRequest = func(r *http.Request) {
+		if r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/"+blobObjectName(digest)) {
+			once.Do(func() { close(deleting) })
+			select {
+			case <-uploaded:
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := RemoveObject("old.txt", testBucket); err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(uploaded)
+		<-deleting
+		_, err := EncryptAndUploadStream("new.txt", strings.NewReader(content), -1, testBucket, UploadOptions{})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+
+	if s3.Object(testBucket, blobObjectName(digest)) == nil {
+		t.Fatal("blob removed while new.txt references it")
+	}
+	if count, _ := ReferenceCount(ctx, testBucket, digest); count != 1 {
+		t.Errorf("got %d references, want 1", count)
+	}
+}
+
+func TestHashContent(t *testing.T) {
+	content := "hash me"
+
+	file, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		reader io.Reader
+	}{
+		"file hashed in place": {reader: file},
+		"stream spooled":       {reader: strings.NewReader(content)},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			spooled, digest, size, err := hashContent(test.reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer spooled.Close()
+
+			data, err := io.ReadAll(spooled)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if digest != sha256Hex(content) || size != int64(len(content)) || string(data) != content {
+				t.Errorf("got %s of %d bytes (%q), want %s of %d bytes", digest, size, data, sha256Hex(content), len(content))
+			}
+		})
+	}
+}
+
+func TestSweepDedup(t *testing.T) {
+	s3 := newDedupStorage(t)
+	ctx := context.Background()
+
+	upload := func(objectName string, content string) {
+		_, err := EncryptAndUploadStream(objectName, strings.NewReader(content), -1, testBucket, UploadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	upload("expired.txt", "expired content")
+	upload("shared/1.txt", "shared content")
+	upload("shared/2.txt", "shared content")
+	upload("overwritten.txt", "old content")
+
+	// Removed behind the service, like an expiration of a lifecycle rule
+	for _, objectName := range []string{"expired.txt", "shared/1.txt"} {
+		err := storage.MinioClient.RemoveObject(ctx, testBucket, objectName, minio.RemoveObjectOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := storage.MinioClient.PutObject(ctx, testBucket, "overwritten.txt", strings.NewReader("plain"), 5, minio.PutObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SweepDedup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		content    string
+		references int
+		blobKept   bool
+	}{
+		"expired reference":           {content: "expired content", references: 0, blobKept: false},
+		"one of two references":       {content: "shared content", references: 1, blobKept: true},
+		"overwritten by plain object": {content: "old content", references: 0, blobKept: false},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			digest := sha256Hex(test.content)
+			if count, _ := ReferenceCount(ctx, testBucket, digest); count != test.references {
+				t.Errorf("got %d references, want %d", count, test.references)
+			}
+			if kept := s3.Object(testBucket, blobObjectName(digest)) != nil; kept != test.blobKept {
+				t.Errorf("got blob kept %t, want %t", kept, test.blobKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/index"
+	"github.com/pavva91/file-upload/internal/notifications"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+// Objects written to the index per transaction by the full scan
+const indexBatchSize = 1000
+
+var ErrIndexDisabled = errors.New("the metadata index is disabled, enable it in the index section of the config")
+
+// SearchObjects function    Objects of the metadata index matching the query and the total number of matches
+func SearchObjects(ctx context.Context, query index.Query) ([]index.Object, int, error) {
+	if storage.MetadataIndex == nil {
+		return nil, 0, ErrIndexDisabled
+	}
+	return storage.MetadataIndex.Search(ctx, query)
+}
+
+// StartIndexReconciler function    Reconcile the index with the buckets now and then every interval (only now when 0) until ctx is done
+func StartIndexReconciler(ctx context.Context, interval time.Duration) {
+	go func() {
+		for {
+			err := ReconcileIndex(ctx)
+			if err != nil {
+				log.Println("index reconciliation:", err)
+			}
+			if interval <= 0 {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// ReconcileIndex function    Full scan of the buckets, indexing every object and removing the ones missed by the updates
+func ReconcileIndex(ctx context.Context) error {
+	if storage.MetadataIndex == nil {
+		return ErrIndexDisabled
+	}
+
+	buckets, err := storage.MinioClient.ListBuckets(ctx)
+	if err != nil {
+		return err
+	}
+
+	bucketNames := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		bucketNames = append(bucketNames, bucket.Name)
+		err := reconcileBucket(ctx, bucket.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return storage.MetadataIndex.RemoveBucketsExcept(ctx, bucketNames)
+}
+
+func reconcileBucket(ctx context.Context, bucketName string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scanStart := time.Now()
+	indexed := 0
+	batch := make([]index.Object, 0, indexBatchSize)
+	flush := func() error {
+		err := storage.MetadataIndex.Put(ctx, batch...)
+		indexed += len(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	// Content types, user metadata and tags are only listed by MinIO
+	for o := range storage.MinioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Recursive: true, WithMetadata: true}) {
+		if o.Err != nil {
+			return o.Err
+		}
+		o, visible := dedupListing(o)
+		if !visible {
+			continue
+		}
+
+		batch = append(batch, listedIndexObject(bucketName, o))
+		if len(batch) == indexBatchSize {
+			err := flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err := flush()
+	if err != nil {
+		return err
+	}
+
+	removed, err := storage.MetadataIndex.RemoveStale(ctx, bucketName, scanStart)
+	if err != nil {
+		return err
+	}
+	log.Printf("Reconciled the index of bucket %s: %d objects, %d removed", bucketName, indexed, removed)
+	return nil
+}
+
+// listedIndexObject function    Index object of a listing with metadata, user metadata keys keep the X-Amz-Meta- prefix there
+func listedIndexObject(bucketName string, o minio.ObjectInfo) index.Object {
+	contentType := o.ContentType
+	metadata := map[string]string{}
+	for k, v := range o.UserMetadata {
+		if key, ok := strings.CutPrefix(k, "X-Amz-Meta-"); ok {
+			metadata[key] = v
+		} else if strings.EqualFold(k, "Content-Type") && contentType == "" {
+			contentType = v
+		}
+	}
+
+	return index.Object{
+		Bucket:       bucketName,
+		Name:         o.Key,
+		Size:         o.Size,
+		ETag:         o.ETag,
+		ContentType:  contentType,
+		LastModified: o.LastModified,
+		Metadata:     filterUserMetadata(metadata),
+		Tags:         o.UserTags,
+	}
+}
+
+// IndexEvent function    Notification consumer keeping the index in sync with the changes made outside the API.
+// The object is looked up instead of trusting the event, events of overwritten or re-created objects can arrive late.
+func IndexEvent(ctx context.Context, event notifications.Event) {
+	if storage.MetadataIndex == nil || dto.ServiceObject(event.Key) {
+		return
+	}
+
+	_, info, err := resolveObject(ctx, event.Bucket, event.Key)
+	if IsNotFound(err) {
+		unindexObject(ctx, event.Bucket, event.Key)
+		return
+	}
+	if err != nil {
+		log.Println("index:", err)
+		return
+	}
+
+	objectTags, err := storage.MinioClient.GetObjectTagging(ctx, event.Bucket, event.Key, minio.GetObjectTaggingOptions{})
+	if err != nil && !IsNotFound(err) {
+		log.Println("index:", err)
+		return
+	}
+	tags := map[string]string{}
+	if objectTags != nil {
+		tags = objectTags.ToMap()
+	}
+
+	err = storage.MetadataIndex.Put(ctx, index.Object{
+		Bucket:       event.Bucket,
+		Name:         event.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+		Metadata:     filterUserMetadata(info.UserMetadata),
+		Tags:         tags,
+	})
+	if err != nil {
+		log.Println("index:", err)
+	}
+}
+
+// indexUpload function    Index an uploaded object, failures are left to the next full scan
+func indexUpload(ctx context.Context, bucketName string, uploadInfo minio.UploadInfo, opts minio.PutObjectOptions) {
+	if storage.MetadataIndex == nil {
+		return
+	}
+
+	lastModified := uploadInfo.LastModified
+	if lastModified.IsZero() {
+		lastModified = time.Now().UTC()
+	}
+	err := storage.MetadataIndex.Put(ctx, index.Object{
+		Bucket:       bucketName,
+		Name:         uploadInfo.Key,
+		Size:         uploadInfo.Size,
+		ETag:         uploadInfo.ETag,
+		ContentType:  opts.ContentType,
+		LastModified: lastModified,
+		Metadata:     filterUserMetadata(opts.UserMetadata),
+		Tags:         opts.UserTags,
+	})
+	if err != nil {
+		log.Println("index:", err)
+	}
+}
+
+func unindexObject(ctx context.Context, bucketName string, objectName string) {
+	if storage.MetadataIndex == nil {
+		return
+	}
+
+	err := storage.MetadataIndex.Delete(ctx, bucketName, objectName)
+	if err != nil {
+		log.Println("index:", err)
+	}
+}
+
+func indexTags(ctx context.Context, bucketName string, objectName string, tags map[string]string) {
+	if storage.MetadataIndex == nil {
+		return
+	}
+
+	err := storage.MetadataIndex.SetTags(ctx, bucketName, objectName, tags)
+	if err != nil {
+		log.Println("index:", err)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/index"
+	"github.com/pavva91/file-upload/internal/notifications"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+func TestIndexEvent(t *testing.T) {
+	newDedupStorage(t)
+	ctx := context.Background()
+
+	metadataIndex, err := index.Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MetadataIndex = metadataIndex
+	t.Cleanup(func() {
+		storage.MetadataIndex = nil
+		metadataIndex.Close()
+	})
+
+	indexed := func() map[string]int64 {
+		objects, _, err := SearchObjects(ctx, index.Query{Bucket: testBucket})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes := map[string]int64{}
+		for _, o := range objects {
+			sizes[o.Name] = o.Size
+		}
+		return sizes
+	}
+
+	// Deduplicated through the API, then overwritten and removed behind it
+	content := strings.Repeat("x", 100)
+	_, err = EncryptAndUploadStream("dedup.bin", strings.NewReader(content), -1, testBucket, UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = storage.MinioClient.PutObject(ctx, testBucket, "direct.txt", strings.NewReader("hello"), 5, minio.PutObjectOptions{
+		UserMetadata: map[string]string{"Owner": "ops"},
+		UserTags:     map[string]string{"stage": "raw"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		event notifications.Event
+		want  map[string]int64
+	}{
+		"created outside the API": {
+			event: notifications.Event{Name: "s3:ObjectCreated:Put", Bucket: testBucket, Key: "direct.txt"},
+			want:  map[string]int64{"dedup.bin": 100, "direct.txt": 5},
+		},
+		"dedup objects ignored": {
+			event: notifications.Event{Name: "s3:ObjectCreated:Put", Bucket: testBucket, Key: blobObjectName(sha256Hex(content))},
+			want:  map[string]int64{"dedup.bin": 100, "direct.txt": 5},
+		},
+		"late removal of an existing object": {
+			event: notifications.Event{Name: "s3:ObjectRemoved:Delete", Bucket: testBucket, Key: "dedup.bin"},
+			want:  map[string]int64{"dedup.bin": 100, "direct.txt": 5},
+		},
+		"removed outside the API": {
+			event: notifications.Event{Name: "s3:ObjectRemoved:Delete", Bucket: testBucket, Key: "direct.txt"},
+			want:  map[string]int64{"dedup.bin": 100},
+		},
+	}
+
+	// Run in order, the removal happens between the cases
+	for _, name := range []string{"created outside the API", "dedup objects ignored", "late removal of an existing object", "removed outside the API"} {
+		test := tests[name]
+
+		t.Run(name, func(t *testing.T) {
+			if test.event.IsRemoved() && test.event.Key == "direct.txt" {
+				err := storage.MinioClient.RemoveObject(ctx, testBucket, "direct.txt", minio.RemoveObjectOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			IndexEvent(ctx, test.event)
+
+			got := indexed()
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for name, size := range test.want {
+				if got[name] != size {
+					t.Errorf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+
+	objects, _, err := SearchObjects(ctx, index.Query{Bucket: testBucket, Tags: map[string]string{"stage": "raw"}, Metadata: map[string]string{"Owner": "ops"}})
+	if err != nil || len(objects) != 0 {
+		t.Errorf("got %v (%v), want direct.txt removed with its tags and metadata", objects, err)
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+// TagExpiresAfter    Object tag set by the X-Expires-After upload header, matched by the expiresAfterRulePrefix rules
+const TagExpiresAfter = "expires-after"
+
+// Rules derived from lifecycle.expires-after, one per number of days: expires-after-7 expires the objects tagged expires-after=7
+const expiresAfterRulePrefix = "expires-after-"
+
+var (
+	ErrExpiresAfter = errors.New("Insert valid X-Expires-After")
+	// ErrLifecycleRule    A rule would act on the dedup blobs and markers, the references would lose their content
+	ErrLifecycleRule = errors.New("Insert valid lifecycle rule")
+)
+
+// LifecycleRule    Actions on the objects under a prefix, and with tags when set
+type LifecycleRule struct {
+	ID     string
+	Prefix string
+	Tags   map[string]string
+	// ExpirationDays after the creation of the objects
+	ExpirationDays int
+	// NoncurrentExpirationDays after a version of an object is replaced
+	NoncurrentExpirationDays int
+	// AbortIncompleteUploadDays after a multipart upload is started
+	AbortIncompleteUploadDays int
+	// TransitionDays after the creation of the objects they move to TransitionStorageClass
+	TransitionDays         int
+	TransitionStorageClass string
+}
+
+// Buckets whose expires-after rules are in place, the X-Expires-After uploads check the other ones once
+var expiresAfterReady sync.Map
+
+// GetLifecycle function    Lifecycle rules of a bucket, the expires-after rules included
+func GetLifecycle(bucketName string) ([]LifecycleRule, error) {
+	configuration, err := storage.MinioClient.GetBucketLifecycle(context.Background(), bucketName)
+	if notConfigured(err) {
+		return []LifecycleRule{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lifecycleRules(configuration), nil
+}
+
+// PutLifecycle function    Replace the lifecycle rules of a bucket, the expires-after rules of the config are kept
+func PutLifecycle(bucketName string, rules []LifecycleRule) error {
+	err := checkDedupRules(rules)
+	if err != nil {
+		return err
+	}
+	err = storage.MinioClient.SetBucketLifecycle(context.Background(), bucketName, lifecycleConfiguration(withExpiresAfterRules(rules)))
+	if err != nil {
+		return err
+	}
+	expiresAfterReady.Store(bucketName, true)
+	return nil
+}
+
+// ApplyLifecycleConfig function    Set the lifecycle rules of lifecycle.buckets, and the expires-after rules on the
+// bucket of the config
+func ApplyLifecycleConfig() error {
+	configured := map[string]bool{}
+	for _, bucket := range config.ServerConfigValues.Lifecycle.Buckets {
+		rules := make([]LifecycleRule, 0, len(bucket.Rules))
+		for _, rule := range bucket.Rules {
+			rules = append(rules, LifecycleRule{
+				ID:                        rule.ID,
+				Prefix:                    rule.Prefix,
+				Tags:                      rule.Tags,
+				ExpirationDays:            rule.ExpirationDays,
+				NoncurrentExpirationDays:  rule.NoncurrentExpirationDays,
+				AbortIncompleteUploadDays: rule.AbortIncompleteUploadDays,
+				TransitionDays:            rule.TransitionDays,
+				TransitionStorageClass:    rule.TransitionStorageClass,
+			})
+		}
+		err := PutLifecycle(bucket.Bucket, rules)
+		if err != nil {
+			return fmt.Errorf("lifecycle of bucket %s: %w", bucket.Bucket, err)
+		}
+		configured[bucket.Bucket] = true
+		log.Printf("Applied %d lifecycle rules to bucket %s", len(rules), bucket.Bucket)
+	}
+
+	bucketName := config.ServerConfigValues.Minio.Bucket
+	if !configured[bucketName] && len(config.ServerConfigValues.Lifecycle.ExpiresAfter) > 0 {
+		return ensureExpiresAfterRules(bucketName)
+	}
+	return nil
+}
+
+// ExpiresAfterTag function    Value of the TagExpiresAfter tag of an X-Expires-After header in days, one of lifecycle.expires-after.
+// The expires-after rules are added to the bucket when it misses them.
+func ExpiresAfterTag(bucketName string, header string) (string, error) {
+	allowed := config.ServerConfigValues.Lifecycle.ExpiresAfter
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("%w: no expiration configured", ErrExpiresAfter)
+	}
+
+	days, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || !slices.Contains(allowed, days) {
+		return "", fmt.Errorf("%w: days among %s", ErrExpiresAfter, strings.Trim(fmt.Sprint(allowed), "[]"))
+	}
+
+	if _, ok := expiresAfterReady.Load(bucketName); !ok {
+		err = ensureExpiresAfterRules(bucketName)
+		if err != nil {
+			return "", err
+		}
+	}
+	return strconv.Itoa(days), nil
+}
+
+// ensureExpiresAfterRules function    Add the missing expires-after rules to the rules of a bucket
+func ensureExpiresAfterRules(bucketName string) error {
+	rules, err := GetLifecycle(bucketName)
+	if err != nil {
+		return err
+	}
+	return PutLifecycle(bucketName, rules)
+}
+
+// checkDedupRules function    ErrLifecycleRule for the rules under the dedup prefix, and with dedup enabled for the
+// expirations without tags whose prefix covers it, e.g. the whole bucket. The blobs and markers have no tags, rules
+// filtering on tags never match them.
+func checkDedupRules(rules []LifecycleRule) error {
+	prefix := DedupPrefix()
+	for _, rule := range rules {
+		if strings.HasPrefix(rule.Prefix, prefix) {
+			return fmt.Errorf("%w %s: %s is reserved by the service", ErrLifecycleRule, rule.ID, prefix)
+		}
+		if config.ServerConfigValues.Minio.EnableDedup && rule.ExpirationDays > 0 && len(rule.Tags) == 0 && strings.HasPrefix(prefix, rule.Prefix) {
+			return fmt.Errorf("%w %s: the prefix %q covers the deduplicated blobs under %s, set a narrower prefix or tags", ErrLifecycleRule, rule.ID, rule.Prefix, prefix)
+		}
+	}
+	return nil
+}
+
+// withExpiresAfterRules function    rules without the expires-after ones, then the expires-after rules of the config.
+// They filter on the TagExpiresAfter tag, which the dedup blobs and markers never have.
+func withExpiresAfterRules(rules []LifecycleRule) []LifecycleRule {
+	merged := make([]LifecycleRule, 0, len(rules)+len(config.ServerConfigValues.Lifecycle.ExpiresAfter))
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.ID, expiresAfterRulePrefix) {
+			merged = append(merged, rule)
+		}
+	}
+
+	days := append([]int{}, config.ServerConfigValues.Lifecycle.ExpiresAfter...)
+	sort.Ints(days)
+	for _, d := range days {
+		value := strconv.Itoa(d)
+		merged = append(merged, LifecycleRule{
+			ID:             expiresAfterRulePrefix + value,
+			Tags:           map[string]string{TagExpiresAfter: value},
+			ExpirationDays: d,
+		})
+	}
+	return merged
+}
+
+// lifecycleConfiguration function    S3 lifecycle configuration of rules, all enabled
+func lifecycleConfiguration(rules []LifecycleRule) *lifecycle.Configuration {
+	configuration := lifecycle.NewConfiguration()
+	for _, rule := range rules {
+		s3Rule := lifecycle.Rule{
+			ID:         rule.ID,
+			Status:     "Enabled",
+			RuleFilter: lifecycleFilter(rule),
+			Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(rule.ExpirationDays)},
+			NoncurrentVersionExpiration: lifecycle.NoncurrentVersionExpiration{
+				NoncurrentDays: lifecycle.ExpirationDays(rule.NoncurrentExpirationDays),
+			},
+			AbortIncompleteMultipartUpload: lifecycle.AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: lifecycle.ExpirationDays(rule.AbortIncompleteUploadDays),
+			},
+		}
+		if rule.TransitionStorageClass != "" {
+			s3Rule.Transition = lifecycle.Transition{
+				Days:         lifecycle.ExpirationDays(rule.TransitionDays),
+				StorageClass: rule.TransitionStorageClass,
+			}
+		}
+		configuration.Rules = append(configuration.Rules, s3Rule)
+	}
+	return configuration
+}
+
+// lifecycleFilter function    Filter on the prefix of a rule, and on its tags when set
+func lifecycleFilter(rule LifecycleRule) lifecycle.Filter {
+	if len(rule.Tags) == 0 {
+		return lifecycle.Filter{Prefix: rule.Prefix}
+	}
+
+	filterTags := make([]lifecycle.Tag, 0, len(rule.Tags))
+	for k, v := range rule.Tags {
+		filterTags = append(filterTags, lifecycle.Tag{Key: k, Value: v})
+	}
+	sort.Slice(filterTags, func(i, j int) bool { return filterTags[i].Key < filterTags[j].Key })
+	if len(filterTags) == 1 && rule.Prefix == "" {
+		return lifecycle.Filter{Tag: filterTags[0]}
+	}
+	return lifecycle.Filter{And: lifecycle.And{Prefix: rule.Prefix, Tags: filterTags}}
+}
+
+// lifecycleRules function    Rules of an S3 lifecycle configuration
+func lifecycleRules(configuration *lifecycle.Configuration) []LifecycleRule {
+	rules := make([]LifecycleRule, 0, len(configuration.Rules))
+	for _, s3Rule := range configuration.Rules {
+		filter := s3Rule.RuleFilter
+		rule := LifecycleRule{
+			ID:                        s3Rule.ID,
+			Prefix:                    filter.Prefix,
+			ExpirationDays:            int(s3Rule.Expiration.Days),
+			NoncurrentExpirationDays:  int(s3Rule.NoncurrentVersionExpiration.NoncurrentDays),
+			AbortIncompleteUploadDays: int(s3Rule.AbortIncompleteMultipartUpload.DaysAfterInitiation),
+			TransitionDays:            int(s3Rule.Transition.Days),
+			TransitionStorageClass:    s3Rule.Transition.StorageClass,
+		}
+		switch {
+		case !filter.And.IsEmpty():
+			rule.Prefix = filter.And.Prefix
+			rule.Tags = make(map[string]string, len(filter.And.Tags))
+			for _, tag := range filter.And.Tags {
+				rule.Tags[tag.Key] = tag.Value
+			}
+		case !filter.Tag.IsEmpty():
+			rule.Tags = map[string]string{filter.Tag.Key: filter.Tag.Value}
+		case rule.Prefix == "":
+			// Deprecated prefix of the rule, outside of the filter
+			rule.Prefix = s3Rule.Prefix
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
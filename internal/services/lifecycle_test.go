@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/pavva91/file-upload/config"
+)
+
+func TestLifecycleConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		rule LifecycleRule
+	}{
+		"prefix":              {rule: LifecycleRule{ID: "tmp", Prefix: "tmp/", ExpirationDays: 7}},
+		"whole bucket":        {rule: LifecycleRule{ID: "all", ExpirationDays: 365}},
+		"one tag":             {rule: LifecycleRule{ID: "tag", Tags: map[string]string{"stage": "raw"}, ExpirationDays: 1}},
+		"prefix and tags":     {rule: LifecycleRule{ID: "and", Prefix: "logs/", Tags: map[string]string{"a": "1", "b": "2"}, ExpirationDays: 30}},
+		"noncurrent versions": {rule: LifecycleRule{ID: "versions", NoncurrentExpirationDays: 14}},
+		"incomplete uploads":  {rule: LifecycleRule{ID: "uploads", AbortIncompleteUploadDays: 2}},
+		"transition":          {rule: LifecycleRule{ID: "cold", Prefix: "archive/", TransitionDays: 30, TransitionStorageClass: "GLACIER"}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			data, err := xml.Marshal(lifecycleConfiguration([]LifecycleRule{test.rule}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			configuration := lifecycle.NewConfiguration()
+			err = xml.Unmarshal(data, configuration)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rules := lifecycleRules(configuration)
+			if len(rules) != 1 || !reflect.DeepEqual(rules[0], test.rule) {
+				t.Errorf("got %+v from %s, want %+v", rules, data, test.rule)
+			}
+		})
+	}
+}
+
+func TestWithExpiresAfterRules(t *testing.T) {
+	config.ServerConfigValues.Lifecycle.ExpiresAfter = []int{30, 1}
+	t.Cleanup(func() { config.ServerConfigValues.Lifecycle.ExpiresAfter = nil })
+
+	rules := withExpiresAfterRules([]LifecycleRule{
+		{ID: "tmp", Prefix: "tmp/", ExpirationDays: 7},
+		{ID: "expires-after-7", Tags: map[string]string{TagExpiresAfter: "7"}, ExpirationDays: 7},
+	})
+	want := []LifecycleRule{
+		{ID: "tmp", Prefix: "tmp/", ExpirationDays: 7},
+		{ID: "expires-after-1", Tags: map[string]string{TagExpiresAfter: "1"}, ExpirationDays: 1},
+		{ID: "expires-after-30", Tags: map[string]string{TagExpiresAfter: "30"}, ExpirationDays: 30},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("got %+v, want %+v", rules, want)
+	}
+}
+
+func TestCheckDedupRules(t *testing.T) {
+	config.ServerConfigValues.Minio.EnableDedup = true
+	t.Cleanup(func() { config.ServerConfigValues.Minio.EnableDedup = false })
+
+	tests := map[string]struct {
+		rule  LifecycleRule
+		valid bool
+	}{
+		"prefix":                   {rule: LifecycleRule{ID: "tmp", Prefix: "tmp/", ExpirationDays: 7}, valid: true},
+		"whole bucket with tags":   {rule: LifecycleRule{ID: "tag", Tags: map[string]string{"stage": "raw"}, ExpirationDays: 1}, valid: true},
+		"whole bucket noncurrent":  {rule: LifecycleRule{ID: "versions", NoncurrentExpirationDays: 14}, valid: true},
+		"whole bucket uploads":     {rule: LifecycleRule{ID: "uploads", AbortIncompleteUploadDays: 2}, valid: true},
+		"dot prefix of the bucket": {rule: LifecycleRule{ID: "hidden", Prefix: ".d/", ExpirationDays: 7}, valid: true},
+		"whole bucket":             {rule: LifecycleRule{ID: "all", ExpirationDays: 365}},
+		"prefix of the dedup one":  {rule: LifecycleRule{ID: "dot", Prefix: ".", ExpirationDays: 7}},
+		"dedup prefix":             {rule: LifecycleRule{ID: "dedup", Prefix: ".dedup/", ExpirationDays: 7}},
+		"dedup blobs with tags":    {rule: LifecycleRule{ID: "blobs", Prefix: ".dedup/blobs/", Tags: map[string]string{"a": "1"}, ExpirationDays: 7}},
+		"dedup markers transition": {rule: LifecycleRule{ID: "refs", Prefix: ".dedup/refs/", TransitionDays: 30, TransitionStorageClass: "GLACIER"}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			err := checkDedupRules([]LifecycleRule{test.rule})
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, want valid %t", err, test.valid)
+			}
+			if err != nil && !errors.Is(err, ErrLifecycleRule) {
+				t.Errorf("got %v, want %v", err, ErrLifecycleRule)
+			}
+		})
+	}
+
+	// The expires-after rules filter on their tag
+	config.ServerConfigValues.Lifecycle.ExpiresAfter = []int{7}
+	t.Cleanup(func() { config.ServerConfigValues.Lifecycle.ExpiresAfter = nil })
+	err := checkDedupRules(withExpiresAfterRules(nil))
+	if err != nil {
+		t.Errorf("got %v for the expires-after rules", err)
+	}
+
+	config.ServerConfigValues.Minio.EnableDedup = false
+	err = checkDedupRules([]LifecycleRule{{ID: "all", ExpirationDays: 365}})
+	if err != nil {
+		t.Errorf("got %v for the whole bucket without dedup", err)
+	}
+}
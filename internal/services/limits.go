@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+var ErrTooLarge = errors.New("object too large")
+
+// MaxObjectSize function    Maximum size in bytes of the objects uploaded to a bucket, 0 for no limit
+func MaxObjectSize(bucketName string) int64 {
+	limit := config.ServerConfigValues.Limits.MaxObjectSize
+	for _, bucket := range config.ServerConfigValues.Limits.Buckets {
+		if bucket.Bucket == bucketName && bucket.MaxObjectSize > 0 {
+			limit = bucket.MaxObjectSize
+		}
+	}
+	return int64(limit) * 1024 * 1024
+}
+
+// MaxUploadSize function    Largest object size accepted by any bucket, bounds the requests read before their bucket is known, 0 when no limit is configured
+func MaxUploadSize() int64 {
+	// Without a global limit, the buckets without their own limit are unlimited
+	if config.ServerConfigValues.Limits.MaxObjectSize <= 0 {
+		return 0
+	}
+	limit := config.ServerConfigValues.Limits.MaxObjectSize
+	for _, bucket := range config.ServerConfigValues.Limits.Buckets {
+		limit = max(limit, bucket.MaxObjectSize)
+	}
+	return int64(limit) * 1024 * 1024
+}
+
+// checkObjectSize function    ErrTooLarge when size exceeds the limit of the bucket
+func checkObjectSize(bucketName string, size int64) error {
+	if limit := MaxObjectSize(bucketName); limit > 0 && size > limit {
+		return tooLarge(bucketName, limit)
+	}
+	return nil
+}
+
+func tooLarge(bucketName string, limit int64) error {
+	return fmt.Errorf("%w: the limit of bucket %s is %d bytes", ErrTooLarge, bucketName, limit)
+}
+
+// sizeLimiter    Reader failing with ErrTooLarge once more than limit bytes are read, for the contents of unknown size
+type sizeLimiter struct {
+	reader     io.Reader
+	bucketName string
+	limit      int64
+	read       int64
+}
+
+func (l *sizeLimiter) Read(p []byte) (int, error) {
+	// One byte more than the limit tells a content of the exact limit from a larger one
+	if remaining := l.limit + 1 - l.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return 0, tooLarge(l.bucketName, l.limit)
+	}
+	return n, err
+}
+
+// limitObjectSize function    Reader of a content of size bytes (-1 when unknown) uploaded to a bucket, failing with ErrTooLarge past its limit
+func limitObjectSize(bucketName string, reader io.Reader, size int64) (io.Reader, error) {
+	limit := MaxObjectSize(bucketName)
+	if limit <= 0 {
+		return reader, nil
+	}
+	if size > limit {
+		return nil, tooLarge(bucketName, limit)
+	}
+	if size >= 0 {
+		return reader, nil
+	}
+	return &sizeLimiter{reader: reader, bucketName: bucketName, limit: limit}, nil
+}
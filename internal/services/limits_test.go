@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+)
+
+func TestMaxUploadSize(t *testing.T) {
+	const MiB = 1024 * 1024
+
+	tests := map[string]struct {
+		global  int
+		buckets []config.BucketLimits
+		want    int64
+	}{
+		"no limit":              {want: 0},
+		"global limit":          {global: 10, want: 10 * MiB},
+		"bucket above global":   {global: 10, buckets: []config.BucketLimits{{Bucket: "a", MaxObjectSize: 20}}, want: 20 * MiB},
+		"bucket below global":   {global: 10, buckets: []config.BucketLimits{{Bucket: "a", MaxObjectSize: 5}}, want: 10 * MiB},
+		"bucket without global": {buckets: []config.BucketLimits{{Bucket: "a", MaxObjectSize: 5}, {Bucket: "b", MaxObjectSize: 8}}, want: 0},
+		"negative global":       {global: -1, buckets: []config.BucketLimits{{Bucket: "a", MaxObjectSize: 5}}, want: 0},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			config.ServerConfigValues.Limits.MaxObjectSize = test.global
+			config.ServerConfigValues.Limits.Buckets = test.buckets
+			t.Cleanup(func() {
+				config.ServerConfigValues.Limits.MaxObjectSize = 0
+				config.ServerConfigValues.Limits.Buckets = nil
+			})
+
+			if got := MaxUploadSize(); got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -3,25 +3,34 @@ package services
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log"
+	"mime"
+	"net/url"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/cheggaaa/pb"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/minio/minio-go/v7/pkg/tags"
 	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/checksum"
 	"github.com/pavva91/file-upload/internal/storage"
 )
 
-func EncryptAndUploadFileMultipart(objectName string, filePath string, contentType string, bucketName string) (minio.UploadInfo, error) {
+// EncryptAndUploadFileMultipart function    Encrypt and upload a file of the server, the checksums of uploadOptions are verified while uploading
+func EncryptAndUploadFileMultipart(objectName string, filePath string, bucketName string, uploadOptions UploadOptions) (UploadInfo, error) {
 	ctx := context.Background()
 
 	// encryption, err := encrypt.NewSSEKMS("dev-key2", ctx)
 	encryption, err := encrypt.NewSSEKMS(config.ServerConfigValues.Minio.EncryptionKeyID, ctx)
 	if err != nil {
 		log.Println(err)
-		return minio.UploadInfo{}, err
+		return UploadInfo{}, err
 	}
 
 	sizeMiB := uint64(config.ServerConfigValues.Minio.FileChunkSize)
@@ -29,14 +38,33 @@ func EncryptAndUploadFileMultipart(objectName string, filePath string, contentTy
 	file, err := os.Open(filePath)
 	if err != nil {
 		log.Println(err)
-		return minio.UploadInfo{}, err
+		return UploadInfo{}, err
 	}
 	defer file.Close()
 
 	fileStat, err := file.Stat()
 	if err != nil {
 		log.Println(err)
-		return minio.UploadInfo{}, err
+		return UploadInfo{}, err
+	}
+	err = checkObjectSize(bucketName, fileStat.Size())
+	if err != nil {
+		return UploadInfo{}, err
+	}
+
+	head, _, err := sniff(file)
+	if err != nil {
+		log.Println(err)
+		return UploadInfo{}, err
+	}
+	contentType, err := ContentType(bucketName, objectName, head, uploadOptions.ContentType)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		log.Println(err)
+		return UploadInfo{}, err
 	}
 
 	// Progress reader is notified as PutObject makes progress with
@@ -44,44 +72,200 @@ func EncryptAndUploadFileMultipart(objectName string, filePath string, contentTy
 	progress := pb.New64(fileStat.Size())
 	progress.Start()
 
-	isMultipart := false
-
-	go func(bool) {
-		notificationChan := storage.MinioClient.ListenNotification(ctx, "", "", []string{
-			"s3:ObjectCreated:CompleteMultipartUpload",
-		})
-		notification := <-notificationChan
-		log.Println(notification)
-		isMultipart = true
-	}(isMultipart)
-
 	opts := minio.PutObjectOptions{
 		DisableMultipart:     !config.ServerConfigValues.Minio.EnableMultipartUpload,
 		PartSize:             1024 * 1024 * sizeMiB,
 		ServerSideEncryption: encryption,
 		Progress:             progress,
+		ContentType:          contentType,
+		UserMetadata:         uploadOptions.UserMetadata,
+		UserTags:             uploadOptions.Tags,
+	}
+
+	if scanned(bucketName) {
+		err = scanFile(ctx, bucketName, objectName, file, fileStat.Size(), &opts)
+		if err != nil {
+			log.Println(err)
+			return UploadInfo{}, err
+		}
 	}
 
-	uploadInfo, err := storage.MinioClient.PutObject(ctx, bucketName, objectName, file, fileStat.Size(), opts)
+	release, err := admitUpload(fileStat.Size(), &opts)
 	if err != nil {
 		log.Println(err)
-		return minio.UploadInfo{}, err
+		return UploadInfo{}, err
+	}
+	defer release()
+
+	uploadInfo, err := putVerifiedObject(ctx, bucketName, objectName, file, fileStat.Size(), opts, uploadOptions.Checksums)
+	if err != nil {
+		log.Println(err)
+		return UploadInfo{}, err
 	}
+	indexUpload(ctx, bucketName, uploadInfo, opts)
+	enqueueProcessing(ctx, bucketName, uploadInfo, opts)
 
 	log.Printf("Successfully uploaded %s of size %d Bytes\n", objectName, uploadInfo.Size)
 
-	time.Sleep(100 * time.Millisecond)
-	if isMultipart {
+	// PutObject switches to a multipart upload from one part, of the default size when the file-chunk-size is not set
+	if !opts.DisableMultipart && fileStat.Size() >= partSize(opts) {
 		log.Println("used multipart upload for object", objectName)
 	} else {
 		log.Println("did not use multipart upload for object", objectName)
 	}
 
+	return UploadInfo{UploadInfo: uploadInfo, ContentType: contentType}, nil
+}
+
+// putVerifiedObject function    putObject verifying the checksums of the content, the verified checksums are stored as user metadata.
+// On a mismatch the reader fails before the end of the content, so PutObject aborts the multipart upload.
+func putVerifiedObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions, checksums checksum.Checksums) (minio.UploadInfo, error) {
+	if checksums.IsZero() {
+		return putObject(ctx, bucketName, objectName, reader, size, opts)
+	}
+
+	verifier, ok := reader.(*checksum.Verifier)
+	if !ok {
+		verifier = checksum.NewVerifier(reader, size, checksums)
+	}
+	metadata := make(map[string]string, len(opts.UserMetadata)+3)
+	for k, v := range opts.UserMetadata {
+		metadata[k] = v
+	}
+	for k, v := range checksums.Metadata() {
+		metadata[k] = v
+	}
+	opts.UserMetadata = metadata
+
+	uploadInfo, err := putObject(ctx, bucketName, objectName, verifier, size, opts)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	// Empty contents can be uploaded without reading them
+	if !verifier.Verified() {
+		_, err = io.Copy(io.Discard, verifier)
+		if err != nil {
+			RemoveObject(objectName, bucketName)
+			return minio.UploadInfo{}, err
+		}
+	}
 	return uploadInfo, nil
 }
 
+// MetadataModTime    User metadata key with the modification time of the original file (RFC3339)
+const MetadataModTime = "Mtime"
+
+// UploadOptions    Object properties set on upload
+type UploadOptions struct {
+	// ContentType declared by the client, the stored type is detected from the content and reconciled with it
+	ContentType  string
+	UserMetadata map[string]string
+	Tags         map[string]string
+	// Checksums provided by the client, verified while uploading and stored as user metadata
+	Checksums checksum.Checksums
+}
+
+// EncryptAndUploadStream function    Encrypt and upload the content of reader, size is -1 when unknown (e.g. request bodies)
+func EncryptAndUploadStream(objectName string, reader io.Reader, size int64, bucketName string, uploadOptions UploadOptions) (UploadInfo, error) {
+	ctx := context.Background()
+
+	encryption, err := encrypt.NewSSEKMS(config.ServerConfigValues.Minio.EncryptionKeyID, ctx)
+	if err != nil {
+		log.Println(err)
+		return UploadInfo{}, err
+	}
+
+	sizeMiB := uint64(config.ServerConfigValues.Minio.FileChunkSize)
+
+	// The size and type are checked before anything is uploaded
+	reader, err = limitObjectSize(bucketName, reader, size)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	head, reader, err := sniff(reader)
+	if err != nil {
+		log.Println(err)
+		return UploadInfo{}, err
+	}
+	contentType, err := ContentType(bucketName, objectName, head, uploadOptions.ContentType)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+
+	// Verify while spooling, a mismatch is found before anything is uploaded
+	if !uploadOptions.Checksums.IsZero() {
+		reader = checksum.NewVerifier(reader, size, uploadOptions.Checksums)
+	}
+
+	// Without multipart upload the size must be known in advance, spool the stream to find it out.
+	// Scanned uploads are spooled too, they are stored only once the whole content is scanned.
+	var spool *os.File
+	if (size < 0 && !config.ServerConfigValues.Minio.EnableMultipartUpload) || scanned(bucketName) {
+		spool, err = os.CreateTemp("", "file-upload-*")
+		if err != nil {
+			log.Println(err)
+			return UploadInfo{}, err
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+
+		size, err = io.Copy(spool, reader)
+		if err != nil {
+			log.Println(err)
+			return UploadInfo{}, err
+		}
+		_, err = spool.Seek(0, io.SeekStart)
+		if err != nil {
+			log.Println(err)
+			return UploadInfo{}, err
+		}
+		reader = spool
+	}
+
+	opts := minio.PutObjectOptions{
+		DisableMultipart:     !config.ServerConfigValues.Minio.EnableMultipartUpload,
+		PartSize:             1024 * 1024 * sizeMiB,
+		ServerSideEncryption: encryption,
+		ContentType:          contentType,
+		UserMetadata:         uploadOptions.UserMetadata,
+		UserTags:             uploadOptions.Tags,
+	}
+
+	if scanned(bucketName) {
+		err = scanFile(ctx, bucketName, objectName, spool, size, &opts)
+		if err != nil {
+			log.Println(err)
+			return UploadInfo{}, err
+		}
+	}
+
+	release, err := admitUpload(size, &opts)
+	if err != nil {
+		log.Println(err)
+		return UploadInfo{}, err
+	}
+	defer release()
+
+	uploadInfo, err := putVerifiedObject(ctx, bucketName, objectName, reader, size, opts, uploadOptions.Checksums)
+	if err != nil {
+		log.Println(err)
+		return UploadInfo{}, err
+	}
+	indexUpload(ctx, bucketName, uploadInfo, opts)
+	enqueueProcessing(ctx, bucketName, uploadInfo, opts)
+
+	log.Printf("Successfully uploaded %s of size %d Bytes\n", objectName, uploadInfo.Size)
+	return UploadInfo{UploadInfo: uploadInfo, ContentType: contentType}, nil
+}
+
 func DownloadFile(bucket string, fileName string, downloadPath string) error {
-	err := storage.MinioClient.FGetObject(context.Background(), bucket, fileName, downloadPath, minio.GetObjectOptions{})
+	objectName, _, err := resolveObject(context.Background(), bucket, fileName)
+	if err != nil {
+		return err
+	}
+
+	err = storage.MinioClient.FGetObject(context.Background(), bucket, objectName, downloadPath, minio.GetObjectOptions{})
 	if err != nil {
 		return err
 	}
@@ -98,24 +282,16 @@ func BucketExist(bucket string) (bool, error) {
 }
 
 func CreateBucket(bucketName string) error {
-	found, err := storage.MinioClient.BucketExists(context.Background(), bucketName)
-	if err != nil {
-		log.Println(err)
-		return err
-	}
-	if found {
+	// Create a bucket at the region of the config with object locking enabled.
+	err := MakeBucket(bucketName, BucketOptions{ObjectLocking: true})
+	if errors.Is(err, ErrBucketExists) {
 		log.Println("Bucket", bucketName, "already exists")
 		return nil
 	}
-
-	// Create a bucket at region 'us-east-1' with object locking enabled.
-	region := config.ServerConfigValues.Minio.Region
-	err = storage.MinioClient.MakeBucket(context.Background(), bucketName, minio.MakeBucketOptions{Region: region, ObjectLocking: true})
 	if err != nil {
 		log.Println(err)
 		return err
 	}
-	log.Println("Successfully created bucket:", bucketName)
 	return nil
 }
 
@@ -125,13 +301,151 @@ func RemoveObject(object string, bucket string) error {
 		VersionID:        "", // remove latest object version
 	}
 
-	err := storage.MinioClient.RemoveObject(context.Background(), bucket, object, opts)
+	// Reference records also release their blob
+	removed, err := removeReference(context.Background(), bucket, object, opts)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	if !removed {
+		err = storage.MinioClient.RemoveObject(context.Background(), bucket, object, opts)
+	}
 	if err != nil {
 		log.Println(err)
 		return err
 	}
+	unindexObject(context.Background(), bucket, object)
+	removeDerivedObjects(context.Background(), bucket, object)
 
 	log.Printf("Successfully removed object %s from bucket %s", object, bucket)
 	return nil
 
 }
+
+// GetObject function    Content of an object, read from the blob of reference records
+func GetObject(bucket string, object string) (*minio.Object, minio.ObjectInfo, error) {
+	contentName, info, err := resolveObject(context.Background(), bucket, object)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+
+	o, err := storage.MinioClient.GetObject(context.Background(), bucket, contentName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+	return o, info, nil
+}
+
+// ListObjects function    Objects under prefix having all the tagFilters, tagFilters can be nil.
+// Tags are read from the listing with metadata, a MinIO extension of ListObjectsV2.
+func ListObjects(ctx context.Context, bucket string, prefix string, tagFilters map[string]string) ([]minio.ObjectInfo, error) {
+	var objects []minio.ObjectInfo
+
+	objectCh := storage.MinioClient.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+		// Reference records carry the size of their content in the metadata
+		WithMetadata: config.ServerConfigValues.Minio.EnableDedup || len(tagFilters) > 0,
+	})
+	for o := range objectCh {
+		if o.Err != nil {
+			return nil, o.Err
+		}
+		if !hasTags(o.UserTags, tagFilters) {
+			continue
+		}
+		if o, visible := dedupListing(o); visible {
+			objects = append(objects, o)
+		}
+	}
+	return objects, nil
+}
+
+func hasTags(objectTags map[string]string, tagFilters map[string]string) bool {
+	for k, v := range tagFilters {
+		if value, ok := objectTags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// GetObjectTagging function    Tags of an object
+func GetObjectTagging(bucket string, object string) (map[string]string, error) {
+	objectTags, err := storage.MinioClient.GetObjectTagging(context.Background(), bucket, object, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return objectTags.ToMap(), nil
+}
+
+// PutObjectTagging function    Replace the tags of an object
+func PutObjectTagging(bucket string, object string, objectTags map[string]string) error {
+	t, err := tags.NewTags(objectTags, true)
+	if err != nil {
+		return err
+	}
+	err = storage.MinioClient.PutObjectTagging(context.Background(), bucket, object, t, minio.PutObjectTaggingOptions{})
+	if err != nil {
+		return err
+	}
+	indexTags(context.Background(), bucket, object, objectTags)
+	return nil
+}
+
+// RemoveObjectTagging function    Remove all the tags of an object
+func RemoveObjectTagging(bucket string, object string) error {
+	err := storage.MinioClient.RemoveObjectTagging(context.Background(), bucket, object, minio.RemoveObjectTaggingOptions{})
+	if err != nil {
+		return err
+	}
+	indexTags(context.Background(), bucket, object, nil)
+	return nil
+}
+
+// PresignedGetObject function    Presigned url of the content, for reference records the blob served with the record content type and name
+func PresignedGetObject(bucket string, object string, expires time.Duration) (*url.URL, error) {
+	contentName, info, err := resolveObject(context.Background(), bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqParams url.Values
+	if contentName != object {
+		reqParams = url.Values{}
+		reqParams.Set("response-content-type", info.ContentType)
+		reqParams.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(object)}))
+	}
+	return storage.MinioClient.PresignedGetObject(context.Background(), bucket, contentName, expires, reqParams)
+}
+
+// IsNotFound function    Check if a minio error is caused by a missing bucket or object
+func IsNotFound(err error) bool {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket", "NoSuchVersion":
+		return true
+	}
+	return false
+}
+
+// UserMetadata function    User metadata of an object without the keys managed by the service (dedup references, checksums)
+func UserMetadata(objectInfo minio.ObjectInfo) map[string]string {
+	return filterUserMetadata(objectInfo.UserMetadata)
+}
+
+func filterUserMetadata(metadata map[string]string) map[string]string {
+	userMetadata := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if strings.HasPrefix(k, "Dedup-") || strings.HasPrefix(k, "Checksum-") {
+			continue
+		}
+		userMetadata[k] = v
+	}
+	return userMetadata
+}
+
+// StatObject function    Info of an object, with the size and ETag of the blob for reference records
+func StatObject(bucket string, object string) (minio.ObjectInfo, error) {
+	_, info, err := resolveObject(context.Background(), bucket, object)
+	return info, err
+}
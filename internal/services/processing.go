@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/processing"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+const defaultJobsPath = "file-upload-jobs.db"
+
+// Processing    Pipeline of the post-upload processors, nil when disabled
+var Processing *processing.Pipeline
+
+var ErrProcessingDisabled = errors.New("processing is disabled, enable it in the processing section of the config")
+
+// NewProcessing function    Pipeline of the processors of the config, with its jobs in processing.path
+func NewProcessing() (*processing.Pipeline, error) {
+	processors := make([]processing.Processor, 0, len(config.ServerConfigValues.Processing.Processors))
+	for _, processorConfig := range config.ServerConfigValues.Processing.Processors {
+		processor, err := processing.New(processorConfig.Name, processorConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, processor)
+	}
+
+	path := config.ServerConfigValues.Processing.Path
+	if path == "" {
+		path = defaultJobsPath
+	}
+	jobs, err := processing.OpenJobs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := processing.NewPipeline(jobs, objectStorage{}, processors)
+	if workers := config.ServerConfigValues.Processing.Workers; workers > 0 {
+		pipeline.Workers = workers
+	}
+	if maxAttempts := config.ServerConfigValues.Processing.MaxAttempts; maxAttempts > 0 {
+		pipeline.MaxAttempts = maxAttempts
+	}
+	return pipeline, nil
+}
+
+// FileJobs function    Processing jobs of an object, newest first
+func FileJobs(ctx context.Context, bucketName string, objectName string) ([]processing.Job, error) {
+	if Processing == nil {
+		return nil, ErrProcessingDisabled
+	}
+	return Processing.Jobs.List(ctx, bucketName, objectName)
+}
+
+// enqueueProcessing function    Queue the processing jobs of an uploaded object, the upload succeeds even if they can't be queued
+func enqueueProcessing(ctx context.Context, bucketName string, uploadInfo minio.UploadInfo, opts minio.PutObjectOptions) {
+	if Processing == nil {
+		return
+	}
+
+	err := Processing.Enqueue(ctx, processing.Object{
+		Bucket:      bucketName,
+		Name:        uploadInfo.Key,
+		ETag:        uploadInfo.ETag,
+		Size:        uploadInfo.Size,
+		ContentType: opts.ContentType,
+	})
+	if err != nil {
+		log.Printf("processing: jobs of %s not queued: %v", uploadInfo.Key, err)
+	}
+}
+
+// removeDerivedObjects function    Remove the objects written by the processing jobs of a removed object, e.g. its thumbnails
+func removeDerivedObjects(ctx context.Context, bucketName string, objectName string) {
+	if Processing == nil {
+		return
+	}
+
+	jobs, err := Processing.Jobs.List(ctx, bucketName, objectName)
+	if err != nil {
+		log.Println("processing:", err)
+		return
+	}
+	removed := map[string]bool{}
+	for _, job := range jobs {
+		if job.Result == nil {
+			continue
+		}
+		for _, output := range job.Result.Outputs {
+			if removed[output] || output == objectName {
+				continue
+			}
+			removed[output] = true
+			err := RemoveObject(output, bucketName)
+			if err != nil && !IsNotFound(err) {
+				log.Printf("processing: derived object %s of %s not removed: %v", output, objectName, err)
+			}
+		}
+	}
+}
+
+// objectStorage    processing.Storage of the MinIO buckets
+type objectStorage struct{}
+
+func (objectStorage) Open(ctx context.Context, bucketName string, objectName string) (io.ReadCloser, error) {
+	object, _, err := GetObject(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// Put method    Encrypted with the KMS key of the source object, not deduplicated, indexed nor processed: the variants
+// are hidden from the listings and are found from their source
+func (objectStorage) Put(ctx context.Context, source processing.Object, objectName string, content io.Reader, size int64, contentType string) error {
+	info, err := storage.MinioClient.StatObject(ctx, source.Bucket, source.Name, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+	encryption, err := encrypt.NewSSEKMS(objectKeyID(info), ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = storage.MinioClient.PutObject(ctx, source.Bucket, objectName, content, size, minio.PutObjectOptions{
+		ServerSideEncryption: encryption,
+		ContentType:          contentType,
+	})
+	return err
+}
+
+// objectKeyID function    KMS key of an object, the key of the config for the objects encrypted without KMS
+func objectKeyID(info minio.ObjectInfo) string {
+	// MinIO reports the key as an ARN
+	keyID := strings.TrimPrefix(info.Metadata.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"), "arn:aws:kms:")
+	if keyID == "" {
+		return config.ServerConfigValues.Minio.EncryptionKeyID
+	}
+	return keyID
+}
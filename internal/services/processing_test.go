@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/processing"
+)
+
+func TestVariantEncryption(t *testing.T) {
+	s3 := newDedupStorage(t)
+	ctx := context.Background()
+	config.ServerConfigValues.Minio.EncryptionKeyID = "config-key"
+	t.Cleanup(func() {
+		config.ServerConfigValues.Minio.EncryptionKeyID = ""
+	})
+
+	_, err := EncryptAndUploadStream("photo.png", strings.NewReader("image"), -1, testBucket, UploadOptions{ContentType: "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CopyObject(testBucket, "photo.png", CopyOptions{DestinationName: "archived.png", EncryptionKeyID: "archive-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		source string
+		keyID  string
+	}{
+		"upload":              {source: "photo.png", keyID: "config-key"},
+		"copy with a new key": {source: "archived.png", keyID: "archive-key"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			variant := processing.VariantName(test.source, "thumb-32")
+			err := objectStorage{}.Put(ctx, processing.Object{Bucket: testBucket, Name: test.source}, variant, strings.NewReader("thumbnail"), 9, "image/png")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			object := s3.Object(testBucket, variant)
+			if object == nil || string(object.Data) != "thumbnail" {
+				t.Fatalf("%s isn't a plain object with the thumbnail", variant)
+			}
+			if keyID := object.Header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); keyID != test.keyID {
+				t.Errorf("got key %q, want %s", keyID, test.keyID)
+			}
+
+			objects, err := ListObjects(ctx, testBucket, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range objects {
+				if o.Key == variant {
+					t.Errorf("%s listed with the uploads", variant)
+				}
+			}
+		})
+	}
+}
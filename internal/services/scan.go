@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/metrics"
+	"github.com/pavva91/file-upload/internal/scan"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+const defaultScanTimeout = time.Minute
+
+// Scan verdict of the objects, stored as user metadata
+const (
+	MetadataScanStatus    = "Scan-Status"
+	MetadataScanSignature = "Scan-Signature"
+	MetadataScanTime      = "Scan-Time"
+
+	// Origin of the quarantined objects
+	MetadataQuarantineBucket = "Quarantine-Bucket"
+	MetadataQuarantineName   = "Quarantine-Name"
+)
+
+// Scanner    Malware scanner of the uploads, nil when disabled
+var Scanner scan.Scanner
+
+var (
+	ErrInfected        = errors.New("malware found")
+	ErrScanUnavailable = errors.New("malware scanner unavailable")
+)
+
+var scansTotal = metrics.NewCounter("file_upload_scans_total",
+	"Malware scans of the uploads by result: clean, infected or error", "result")
+
+// NewScanner function    clamd scanner of the scan section of the config
+func NewScanner() (scan.Scanner, error) {
+	clamd, err := scan.NewClamd(config.ServerConfigValues.Scan.Address)
+	if err != nil {
+		return nil, err
+	}
+	if timeout := config.ServerConfigValues.Scan.Timeout; timeout > 0 {
+		clamd.Timeout = time.Duration(timeout) * time.Second
+	} else {
+		clamd.Timeout = defaultScanTimeout
+	}
+	return clamd, nil
+}
+
+// scanned function    Whether the uploads to a bucket are scanned
+func scanned(bucketName string) bool {
+	if Scanner == nil {
+		return false
+	}
+	buckets := config.ServerConfigValues.Scan.Buckets
+	return len(buckets) == 0 || slices.Contains(buckets, bucketName)
+}
+
+// scanFile function    Scan the content of an upload before it is stored, the verdict is added to the user metadata of opts.
+// Infected content is moved to the quarantine bucket and ErrInfected returned, a failed scan returns ErrScanUnavailable
+// unless the uploads fail open. The file is rewound for the upload.
+func scanFile(ctx context.Context, bucketName string, objectName string, file *os.File, size int64, opts *minio.PutObjectOptions) error {
+	verdict, err := Scanner.Scan(ctx, file)
+	if err != nil {
+		scansTotal.Inc("error")
+		if !config.ServerConfigValues.Scan.FailOpen {
+			return fmt.Errorf("%w: %v", ErrScanUnavailable, err)
+		}
+		log.Printf("storing %s of bucket %s unscanned: %v", objectName, bucketName, err)
+		verdict = scan.Verdict{Status: scan.StatusUnscanned}
+	} else {
+		scansTotal.Inc(verdict.Status)
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	metadata := make(map[string]string, len(opts.UserMetadata)+3)
+	for k, v := range opts.UserMetadata {
+		metadata[k] = v
+	}
+	metadata[MetadataScanStatus] = verdict.Status
+	metadata[MetadataScanTime] = time.Now().UTC().Format(time.RFC3339)
+	if verdict.Signature != "" {
+		metadata[MetadataScanSignature] = verdict.Signature
+	}
+	opts.UserMetadata = metadata
+
+	if !verdict.Infected() {
+		return nil
+	}
+
+	log.Printf("%s found in %s of bucket %s", verdict.Signature, objectName, bucketName)
+	err = quarantine(ctx, bucketName, objectName, file, size, *opts)
+	if err != nil {
+		log.Println(err)
+	}
+	return fmt.Errorf("%w: %s", ErrInfected, verdict.Signature)
+}
+
+// quarantine function    Keep infected content in the quarantine bucket as {bucket}/{object name}, with the same encryption
+func quarantine(ctx context.Context, bucketName string, objectName string, content io.Reader, size int64, opts minio.PutObjectOptions) error {
+	quarantineBucket := config.ServerConfigValues.Scan.QuarantineBucket
+	if quarantineBucket == "" {
+		return nil
+	}
+
+	opts.UserMetadata[MetadataQuarantineBucket] = bucketName
+	opts.UserMetadata[MetadataQuarantineName] = objectName
+	opts.UserTags = nil
+	opts.Progress = nil
+	_, err := storage.MinioClient.PutObject(ctx, quarantineBucket, bucketName+"/"+objectName, content, size, opts)
+	if err != nil {
+		return fmt.Errorf("quarantine of %s of bucket %s: %w", objectName, bucketName, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/scan"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+// fakeScanner    Scanner returning verdict or err, after reading the whole content
+type fakeScanner struct {
+	verdict scan.Verdict
+	err     error
+	scanned string
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, content io.Reader) (scan.Verdict, error) {
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return scan.Verdict{}, err
+	}
+	s.scanned = string(b)
+	return s.verdict, s.err
+}
+
+func TestScanUpload(t *testing.T) {
+	const content = "X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR"
+	infected := scan.Verdict{Status: scan.StatusInfected, Signature: "Eicar-Test-Signature"}
+
+	tests := map[string]struct {
+		verdict    scan.Verdict
+		scanErr    error
+		failOpen   bool
+		quarantine bool
+		buckets    []string
+		err        error
+		status     string
+	}{
+		"clean":                    {verdict: scan.Verdict{Status: scan.StatusClean}, status: scan.StatusClean},
+		"infected":                 {verdict: infected, err: ErrInfected},
+		"infected with quarantine": {verdict: infected, quarantine: true, err: ErrInfected},
+		"scanner down":             {scanErr: errors.New("connection refused"), err: ErrScanUnavailable},
+		"scanner down fail open":   {scanErr: errors.New("connection refused"), failOpen: true, status: scan.StatusUnscanned},
+		"bucket not scanned":       {verdict: infected, buckets: []string{"other"}},
+		"bucket scanned":           {verdict: scan.Verdict{Status: scan.StatusClean}, buckets: []string{"other", testBucket}, status: scan.StatusClean},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			s3 := newDedupStorage(t)
+			config.ServerConfigValues.Minio.EnableDedup = false
+			err := storage.MinioClient.MakeBucket(context.Background(), "quarantine", minio.MakeBucketOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scanner := &fakeScanner{verdict: test.verdict, err: test.scanErr}
+			Scanner = scanner
+			scanConfig := &config.ServerConfigValues.Scan
+			previous := *scanConfig
+			scanConfig.FailOpen = test.failOpen
+			scanConfig.Buckets = test.buckets
+			if test.quarantine {
+				scanConfig.QuarantineBucket = "quarantine"
+			}
+			t.Cleanup(func() {
+				Scanner = nil
+				*scanConfig = previous
+			})
+
+			_, err = EncryptAndUploadStream("eicar.txt", strings.NewReader(content), int64(len(content)), testBucket, UploadOptions{
+				UserMetadata: map[string]string{"Owner": "ops"},
+				Tags:         map[string]string{"stage": "raw"},
+			})
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+
+			scannedBucket := test.buckets == nil || slices.Contains(test.buckets, testBucket)
+			if scannedBucket && scanner.scanned != content {
+				t.Errorf("got %q scanned, want the whole content", scanner.scanned)
+			}
+			if !scannedBucket && scanner.scanned != "" {
+				t.Errorf("got %q scanned, want the bucket skipped", scanner.scanned)
+			}
+
+			object := s3.Object(testBucket, "eicar.txt")
+			if test.err != nil {
+				if object != nil {
+					t.Errorf("got the refused upload stored")
+				}
+			} else {
+				if object == nil || string(object.Data) != content {
+					t.Fatalf("got %v, want the upload stored", object)
+				}
+				if status := object.Header.Get("X-Amz-Meta-" + MetadataScanStatus); status != test.status {
+					t.Errorf("got scan status %q, want %q", status, test.status)
+				}
+				if owner := object.Header.Get("X-Amz-Meta-Owner"); owner != "ops" {
+					t.Errorf("got owner %q, want the metadata of the upload kept", owner)
+				}
+			}
+
+			quarantined := s3.Object("quarantine", testBucket+"/eicar.txt")
+			if !test.quarantine {
+				if quarantined != nil {
+					t.Errorf("got the upload quarantined without a quarantine bucket")
+				}
+				return
+			}
+			if quarantined == nil || string(quarantined.Data) != content {
+				t.Fatalf("got %v, want the whole upload quarantined", quarantined)
+			}
+			header := quarantined.Header
+			if header.Get("X-Amz-Meta-"+MetadataQuarantineBucket) != testBucket || header.Get("X-Amz-Meta-"+MetadataQuarantineName) != "eicar.txt" ||
+				header.Get("X-Amz-Meta-"+MetadataScanSignature) != infected.Signature || header.Get("X-Amz-Meta-"+MetadataScanStatus) != scan.StatusInfected {
+				t.Errorf("got %v, want the origin and verdict in the metadata", header)
+			}
+			if len(quarantined.Tags) != 0 {
+				t.Errorf("got tags %v, want none on the quarantined object", quarantined.Tags)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/index"
+	"github.com/pavva91/file-upload/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultShareExpires    Validity of the share links created without expiry
+const DefaultShareExpires = 24 * time.Hour
+
+const defaultShareMaxExpires = 7 * 24 * time.Hour
+
+// Results of the accesses to a share link, kept in the access log
+const (
+	ShareDownloaded = "downloaded"
+	ShareResumed    = "resumed"
+	ShareChecked    = "checked"
+	ShareRevoked    = "revoked"
+	ShareExpired    = "expired"
+	ShareExhausted  = "exhausted"
+	ShareBadPass    = "wrong-password"
+)
+
+// ShareAccessKind    Kind of request to a share link, only the downloads from the start of the object are counted
+type ShareAccessKind int
+
+const (
+	// ShareDownload    GET of the whole object or of a first range
+	ShareDownload ShareAccessKind = iota
+	// ShareResume    GET of a range past the start, resuming a download already counted
+	ShareResume
+	// ShareHead    HEAD, no content is sent
+	ShareHead
+)
+
+var (
+	ErrShareNotFound = index.ErrShareNotFound
+	ErrShareExpires  = errors.New("Insert valid expiry")
+	// ErrShareGone    The share link is revoked, expired or out of downloads
+	ErrShareGone     = errors.New("share link no longer available")
+	ErrSharePassword = errors.New("share link password required")
+)
+
+// ShareOptions    Restrictions of a share link, zero values don't restrict
+type ShareOptions struct {
+	// Expires defaults to DefaultShareExpires, up to shares.max-expires of the config
+	Expires      time.Duration
+	Password     string
+	MaxDownloads int
+	CreatedBy    string
+}
+
+// CreateShare function    Create a share link of an existing object, the token is returned once and only its hash is kept
+func CreateShare(ctx context.Context, bucketName string, objectName string, opts ShareOptions) (index.Share, string, error) {
+	if storage.MetadataIndex == nil {
+		return index.Share{}, "", ErrIndexDisabled
+	}
+
+	expires := opts.Expires
+	if expires == 0 {
+		expires = DefaultShareExpires
+	}
+	maxExpires := time.Duration(config.ServerConfigValues.Shares.MaxExpires) * time.Hour
+	if maxExpires <= 0 {
+		maxExpires = defaultShareMaxExpires
+	}
+	if expires < 0 || expires > maxExpires {
+		return index.Share{}, "", fmt.Errorf("%w in seconds (max %d)", ErrShareExpires, int(maxExpires.Seconds()))
+	}
+
+	_, err := StatObject(bucketName, objectName)
+	if err != nil {
+		return index.Share{}, "", err
+	}
+
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return index.Share{}, "", err
+	}
+	token, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return index.Share{}, "", err
+	}
+
+	now := time.Now().UTC()
+	share := index.Share{
+		ID:           id,
+		TokenHash:    hashShareToken(token),
+		Bucket:       bucketName,
+		Name:         objectName,
+		CreatedBy:    opts.CreatedBy,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(expires),
+		MaxDownloads: opts.MaxDownloads,
+	}
+	if opts.Password != "" {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return index.Share{}, "", err
+		}
+		share.PasswordHash = string(passwordHash)
+	}
+
+	err = storage.MetadataIndex.PutShare(ctx, share)
+	if err != nil {
+		return index.Share{}, "", err
+	}
+	log.Printf("Share %s of %s of bucket %s created by %s, expires at %s", id, objectName, bucketName, opts.CreatedBy, share.ExpiresAt.Format(time.RFC3339))
+	return share, token, nil
+}
+
+// ListShares function    Share links of an object, the revoked and expired ones included
+func ListShares(ctx context.Context, bucketName string, objectName string) ([]index.Share, error) {
+	if storage.MetadataIndex == nil {
+		return nil, ErrIndexDisabled
+	}
+	return storage.MetadataIndex.Shares(ctx, bucketName, objectName)
+}
+
+// GetShare function    Share link id of an object, ErrShareNotFound when it belongs to another object
+func GetShare(ctx context.Context, bucketName string, objectName string, id string) (index.Share, error) {
+	if storage.MetadataIndex == nil {
+		return index.Share{}, ErrIndexDisabled
+	}
+	share, err := storage.MetadataIndex.Share(ctx, id)
+	if err != nil {
+		return index.Share{}, err
+	}
+	if share.Bucket != bucketName || share.Name != objectName {
+		return index.Share{}, ErrShareNotFound
+	}
+	return share, nil
+}
+
+// RevokeShare function    Revoke the share link id of an object, the link answers 410 Gone from now on
+func RevokeShare(ctx context.Context, bucketName string, objectName string, id string) error {
+	_, err := GetShare(ctx, bucketName, objectName, id)
+	if err != nil {
+		return err
+	}
+	err = storage.MetadataIndex.RevokeShare(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+	log.Printf("Share %s of %s of bucket %s revoked", id, objectName, bucketName)
+	return nil
+}
+
+// ShareAccesses function    Access log of the share link id of an object
+func ShareAccesses(ctx context.Context, bucketName string, objectName string, id string) ([]index.ShareAccess, error) {
+	_, err := GetShare(ctx, bucketName, objectName, id)
+	if err != nil {
+		return nil, err
+	}
+	return storage.MetadataIndex.ShareAccesses(ctx, id)
+}
+
+// OpenShare function    Share link of a token allowed to download now, a ShareDownload is counted. Every access to an
+// existing link is logged with its result: ErrShareGone for revoked, expired and exhausted links, ErrSharePassword for
+// a missing or wrong password.
+func OpenShare(ctx context.Context, token string, password string, kind ShareAccessKind, remoteAddr string, userAgent string) (index.Share, error) {
+	if storage.MetadataIndex == nil {
+		return index.Share{}, ErrIndexDisabled
+	}
+	share, err := storage.MetadataIndex.ShareByToken(ctx, hashShareToken(token))
+	if err != nil {
+		return index.Share{}, err
+	}
+
+	access := index.ShareAccess{ShareID: share.ID, At: time.Now(), RemoteAddr: remoteAddr, UserAgent: userAgent}
+	access.Result, err = openShare(ctx, share, password, kind, access.At)
+	if err != nil && access.Result == "" {
+		return index.Share{}, err
+	}
+
+	log.Printf("Share %s of %s of bucket %s accessed from %s: %s", share.ID, share.Name, share.Bucket, remoteAddr, access.Result)
+	logErr := storage.MetadataIndex.LogShareAccess(ctx, access)
+	if logErr != nil {
+		log.Println("share access log:", logErr)
+	}
+	if err != nil {
+		return index.Share{}, err
+	}
+	return share, nil
+}
+
+// openShare function    Result of an access to a share link at a time, with the error refusing it. A resume of a link
+// limiting the downloads is counted as a download while no download was counted yet.
+func openShare(ctx context.Context, share index.Share, password string, kind ShareAccessKind, at time.Time) (string, error) {
+	switch {
+	case !share.RevokedAt.IsZero():
+		return ShareRevoked, ErrShareGone
+	case !at.Before(share.ExpiresAt):
+		return ShareExpired, ErrShareGone
+	}
+	if share.PasswordHash != "" && bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)) != nil {
+		return ShareBadPass, ErrSharePassword
+	}
+
+	exhausted := share.MaxDownloads > 0 && share.Downloads >= share.MaxDownloads
+	switch {
+	case kind == ShareHead && exhausted:
+		return ShareExhausted, ErrShareGone
+	case kind == ShareHead:
+		return ShareChecked, nil
+	case kind == ShareResume && (share.MaxDownloads == 0 || share.Downloads > 0):
+		return ShareResumed, nil
+	}
+
+	counted, err := storage.MetadataIndex.CountShareDownload(ctx, share.ID)
+	if err != nil {
+		return "", err
+	}
+	if !counted {
+		return ShareExhausted, ErrShareGone
+	}
+	return ShareDownloaded, nil
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int, encode func([]byte) string) (string, error) {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
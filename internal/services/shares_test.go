@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavva91/file-upload/internal/index"
+	"github.com/pavva91/file-upload/internal/storage"
+)
+
+func TestOpenShare(t *testing.T) {
+	newDedupStorage(t)
+	ctx := context.Background()
+
+	metadataIndex, err := index.Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MetadataIndex = metadataIndex
+	t.Cleanup(func() {
+		storage.MetadataIndex = nil
+		metadataIndex.Close()
+	})
+
+	_, err = EncryptAndUploadStream("report.txt", strings.NewReader("report"), -1, testBucket, UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opts     ShareOptions
+		revoke   bool
+		password string
+		// downloaded are the downloads before the opens of kind
+		downloaded int
+		kind       ShareAccessKind
+		opens      int
+		err        error
+		result     string
+		downloads  int
+	}{
+		"unrestricted":              {opens: 3, result: ShareDownloaded, downloads: 3},
+		"password":                  {opts: ShareOptions{Password: "secret"}, password: "secret", opens: 1, result: ShareDownloaded, downloads: 1},
+		"wrong password":            {opts: ShareOptions{Password: "secret"}, password: "guess", opens: 1, err: ErrSharePassword, result: ShareBadPass},
+		"within the downloads":      {opts: ShareOptions{MaxDownloads: 2}, opens: 2, result: ShareDownloaded, downloads: 2},
+		"out of downloads":          {opts: ShareOptions{MaxDownloads: 2}, opens: 3, err: ErrShareGone, result: ShareExhausted, downloads: 2},
+		"revoked":                   {revoke: true, opens: 1, err: ErrShareGone, result: ShareRevoked},
+		"expired":                   {opts: ShareOptions{Expires: time.Nanosecond}, opens: 1, err: ErrShareGone, result: ShareExpired},
+		"head":                      {opts: ShareOptions{MaxDownloads: 1}, kind: ShareHead, opens: 3, result: ShareChecked},
+		"head out of downloads":     {opts: ShareOptions{MaxDownloads: 1}, downloaded: 1, kind: ShareHead, opens: 1, err: ErrShareGone, result: ShareExhausted, downloads: 1},
+		"head with wrong password":  {opts: ShareOptions{Password: "secret"}, password: "guess", kind: ShareHead, opens: 1, err: ErrSharePassword, result: ShareBadPass},
+		"resume":                    {opts: ShareOptions{MaxDownloads: 1}, downloaded: 1, kind: ShareResume, opens: 2, result: ShareResumed, downloads: 1},
+		"resume without a download": {opts: ShareOptions{MaxDownloads: 2}, kind: ShareResume, opens: 1, result: ShareDownloaded, downloads: 1},
+		"resume unrestricted":       {kind: ShareResume, opens: 2, result: ShareResumed},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			share, token, err := CreateShare(ctx, testBucket, "report.txt", test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.revoke {
+				err = RevokeShare(ctx, testBucket, "report.txt", share.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for i := 0; i < test.downloaded; i++ {
+				_, err = OpenShare(ctx, token, test.password, ShareDownload, "192.0.2.1", "test")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < test.opens; i++ {
+				_, err = OpenShare(ctx, token, test.password, test.kind, "192.0.2.1", "test")
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("got %v, want %v", err, test.err)
+			}
+
+			accesses, err := ShareAccesses(ctx, testBucket, "report.txt", share.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(accesses) != test.downloaded+test.opens {
+				t.Fatalf("got %d accesses, want %d", len(accesses), test.downloaded+test.opens)
+			}
+			results := map[string]bool{}
+			for _, access := range accesses {
+				results[access.Result] = true
+			}
+			if !results[test.result] {
+				t.Errorf("got accesses %+v, want one %s", accesses, test.result)
+			}
+
+			shares, err := ListShares(ctx, testBucket, "report.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range shares {
+				if s.ID == share.ID && s.Downloads != test.downloads {
+					t.Errorf("got %d downloads, want %d", s.Downloads, test.downloads)
+				}
+			}
+		})
+	}
+
+	_, err = OpenShare(ctx, "unknown", "", ShareDownload, "192.0.2.1", "test")
+	if !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("got %v for an unknown token, want %v", err, ErrShareNotFound)
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/admission"
+	"github.com/pavva91/file-upload/internal/metrics"
+)
+
+const (
+	defaultQueueTimeout = 30 * time.Second
+	// Default NumThreads of minio-go
+	defaultNumThreads = 4
+	// Part size of minio-go when the file-chunk-size is not set
+	defaultPartSize = 16 * 1024 * 1024
+)
+
+// Admission    Budget of the uploads to MinIO in flight, nil when the uploads section of the config sets no limit
+var Admission *admission.Controller
+
+var (
+	uploadsInFlight = metrics.NewGauge("file_upload_uploads_in_flight",
+		"Uploads to MinIO in flight")
+	uploadsMemory = metrics.NewGauge("file_upload_uploads_memory_bytes",
+		"Part buffers of the uploads in flight, estimated as NumThreads parts of PartSize")
+	uploadsWaiting = metrics.NewGauge("file_upload_uploads_waiting",
+		"Uploads waiting for admission")
+	uploadsRefused = metrics.NewCounter("file_upload_uploads_refused_total",
+		"Uploads refused with 503 Service Unavailable by reason: queue_full or timeout", "reason")
+)
+
+// NewAdmission function    Admission controller of the uploads section of the config, nil without limits
+func NewAdmission() *admission.Controller {
+	uploads := config.ServerConfigValues.Uploads
+	if uploads.MaxConcurrent <= 0 && uploads.MaxMemory <= 0 {
+		return nil
+	}
+	return &admission.Controller{
+		MaxConcurrent: max(0, uploads.MaxConcurrent),
+		MaxMemory:     int64(max(0, uploads.MaxMemory)) * 1024 * 1024,
+		QueueSize:     max(0, uploads.QueueSize),
+	}
+}
+
+// admitUpload function    Set the NumThreads of an upload of size bytes (-1 when unknown) and wait in the queue until it fits
+// in the budget. release must be called once the upload is done.
+func admitUpload(size int64, opts *minio.PutObjectOptions) (release func(), err error) {
+	opts.NumThreads = uploadThreads(size, partSize(*opts))
+	if Admission == nil {
+		return func() {}, nil
+	}
+
+	timeout := defaultQueueTimeout
+	if seconds := config.ServerConfigValues.Uploads.QueueTimeout; seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	memory := uploadMemory(size, *opts)
+	uploadsWaiting.Inc()
+	err = Admission.Acquire(ctx, memory)
+	uploadsWaiting.Dec()
+	if errors.Is(err, admission.ErrQueueFull) {
+		uploadsRefused.Inc("queue_full")
+		return nil, err
+	}
+	if err != nil {
+		uploadsRefused.Inc("timeout")
+		return nil, err
+	}
+	updateUploadGauges()
+
+	return func() {
+		Admission.Release(memory)
+		updateUploadGauges()
+	}, nil
+}
+
+func updateUploadGauges() {
+	inFlight, memory, _ := Admission.Stats()
+	uploadsInFlight.Set(float64(inFlight))
+	uploadsMemory.Set(float64(memory))
+}
+
+// uploadThreads function    NumThreads of an upload: no more threads than parts, and no more part buffers than the memory budget
+func uploadThreads(size int64, partSize int64) uint {
+	threads := int64(config.ServerConfigValues.Uploads.NumThreads)
+	if threads <= 0 {
+		threads = defaultNumThreads
+	}
+	if size >= 0 {
+		threads = min(threads, max(1, (size+partSize-1)/partSize))
+	}
+	if Admission != nil && Admission.MaxMemory > 0 {
+		threads = min(threads, max(1, Admission.MaxMemory/partSize))
+	}
+	return uint(threads)
+}
+
+// uploadMemory function    Memory minio-go can take to upload size bytes (-1 when unknown): NumThreads part buffers,
+// or the size when it fits in a single PUT
+func uploadMemory(size int64, opts minio.PutObjectOptions) int64 {
+	partSize := partSize(opts)
+	if size >= 0 && (size < partSize || opts.DisableMultipart) {
+		return min(size, partSize)
+	}
+	return partSize * int64(max(1, opts.NumThreads))
+}
+
+func partSize(opts minio.PutObjectOptions) int64 {
+	if opts.PartSize == 0 {
+		return defaultPartSize
+	}
+	return int64(opts.PartSize)
+}
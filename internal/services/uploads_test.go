@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/internal/admission"
+)
+
+func TestUploadThreads(t *testing.T) {
+	const MiB = 1024 * 1024
+
+	tests := map[string]struct {
+		admission *admission.Controller
+		size      int64
+		threads   uint
+		memory    int64
+	}{
+		"unknown size":          {size: -1, threads: 4, memory: 4 * 16 * MiB},
+		"single part":           {size: MiB, threads: 1, memory: MiB},
+		"two parts":             {size: 20 * MiB, threads: 2, memory: 2 * 16 * MiB},
+		"many parts":            {size: 1000 * MiB, threads: 4, memory: 4 * 16 * MiB},
+		"within the budget":     {admission: &admission.Controller{MaxMemory: 40 * MiB}, size: 1000 * MiB, threads: 2, memory: 2 * 16 * MiB},
+		"part above the budget": {admission: &admission.Controller{MaxMemory: 10 * MiB}, size: 1000 * MiB, threads: 1, memory: 16 * MiB},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			Admission = test.admission
+			t.Cleanup(func() { Admission = nil })
+
+			opts := minio.PutObjectOptions{}
+			release, err := admitUpload(test.size, &opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer release()
+
+			if opts.NumThreads != test.threads {
+				t.Errorf("got %d threads, want %d", opts.NumThreads, test.threads)
+			}
+			if memory := uploadMemory(test.size, opts); memory != test.memory {
+				t.Errorf("got %d bytes, want %d", memory, test.memory)
+			}
+		})
+	}
+}
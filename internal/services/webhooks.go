@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/dto"
+	"github.com/pavva91/file-upload/internal/notifications"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+const defaultWebhooksQueueDir = "file-upload-webhooks"
+
+// Webhooks    Delivery queue of the webhook events, nil when no webhook is configured
+var Webhooks *webhooks.Dispatcher
+
+var ErrWebhooksDisabled = errors.New("webhooks are disabled, configure endpoints in the webhooks section of the config")
+
+// NewWebhooks function    Dispatcher of the webhook endpoints of the config, with its queue in webhooks.queue-dir
+func NewWebhooks() (*webhooks.Dispatcher, error) {
+	queueDir := config.ServerConfigValues.Webhooks.QueueDir
+	if queueDir == "" {
+		queueDir = defaultWebhooksQueueDir
+	}
+
+	// Queued deliveries refer to their endpoint by name
+	names := map[string]bool{}
+	subscriptions := make([]webhooks.Subscription, 0, len(config.ServerConfigValues.Webhooks.Endpoints))
+	for _, endpoint := range config.ServerConfigValues.Webhooks.Endpoints {
+		if endpoint.Name == "" || endpoint.URL == "" {
+			return nil, errors.New("webhook endpoints need a name and a url")
+		}
+		if names[endpoint.Name] {
+			return nil, fmt.Errorf("webhook endpoint %s is configured twice", endpoint.Name)
+		}
+		names[endpoint.Name] = true
+		subscriptions = append(subscriptions, webhooks.Subscription{
+			Name:   endpoint.Name,
+			URL:    endpoint.URL,
+			Events: endpoint.Events,
+			Bucket: endpoint.Bucket,
+			Prefix: endpoint.Prefix,
+			Secret: endpoint.Secret,
+		})
+	}
+
+	dispatcher, err := webhooks.NewDispatcher(queueDir, subscriptions)
+	if err != nil {
+		return nil, err
+	}
+	if maxAttempts := config.ServerConfigValues.Webhooks.MaxAttempts; maxAttempts > 0 {
+		dispatcher.MaxAttempts = maxAttempts
+	}
+	return dispatcher, nil
+}
+
+// PublishEvent function    Queue an event of the API for the webhooks, failures are logged since the operation already succeeded
+func PublishEvent(event webhooks.Event) {
+	if Webhooks == nil {
+		return
+	}
+	err := Webhooks.Publish(event)
+	if err != nil {
+		log.Printf("webhooks: %s of %s not queued: %v", event.Type, event.Name, err)
+	}
+}
+
+// WebhookEvent function    Consumer of the bucket notifications publishing the changes made directly in MinIO, the changes
+// made by the service itself (its access key) are published by the API already
+func WebhookEvent(ctx context.Context, event notifications.Event) {
+	if Webhooks == nil || dto.ServiceObject(event.Key) {
+		return
+	}
+	if event.Principal != "" && event.Principal == config.ServerConfigValues.Minio.AccessKeyID {
+		return
+	}
+
+	webhookEvent := webhooks.Event{
+		Bucket:      event.Bucket,
+		Name:        event.Key,
+		Size:        event.Size,
+		ETag:        event.ETag,
+		ContentType: event.ContentType,
+		Principal:   event.Principal,
+		Time:        event.Time,
+	}
+	switch {
+	case event.IsCreated():
+		webhookEvent.Type = webhooks.EventFileUploaded
+	case event.IsRemoved():
+		webhookEvent.Type = webhooks.EventFileDeleted
+	default:
+		return
+	}
+	PublishEvent(webhookEvent)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/notifications"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+func TestWebhookEvent(t *testing.T) {
+	config.ServerConfigValues.Minio.AccessKeyID = "service"
+	t.Cleanup(func() {
+		config.ServerConfigValues.Minio.AccessKeyID = ""
+		Webhooks = nil
+	})
+
+	tests := map[string]struct {
+		event notifications.Event
+		want  string
+	}{
+		"created":    {event: notifications.Event{Name: "s3:ObjectCreated:Put", Bucket: testBucket, Key: "a.txt", Principal: "console"}, want: webhooks.EventFileUploaded},
+		"removed":    {event: notifications.Event{Name: "s3:ObjectRemoved:Delete", Bucket: testBucket, Key: "a.txt"}, want: webhooks.EventFileDeleted},
+		"by service": {event: notifications.Event{Name: "s3:ObjectCreated:Put", Bucket: testBucket, Key: "a.txt", Principal: "service"}},
+		"dedup blob": {event: notifications.Event{Name: "s3:ObjectCreated:Put", Bucket: testBucket, Key: DedupPrefix() + "blobs/ab"}},
+		"accessed":   {event: notifications.Event{Name: "s3:ObjectAccessed:Get", Bucket: testBucket, Key: "a.txt"}},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			dispatcher, err := webhooks.NewDispatcher(t.TempDir(), []webhooks.Subscription{{Name: "hook", URL: "http://localhost"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			Webhooks = dispatcher
+
+			WebhookEvent(context.Background(), test.event)
+
+			deliveries, err := dispatcher.List(webhooks.StatusPending)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want == "" {
+				if len(deliveries) != 0 {
+					t.Fatalf("got %d deliveries, want none", len(deliveries))
+				}
+				return
+			}
+			if len(deliveries) != 1 {
+				t.Fatalf("got %d deliveries, want 1", len(deliveries))
+			}
+			if got := deliveries[0].Event; got.Type != test.want || got.Name != test.event.Key {
+				t.Errorf("got %s of %s, want %s of %s", got.Type, got.Name, test.want, test.event.Key)
+			}
+		})
+	}
+}
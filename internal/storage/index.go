@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"log"
+
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/index"
+)
+
+// MetadataIndex    Local index of the objects, nil when disabled
+var MetadataIndex *index.Index
+
+const defaultIndexPath = "file-upload-index.db"
+
+func OpenMetadataIndex() *index.Index {
+	path := config.ServerConfigValues.Index.Path
+	if path == "" {
+		path = defaultIndexPath
+	}
+
+	metadataIndex, err := index.Open(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return metadataIndex
+}
@@ -1,12 +1,10 @@
 package storage
 
 import (
-	"context"
 	"log"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
-	"github.com/minio/minio-go/v7/pkg/sse"
 	"github.com/pavva91/file-upload/config"
 )
 
@@ -21,7 +19,6 @@ func CreateMinioClient() *minio.Client {
 	secretAccessKey := config.ServerConfigValues.Minio.SecretAccessKey
 
 	useSSL := false
-	encryptedBucket := "testbucket"
 
 	// Initialize minio client object.
 	minioClient, err := minio.New(endpoint, &minio.Options{
@@ -32,11 +29,5 @@ func CreateMinioClient() *minio.Client {
 		log.Fatalln(err)
 	}
 
-	// Set default encryption configuration on a bucket
-	err = minioClient.SetBucketEncryption(context.Background(), encryptedBucket, sse.NewConfigurationSSES3())
-	if err != nil {
-		log.Fatalln(err)
-	}
-
 	return minioClient
 }
@@ -0,0 +1,117 @@
+// Package fakeclamd is a clamd speaking the INSTREAM command over TCP, enough to test
+// the scanning of the uploads without a running ClamAV. Content containing the EICAR test
+// string is reported as infected.
+package fakeclamd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// EICAR    Standard antivirus test file, harmless
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Signature    Reported for the content containing EICAR
+const Signature = "Eicar-Test-Signature"
+
+type Server struct {
+	// Addr is the tcp://host:port address of the server
+	Addr string
+	// MaxLength refuses longer streams like StreamMaxLength of clamd, 0 for no limit
+	MaxLength int
+
+	listener net.Listener
+	mu       sync.Mutex
+	scans    int
+	wg       sync.WaitGroup
+}
+
+func New() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{Addr: "tcp://" + listener.Addr().String(), listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Scans method    Number of streams scanned
+func (s *Server) Scans() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scans
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+	switch strings.TrimSuffix(command, "\x00") {
+	case "zPING":
+		io.WriteString(conn, "PONG\x00")
+		return
+	case "zINSTREAM":
+	default:
+		io.WriteString(conn, "UNKNOWN COMMAND\x00")
+		return
+	}
+
+	var content bytes.Buffer
+	for {
+		var size uint32
+		err := binary.Read(r, binary.BigEndian, &size)
+		if err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if s.MaxLength > 0 && content.Len()+int(size) > s.MaxLength {
+			io.WriteString(conn, "INSTREAM size limit exceeded. ERROR\x00")
+			return
+		}
+		_, err = io.CopyN(&content, r, int64(size))
+		if err != nil {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.scans++
+	s.mu.Unlock()
+	if bytes.Contains(content.Bytes(), []byte(EICAR)) {
+		io.WriteString(conn, "stream: "+Signature+" FOUND\x00")
+		return
+	}
+	io.WriteString(conn, "stream: OK\x00")
+}
@@ -0,0 +1,598 @@
+// Package fakes3 is an in-memory subset of the S3 API, enough for minio-go
+// to be exercised by tests without a running MinIO server.
+package fakes3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	AccessKeyID     = "fakeaccesskey"
+	SecretAccessKey = "fakesecretkey"
+	Region          = "us-east-1"
+)
+
+type Object struct {
+	Data         []byte
+	ETag         string
+	LastModified time.Time
+	Header       http.Header
+	Tags         map[string]string
+}
+
+type Bucket struct {
+	Objects     map[string]*Object
+	Subresource map[string][]byte
+}
+
+type upload struct {
+	bucket string
+	key    string
+	header http.Header
+	parts  map[int][]byte
+}
+
+type Server struct {
+	*httptest.Server
+
+	// BeforeRequest is called with every request before it is served, e.g. to delay it
+	BeforeRequest func(r *http.Request)
+	// DenyRequest refuses the requests it returns true for with 403 AccessDenied, e.g. to fail a single step
+	DenyRequest func(r *http.Request) bool
+
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+	uploads map[string]*upload
+	nextID  int
+}
+
+func New() *Server {
+	s := &Server{
+		buckets: make(map[string]*Bucket),
+		uploads: make(map[string]*upload),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client method    Return a minio client talking to the fake server
+func (s *Server) Client() (*minio.Client, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	return minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(AccessKeyID, SecretAccessKey, ""),
+		Secure: false,
+		Region: Region,
+	})
+}
+
+// Object method    Return a copy of a stored object, nil if not present
+func (s *Server) Object(bucket string, key string) *Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[bucket]
+	if !ok {
+		return nil
+	}
+	o, ok := b.Objects[key]
+	if !ok {
+		return nil
+	}
+	c := *o
+	return &c
+}
+
+// Subresource method    Return the last body stored with a bucket subresource PUT (e.g. "lifecycle")
+func (s *Server) Subresource(bucket string, name string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[bucket]
+	if !ok {
+		return nil
+	}
+	return b.Subresource[name]
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.BeforeRequest != nil {
+		s.BeforeRequest(r)
+	}
+	if s.DenyRequest != nil && s.DenyRequest(r) {
+		writeError(w, r, http.StatusForbidden, "AccessDenied", "Access Denied.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+
+	if bucket == "" {
+		s.listBuckets(w)
+		return
+	}
+
+	if key == "" {
+		s.serveBucket(w, r, bucket, query)
+		return
+	}
+
+	b, ok := s.buckets[bucket]
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.nextID++
+		id := strconv.Itoa(s.nextID)
+		s.uploads[id] = &upload{bucket: bucket, key: key, header: r.Header.Clone(), parts: make(map[int][]byte)}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: bucket, Key: key, UploadId: id})
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		u, ok := s.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist")
+			return
+		}
+		data, err := readBody(r)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			data, err = s.copySource(source, r.Header.Get("X-Amz-Copy-Source-Range"))
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, "NoSuchKey", err.Error())
+				return
+			}
+		}
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		u.parts[partNumber] = data
+		etag := etagOf(data)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			writeXML(w, struct {
+				XMLName      xml.Name `xml:"CopyPartResult"`
+				ETag         string
+				LastModified string
+			}{ETag: etag, LastModified: time.Now().UTC().Format(time.RFC3339)})
+		}
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		u, ok := s.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist")
+			return
+		}
+		numbers := make([]int, 0, len(u.parts))
+		for n := range u.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, n := range numbers {
+			data = append(data, u.parts[n]...)
+		}
+		delete(s.uploads, query.Get("uploadId"))
+		o := s.putObject(b, key, data, u.header)
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: bucket, Key: key, ETag: o.ETag})
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && query.Has("tagging"):
+		o, ok := b.Objects[key]
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		var tagging taggingXML
+		if err := xml.NewDecoder(r.Body).Decode(&tagging); err != nil {
+			writeError(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		o.Tags = make(map[string]string)
+		for _, tag := range tagging.TagSet.Tags {
+			o.Tags[tag.Key] = tag.Value
+		}
+	case r.Method == http.MethodGet && query.Has("tagging"):
+		o, ok := b.Objects[key]
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		var tagging taggingXML
+		for k, v := range o.Tags {
+			tagging.TagSet.Tags = append(tagging.TagSet.Tags, tagXML{Key: k, Value: v})
+		}
+		writeXML(w, tagging)
+	case r.Method == http.MethodDelete && query.Has("tagging"):
+		if o, ok := b.Objects[key]; ok {
+			o.Tags = nil
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		data, err := s.copySource(r.Header.Get("X-Amz-Copy-Source"), "")
+		if err != nil {
+			writeError(w, r, http.StatusNotFound, "NoSuchKey", err.Error())
+			return
+		}
+		header := r.Header.Clone()
+		if r.Header.Get("X-Amz-Metadata-Directive") != "REPLACE" {
+			sourceBucket, sourceKey := splitCopySource(r.Header.Get("X-Amz-Copy-Source"))
+			header = s.buckets[sourceBucket].Objects[sourceKey].Header.Clone()
+		}
+		o := s.putObject(b, key, data, header)
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: o.ETag, LastModified: o.LastModified.Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		data, err := readBody(r)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		o := s.putObject(b, key, data, r.Header)
+		w.Header().Set("ETag", o.ETag)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		o, ok := b.Objects[key]
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		for k, v := range o.Header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", o.ETag)
+		w.Header().Set("Last-Modified", o.LastModified.Format(http.TimeFormat))
+		if len(o.Tags) > 0 {
+			w.Header().Set("X-Amz-Tagging-Count", strconv.Itoa(len(o.Tags)))
+		}
+		http.ServeContent(w, r, "", o.LastModified, bytes.NewReader(o.Data))
+	case r.Method == http.MethodDelete:
+		delete(b.Objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "A header you provided implies functionality that is not implemented")
+	}
+}
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *http.Request, bucket string, query url.Values) {
+	b, exists := s.buckets[bucket]
+
+	switch {
+	case r.Method == http.MethodPut && len(query) == 0:
+		if exists {
+			writeError(w, r, http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.")
+			return
+		}
+		s.buckets[bucket] = &Bucket{Objects: make(map[string]*Object), Subresource: make(map[string][]byte)}
+		if r.Header.Get("X-Amz-Bucket-Object-Lock-Enabled") == "true" {
+			s.buckets[bucket].Subresource["object-lock"] = []byte("<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>")
+		}
+	case !exists:
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete && len(query) == 0:
+		if len(b.Objects) > 0 {
+			writeError(w, r, http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty")
+			return
+		}
+		delete(s.buckets, bucket)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && query.Has("location"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+			Value   string   `xml:",chardata"`
+		}{Value: Region})
+	case r.Method == http.MethodGet && (query.Get("list-type") == "2" || len(query) == 0 || query.Has("prefix")):
+		s.listObjects(w, bucket, b, query)
+	case r.Method == http.MethodPost && query.Has("delete"):
+		var request struct {
+			Objects []struct {
+				Key string
+			} `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		type deleted struct {
+			Key string
+		}
+		result := struct {
+			XMLName xml.Name  `xml:"DeleteResult"`
+			Deleted []deleted `xml:"Deleted"`
+		}{}
+		for _, o := range request.Objects {
+			delete(b.Objects, o.Key)
+			result.Deleted = append(result.Deleted, deleted{Key: o.Key})
+		}
+		writeXML(w, result)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		for name := range query {
+			b.Subresource[name] = data
+		}
+	case r.Method == http.MethodGet:
+		for name := range query {
+			if data, ok := b.Subresource[name]; ok {
+				w.Header().Set("Content-Type", "application/xml")
+				w.Write(data)
+				return
+			}
+		}
+		writeError(w, r, http.StatusNotFound, "NoSuchConfiguration", "The specified configuration does not exist")
+	case r.Method == http.MethodDelete:
+		for name := range query {
+			delete(b.Subresource, name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "A header you provided implies functionality that is not implemented")
+	}
+}
+
+func (s *Server) listBuckets(w http.ResponseWriter) {
+	type bucketXML struct {
+		Name         string
+		CreationDate string
+	}
+	result := struct {
+		XMLName xml.Name    `xml:"ListAllMyBucketsResult"`
+		Buckets []bucketXML `xml:"Buckets>Bucket"`
+	}{}
+	names := make([]string, 0, len(s.buckets))
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result.Buckets = append(result.Buckets, bucketXML{Name: name, CreationDate: time.Now().UTC().Format(time.RFC3339)})
+	}
+	writeXML(w, result)
+}
+
+func (s *Server) listObjects(w http.ResponseWriter, bucket string, b *Bucket, query url.Values) {
+	type contents struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int64
+		StorageClass string
+		UserMetadata *userMetadataXML `xml:",omitempty"`
+		UserTags     string           `xml:",omitempty"`
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		KeyCount       int
+		MaxKeys        int
+		Delimiter      string
+		IsTruncated    bool
+		Contents       []contents
+		CommonPrefixes []commonPrefix
+	}{Name: bucket, Prefix: query.Get("prefix"), MaxKeys: 1000, Delimiter: query.Get("delimiter")}
+
+	keys := make([]string, 0, len(b.Objects))
+	for key := range b.Objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	seenPrefixes := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, result.Prefix) {
+			continue
+		}
+		if result.Delimiter != "" {
+			rest := strings.TrimPrefix(key, result.Prefix)
+			if i := strings.Index(rest, result.Delimiter); i >= 0 {
+				prefix := result.Prefix + rest[:i+len(result.Delimiter)]
+				if !seenPrefixes[prefix] {
+					seenPrefixes[prefix] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: prefix})
+				}
+				continue
+			}
+		}
+		o := b.Objects[key]
+		c := contents{
+			Key:          key,
+			LastModified: o.LastModified.Format(time.RFC3339Nano),
+			ETag:         o.ETag,
+			Size:         int64(len(o.Data)),
+			StorageClass: "STANDARD",
+		}
+		// MinIO extension, the user metadata keeps the X-Amz-Meta- prefix
+		if query.Get("metadata") == "true" {
+			c.UserMetadata = &userMetadataXML{}
+			for k := range o.Header {
+				if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") || k == "Content-Type" {
+					c.UserMetadata.Items = append(c.UserMetadata.Items, metadataItemXML{XMLName: xml.Name{Local: k}, Value: o.Header.Get(k)})
+				}
+			}
+			tags := url.Values{}
+			for k, v := range o.Tags {
+				tags.Set(k, v)
+			}
+			c.UserTags = tags.Encode()
+		}
+		result.Contents = append(result.Contents, c)
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+	writeXML(w, result)
+}
+
+type userMetadataXML struct {
+	Items []metadataItemXML
+}
+
+type metadataItemXML struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+func (s *Server) putObject(b *Bucket, key string, data []byte, header http.Header) *Object {
+	stored := make(http.Header)
+	for k, v := range header {
+		lower := strings.ToLower(k)
+		if strings.HasPrefix(lower, "x-amz-meta-") || strings.HasPrefix(lower, "x-amz-server-side-encryption") ||
+			strings.HasPrefix(lower, "x-amz-object-lock") || lower == "content-type" || lower == "content-disposition" ||
+			lower == "content-encoding" || lower == "cache-control" {
+			stored[k] = v
+		}
+	}
+	if stored.Get("Content-Type") == "" {
+		stored.Set("Content-Type", "binary/octet-stream")
+	}
+	stored.Del("Content-Encoding")
+
+	o := &Object{
+		Data:         data,
+		ETag:         etagOf(data),
+		LastModified: time.Now().UTC().Truncate(time.Second),
+		Header:       stored,
+	}
+	if tagging := header.Get("X-Amz-Tagging"); tagging != "" {
+		values, _ := url.ParseQuery(tagging)
+		o.Tags = make(map[string]string)
+		for k := range values {
+			o.Tags[k] = values.Get(k)
+		}
+	}
+	b.Objects[key] = o
+	return o
+}
+
+func (s *Server) copySource(source string, byteRange string) ([]byte, error) {
+	bucket, key := splitCopySource(source)
+	b, ok := s.buckets[bucket]
+	if !ok {
+		return nil, fmt.Errorf("bucket %s does not exist", bucket)
+	}
+	o, ok := b.Objects[key]
+	if !ok {
+		return nil, fmt.Errorf("The specified key does not exist.")
+	}
+	data := o.Data
+	if byteRange != "" {
+		var start, end int
+		if _, err := fmt.Sscanf(byteRange, "bytes=%d-%d", &start, &end); err != nil || end >= len(data) || start > end {
+			return nil, fmt.Errorf("invalid copy source range %s", byteRange)
+		}
+		data = data[start : end+1]
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func splitCopySource(source string) (string, string) {
+	source, _ = url.PathUnescape(source)
+	source, _, _ = strings.Cut(source, "?")
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	return bucket, key
+}
+
+type tagXML struct {
+	Key   string
+	Value string
+}
+
+type taggingXML struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  struct {
+		Tags []tagXML `xml:"Tag"`
+	}
+}
+
+// readBody function    Read a request body, decoding the aws-chunked encoding used by streaming signatures
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	xml.NewEncoder(w).Encode(struct {
+		XMLName    xml.Name `xml:"Error"`
+		Code       string
+		Message    string
+		Resource   string
+		RequestId  string
+		BucketName string
+	}{Code: code, Message: message, Resource: r.URL.Path, RequestId: "fake"})
+}
@@ -0,0 +1,536 @@
+// Package webhooks delivers the events of the API to the configured HTTP endpoints.
+// Deliveries are queued on local disk before being sent, retried with exponential backoff
+// and moved to a dead-letter directory once they run out of attempts, from where they can be replayed.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavva91/file-upload/internal/metrics"
+)
+
+// Event types
+const (
+	EventFileUploaded   = "file.uploaded"
+	EventFileDownloaded = "file.downloaded"
+	EventFileDeleted    = "file.deleted"
+	EventFileCopied     = "file.copied"
+	EventFileMoved      = "file.moved"
+	EventFileComposed   = "file.composed"
+)
+
+// Delivery statuses, the directories of the queue
+const (
+	StatusPending = "pending"
+	StatusDead    = "dead"
+)
+
+// Headers of the delivery requests
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is sha256=hex(HMAC-SHA256(secret, timestamp + "." + body)), sent when the webhook has a secret
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	defaultMaxAttempts  = 10
+	defaultMinBackoff   = 10 * time.Second
+	defaultMaxBackoff   = time.Hour
+	defaultTimeout      = 10 * time.Second
+	defaultPollInterval = time.Minute
+	defaultConcurrency  = 4
+)
+
+// ErrNotFound    No dead delivery with the given id
+var ErrNotFound = errors.New("webhook delivery not found")
+
+var deliveriesTotal = metrics.NewCounter("file_upload_webhook_deliveries_total",
+	"Webhook delivery attempts by result: delivered, failed or dead", "webhook", "result")
+
+// Event    JSON payload of a delivery
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Time        time.Time `json:"time"`
+	Bucket      string    `json:"bucket"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+	Principal   string    `json:"principal,omitempty"`
+	// Source of a copied or moved object
+	SourceBucket string `json:"sourceBucket,omitempty"`
+	SourceName   string `json:"sourceName,omitempty"`
+}
+
+// Subscription    Endpoint receiving the events matching its filters, empty filters match everything
+type Subscription struct {
+	Name   string
+	URL    string
+	Events []string
+	Bucket string
+	Prefix string
+	Secret string
+}
+
+// Matches method    The event passes the event type, bucket and prefix filters
+func (s Subscription) Matches(event Event) bool {
+	if s.Bucket != "" && s.Bucket != event.Bucket {
+		return false
+	}
+	if !strings.HasPrefix(event.Name, s.Prefix) {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, eventType := range s.Events {
+		if eventType == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery    Event queued for a webhook, the secret and url are looked up by name when sending
+type Delivery struct {
+	ID          string    `json:"id"`
+	Webhook     string    `json:"webhook"`
+	Event       Event     `json:"event"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Dispatcher    Disk backed delivery queue, a file per delivery in {Dir}/pending and {Dir}/dead
+type Dispatcher struct {
+	Subscriptions []Subscription
+	Dir           string
+	Client        *http.Client
+	// MaxAttempts before a delivery is moved to the dead letters
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	// PollInterval bounds the wait between two scans of the queue
+	PollInterval time.Duration
+	// Concurrency is the number of deliveries sent at the same time
+	Concurrency int
+
+	// mu serializes the moves of the delivery files between the directories
+	mu   sync.Mutex
+	wake chan struct{}
+}
+
+// NewDispatcher function    Dispatcher with the default retry policy, the queue directories are created in dir
+func NewDispatcher(dir string, subscriptions []Subscription) (*Dispatcher, error) {
+	for _, status := range []string{StatusPending, StatusDead} {
+		err := os.MkdirAll(filepath.Join(dir, status), 0o700)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Dispatcher{
+		Subscriptions: subscriptions,
+		Dir:           dir,
+		Client:        &http.Client{Timeout: defaultTimeout},
+		MaxAttempts:   defaultMaxAttempts,
+		MinBackoff:    defaultMinBackoff,
+		MaxBackoff:    defaultMaxBackoff,
+		PollInterval:  defaultPollInterval,
+		Concurrency:   defaultConcurrency,
+		wake:          make(chan struct{}, 1),
+	}, nil
+}
+
+// Publish method    Queue the event for every matching webhook, the deliveries are on disk when it returns
+func (d *Dispatcher) Publish(event Event) error {
+	if event.ID == "" {
+		event.ID = newID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	queued := false
+	for _, s := range d.Subscriptions {
+		if !s.Matches(event) {
+			continue
+		}
+		now := time.Now().UTC()
+		delivery := Delivery{
+			ID:          newID(),
+			Webhook:     s.Name,
+			Event:       event,
+			Status:      StatusPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+		}
+		err := d.write(delivery)
+		if err != nil {
+			return err
+		}
+		queued = true
+	}
+
+	if queued {
+		d.notify()
+	}
+	return nil
+}
+
+// Run method    Send the due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		next := d.deliverDue(ctx)
+
+		wait := d.PollInterval
+		if !next.IsZero() {
+			wait = min(wait, max(time.Until(next), 0))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// List method    Deliveries with the given status, oldest first
+func (d *Dispatcher) List(status string) ([]Delivery, error) {
+	if status != StatusPending && status != StatusDead {
+		return nil, fmt.Errorf("Insert valid status: %s or %s", StatusPending, StatusDead)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.read(status)
+}
+
+// Replay method    Move a dead delivery back to the queue with its attempts reset
+func (d *Dispatcher) Replay(id string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !validID(id) {
+		return Delivery{}, ErrNotFound
+	}
+	delivery, err := readDelivery(d.path(StatusDead, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Delivery{}, ErrNotFound
+	}
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	delivery, err = d.replay(delivery)
+	if err != nil {
+		return Delivery{}, err
+	}
+	d.notify()
+	return delivery, nil
+}
+
+// ReplayAll method    Move every dead delivery back to the queue, return the replayed deliveries
+func (d *Dispatcher) ReplayAll() ([]Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dead, err := d.read(StatusDead)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make([]Delivery, 0, len(dead))
+	for _, delivery := range dead {
+		delivery, err = d.replay(delivery)
+		if err != nil {
+			return replayed, err
+		}
+		replayed = append(replayed, delivery)
+	}
+	if len(replayed) > 0 {
+		d.notify()
+	}
+	return replayed, nil
+}
+
+func (d *Dispatcher) replay(delivery Delivery) (Delivery, error) {
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttempt = time.Now().UTC()
+	err := d.write(delivery)
+	if err != nil {
+		return Delivery{}, err
+	}
+	return delivery, os.Remove(d.path(StatusDead, delivery.ID))
+}
+
+// deliverDue method    Send the pending deliveries that are due, return when the next one is due (zero when none)
+func (d *Dispatcher) deliverDue(ctx context.Context) time.Time {
+	d.mu.Lock()
+	pending, err := d.read(StatusPending)
+	d.mu.Unlock()
+	if err != nil {
+		log.Println("webhooks:", err)
+		return time.Time{}
+	}
+
+	var next time.Time
+	var nextMu sync.Mutex
+	updateNext := func(t time.Time) {
+		nextMu.Lock()
+		defer nextMu.Unlock()
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(d.Concurrency, 1))
+	now := time.Now()
+	for _, delivery := range pending {
+		if delivery.NextAttempt.After(now) {
+			updateNext(delivery.NextAttempt)
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(delivery Delivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			retry := d.attempt(ctx, delivery)
+			if !retry.IsZero() {
+				updateNext(retry)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return next
+}
+
+// attempt method    Send a delivery and update its file, return when it is retried (zero when done)
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) time.Time {
+	err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		return time.Time{}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err == nil {
+		deliveriesTotal.Inc(delivery.Webhook, "delivered")
+		removeErr := os.Remove(d.path(StatusPending, delivery.ID))
+		if removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			log.Println("webhooks:", removeErr)
+		}
+		return time.Time{}
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.MaxAttempts {
+		deliveriesTotal.Inc(delivery.Webhook, "dead")
+		log.Printf("webhooks: delivery %s to %s dead after %d attempts: %v", delivery.ID, delivery.Webhook, delivery.Attempts, err)
+		delivery.Status = StatusDead
+		err = d.write(delivery)
+		if err == nil {
+			err = os.Remove(d.path(StatusPending, delivery.ID))
+		}
+		if err != nil {
+			log.Println("webhooks:", err)
+		}
+		return time.Time{}
+	}
+
+	deliveriesTotal.Inc(delivery.Webhook, "failed")
+	delivery.NextAttempt = time.Now().UTC().Add(d.backoff(delivery.Attempts))
+	err = d.write(delivery)
+	if err != nil {
+		log.Println("webhooks:", err)
+	}
+	return delivery.NextAttempt
+}
+
+// send method    POST the signed event, any status other than 2xx is a failure
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) error {
+	subscription, ok := d.subscription(delivery.Webhook)
+	if !ok {
+		return fmt.Errorf("webhook %s is not configured", delivery.Webhook)
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, delivery.Event.ID)
+	req.Header.Set(HeaderEvent, delivery.Event.Type)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if subscription.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded %s", delivery.Webhook, resp.Status)
+	}
+	return nil
+}
+
+// Sign function    Signature of a delivery, receivers compare it with the X-Webhook-Signature header
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff method    Wait before the next attempt, doubling from MinBackoff up to MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.MinBackoff
+	for i := 1; i < attempts && backoff < d.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.MaxBackoff)
+}
+
+func (d *Dispatcher) subscription(name string) (Subscription, bool) {
+	for _, s := range d.Subscriptions {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Subscription{}, false
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) path(status string, id string) string {
+	return filepath.Join(d.Dir, status, id+".json")
+}
+
+// write method    Store a delivery in the directory of its status, through a rename so that a crash can't leave half a file
+func (d *Dispatcher) write(delivery Delivery) error {
+	js, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Join(d.Dir, delivery.Status), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(js)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), d.path(delivery.Status, delivery.ID))
+}
+
+// read method    Deliveries of a directory sorted by creation, unreadable files are logged and skipped
+func (d *Dispatcher) read(status string) ([]Delivery, error) {
+	entries, err := os.ReadDir(filepath.Join(d.Dir, status))
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []Delivery{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !validID(id) {
+			continue
+		}
+		delivery, err := readDelivery(d.path(status, id))
+		if err != nil {
+			log.Println("webhooks:", err)
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+	return deliveries, nil
+}
+
+func readDelivery(path string) (Delivery, error) {
+	js, err := os.ReadFile(path)
+	if err != nil {
+		return Delivery{}, err
+	}
+	var delivery Delivery
+	err = json.Unmarshal(js, &delivery)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return delivery, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validID function    Ids are hex, so that they can't escape the queue directories
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver    Webhook endpoint failing the first failures requests
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	events   []Event
+	received chan struct{}
+}
+
+func newReceiver(t *testing.T, secret string, failures int) (*receiver, *httptest.Server) {
+	rec := &receiver{failures: failures, received: make(chan struct{}, 100)}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if secret != "" && r.Header.Get(HeaderSignature) != Sign(secret, r.Header.Get(HeaderTimestamp), body) {
+			t.Errorf("got signature %q, want the HMAC of the body", r.Header.Get(HeaderSignature))
+		}
+
+		rec.mu.Lock()
+		defer func() {
+			rec.mu.Unlock()
+			rec.received <- struct{}{}
+		}()
+		if rec.failures > 0 {
+			rec.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event Event
+		err := json.Unmarshal(body, &event)
+		if err != nil || r.Header.Get(HeaderID) != event.ID || r.Header.Get(HeaderEvent) != event.Type {
+			t.Errorf("got %s (%v) with headers %v, want the event and its id and type", body, err, r.Header)
+		}
+		rec.events = append(rec.events, event)
+	}))
+	t.Cleanup(ts.Close)
+	return rec, ts
+}
+
+func (rec *receiver) wait(t *testing.T, requests int) {
+	for i := 0; i < requests; i++ {
+		select {
+		case <-rec.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d requests, want %d", i, requests)
+		}
+	}
+}
+
+func newTestDispatcher(t *testing.T, dir string, subscriptions ...Subscription) *Dispatcher {
+	d, err := NewDispatcher(dir, subscriptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.MaxAttempts = 3
+	d.MinBackoff = time.Millisecond
+	d.MaxBackoff = 5 * time.Millisecond
+	return d
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	event := Event{Type: EventFileUploaded, Bucket: "data", Name: "raw/a.csv"}
+
+	tests := map[string]struct {
+		subscription Subscription
+		want         bool
+	}{
+		"everything":        {subscription: Subscription{}, want: true},
+		"event type":        {subscription: Subscription{Events: []string{EventFileDeleted, EventFileUploaded}}, want: true},
+		"other event type":  {subscription: Subscription{Events: []string{EventFileDeleted}}, want: false},
+		"bucket and prefix": {subscription: Subscription{Bucket: "data", Prefix: "raw/"}, want: true},
+		"other bucket":      {subscription: Subscription{Bucket: "other"}, want: false},
+		"other prefix":      {subscription: Subscription{Prefix: "img/"}, want: false},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if got := test.subscription.Matches(event); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flaky, flakyServer := newReceiver(t, "s3cr3t", 2)
+	down, downServer := newReceiver(t, "", 3)
+	dir := t.TempDir()
+
+	// Queued before the dispatcher runs, as after a restart
+	d := newTestDispatcher(t, dir,
+		Subscription{Name: "flaky", URL: flakyServer.URL, Secret: "s3cr3t"},
+		Subscription{Name: "down", URL: downServer.URL, Events: []string{EventFileDeleted}},
+	)
+	err := d.Publish(Event{Type: EventFileUploaded, Bucket: "data", Name: "a.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Publish(Event{Type: EventFileDeleted, Bucket: "data", Name: "a.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d = newTestDispatcher(t, dir, d.Subscriptions...)
+	go d.Run(ctx)
+
+	// Retried until delivered
+	flaky.wait(t, 4)
+	types := map[string]bool{}
+	for _, event := range flaky.events {
+		types[event.Type] = true
+	}
+	if len(flaky.events) != 2 || !types[EventFileUploaded] || !types[EventFileDeleted] {
+		t.Errorf("got %+v, want the upload and delete events", flaky.events)
+	}
+
+	// Dead after MaxAttempts
+	down.wait(t, 3)
+	var dead []Delivery
+	for start := time.Now(); len(dead) == 0 && time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		dead, err = d.List(StatusDead)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(dead) != 1 || dead[0].Webhook != "down" || dead[0].Attempts != 3 || dead[0].LastError == "" {
+		t.Fatalf("got %+v, want the delivery to down dead after 3 attempts", dead)
+	}
+
+	_, err = d.Replay("0123")
+	if err != ErrNotFound {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	replayed, err := d.Replay(dead[0].ID)
+	if err != nil || replayed.Status != StatusPending || replayed.Attempts != 0 {
+		t.Errorf("got %+v (%v), want the delivery pending again", replayed, err)
+	}
+
+	down.wait(t, 1)
+	if len(down.events) != 1 || down.events[0].ID != dead[0].Event.ID {
+		t.Errorf("got %+v, want the replayed event with its id", down.events)
+	}
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		pending, _ := d.List(StatusPending)
+		dead, _ = d.List(StatusDead)
+		if len(pending) == 0 && len(dead) == 0 {
+			return
+		}
+	}
+	t.Errorf("got deliveries left in the queue, want all delivered")
+}
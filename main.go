@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/pavva91/file-upload/config"
 	"github.com/pavva91/file-upload/internal/handlers"
+	"github.com/pavva91/file-upload/internal/metrics"
+	"github.com/pavva91/file-upload/internal/middleware"
+	"github.com/pavva91/file-upload/internal/notifications"
+	"github.com/pavva91/file-upload/internal/openapi"
+	"github.com/pavva91/file-upload/internal/router"
 	"github.com/pavva91/file-upload/internal/services"
 	"github.com/pavva91/file-upload/internal/storage"
 )
@@ -37,21 +44,118 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	err = services.ApplyLifecycleConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Lifecycle rules remove the expired reference records without releasing their blobs
+	if config.ServerConfigValues.Minio.EnableDedup {
+		sweepInterval := time.Duration(config.ServerConfigValues.Minio.DedupSweepInterval) * time.Minute
+		services.StartDedupSweeper(context.Background(), sweepInterval)
+	}
+
+	if config.ServerConfigValues.Index.Enable {
+		storage.MetadataIndex = storage.OpenMetadataIndex()
+		defer storage.MetadataIndex.Close()
+		reconcileInterval := time.Duration(config.ServerConfigValues.Index.ReconcileInterval) * time.Minute
+		services.StartIndexReconciler(context.Background(), reconcileInterval)
+	}
+
+	if len(config.ServerConfigValues.Webhooks.Endpoints) > 0 {
+		services.Webhooks, err = services.NewWebhooks()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go services.Webhooks.Run(context.Background())
+	}
+
+	// One subscriber for the whole server, consumers are added per subsystem
+	if config.ServerConfigValues.Notifications.Enable {
+		notificationBuckets := config.ServerConfigValues.Notifications.Buckets
+		if len(notificationBuckets) == 0 {
+			notificationBuckets = []string{bucketName}
+		}
+		subscriber := notifications.NewSubscriber(notificationBuckets)
+		if storage.MetadataIndex != nil {
+			subscriber.Subscribe("index", services.IndexEvent)
+		}
+		if services.Webhooks != nil {
+			subscriber.Subscribe("webhooks", services.WebhookEvent)
+		}
+		if subscriber.HasConsumers() {
+			go subscriber.Run(context.Background())
+		} else {
+			log.Println("notifications: no consumer enabled (index or webhooks), not listening to the buckets")
+		}
+	}
+
+	if config.ServerConfigValues.Processing.Enable {
+		services.Processing, err = services.NewProcessing()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer services.Processing.Jobs.Close()
+		go services.Processing.Run(context.Background())
+	}
+
+	services.Admission = services.NewAdmission()
+
+	if config.ServerConfigValues.Scan.Enable {
+		services.Scanner, err = services.NewScanner()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if quarantineBucket := config.ServerConfigValues.Scan.QuarantineBucket; quarantineBucket != "" {
+			err = services.CreateBucket(quarantineBucket)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
 
 	// Create a new request multiplexer
 	// Take incoming requests and dispatch them to the matching handlers
 	mux := http.NewServeMux()
 
 	// Register the routes and handlers
+	// API endpoints are mounted under {api-path}/{api-version} (e.g. /api/v1/files)
+	basePath := router.BasePath(config.ServerConfigValues.Server.ApiPath, config.ServerConfigValues.Server.ApiVersion)
+	// Rate limits apply to the principals, after authentication
+	rateLimiter := middleware.NewRateLimiter()
+	filesHandler := middleware.Auth(rateLimiter.Handler(http.StripPrefix(basePath, &handlers.FilesHandler{})))
+
 	mux.Handle("/", &homeHandler{})
 	mux.Handle("/health", &healthHandler{})
-	mux.Handle("/files", &handlers.FilesHandler{})
-	mux.Handle("/files/", &handlers.FilesHandler{})
+	mux.Handle("/metrics", metrics.Handler())
+	if basePath != "" {
+		mux.Handle(basePath+"/health", &healthHandler{})
+	}
+	mux.Handle("/openapi.json", &openapi.SpecHandler{BasePath: basePath})
+	mux.Handle("/docs", &openapi.SwaggerUIHandler{})
+	mux.Handle("/docs/", &openapi.SwaggerUIHandler{})
+	for _, filesPath := range handlers.FilesPaths {
+		mux.Handle(basePath+filesPath, filesHandler)
+	}
+	webhooksHandler := middleware.Auth(rateLimiter.Handler(http.StripPrefix(basePath, &handlers.WebhooksHandler{})))
+	for _, webhooksPath := range handlers.WebhooksPaths {
+		mux.Handle(basePath+webhooksPath, webhooksHandler)
+	}
+	bucketsHandler := middleware.Auth(rateLimiter.Handler(http.StripPrefix(basePath, &handlers.BucketsHandler{})))
+	for _, bucketsPath := range handlers.BucketsPaths {
+		mux.Handle(basePath+bucketsPath, bucketsHandler)
+	}
+
+	// Share links are authenticated by their token, the rate limits apply by client address
+	sharesHandler := rateLimiter.Handler(http.StripPrefix(basePath, &handlers.SharesHandler{}))
+	for _, sharesPath := range handlers.SharesPaths {
+		mux.Handle(basePath+sharesPath, sharesHandler)
+	}
 
 	// Run the server
 	fmt.Printf("Server is running on port %s", config.ServerConfigValues.Server.Port)
 	// http.ListenAndServe(":8080", mux)
-	http.ListenAndServe(fmt.Sprintf(":%s", config.ServerConfigValues.Server.Port), mux)
+	http.ListenAndServe(fmt.Sprintf(":%s", config.ServerConfigValues.Server.Port), middleware.Cors(mux))
 }
 
 func setConfig(path string) {
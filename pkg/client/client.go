@@ -0,0 +1,806 @@
+// Package client is a typed Go client of the file upload API described in
+// internal/openapi/openapi.json (served by the service at /openapi.json).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavva91/file-upload/internal/dto"
+)
+
+// Payloads are shared with the server
+type (
+	UploadFileResponse  = dto.UploadFileResponse
+	DownloadFileRequest = dto.DownloadFileRequest
+	PresignFileResponse = dto.PresignFileResponse
+	FileInfo            = dto.FileInfo
+	FileMetadata        = dto.FileMetadata
+	FileChecksums       = dto.FileChecksums
+	ObjectTags          = dto.ObjectTags
+	SearchFilesResponse = dto.SearchFilesResponse
+	CopyFileRequest     = dto.CopyFileRequest
+	MoveFileResponse    = dto.MoveFileResponse
+	ComposeFileRequest  = dto.ComposeFileRequest
+	ComposeSource       = dto.ComposeSource
+
+	FileJobsResponse = dto.FileJobsResponse
+	ProcessingJob    = dto.ProcessingJob
+
+	WebhookDelivery           = dto.WebhookDelivery
+	WebhookEvent              = dto.WebhookEvent
+	WebhookDeliveriesResponse = dto.WebhookDeliveriesResponse
+
+	BucketInfo          = dto.BucketInfo
+	BucketLifecycle     = dto.BucketLifecycle
+	CreateBucketRequest = dto.CreateBucketRequest
+	LifecycleRule       = dto.LifecycleRule
+	ListBucketsResponse = dto.ListBucketsResponse
+
+	CreateShareRequest    = dto.CreateShareRequest
+	ShareInfo             = dto.ShareInfo
+	ListSharesResponse    = dto.ListSharesResponse
+	ShareAccess           = dto.ShareAccess
+	ShareAccessesResponse = dto.ShareAccessesResponse
+
+	ImportArchiveResponse = dto.ImportArchiveResponse
+	ImportArchiveEntry    = dto.ImportArchiveEntry
+	ExportArchiveRequest  = dto.ExportArchiveRequest
+)
+
+// ImportArchiveOptions    Query parameters of POST /files:import
+type ImportArchiveOptions struct {
+	BucketName string
+	// Format is tar, tar.gz or zip
+	Format string
+	// Mode is expand (default) or single
+	Mode       string
+	Prefix     string
+	ObjectName string
+	// Metadata and Tags are set on every imported object
+	Metadata map[string]string
+	Tags     map[string]string
+	// ExpiresAfter days the imported objects expire, sent as X-Expires-After, 0 keeps them
+	ExpiresAfter int
+}
+
+// SearchFilesOptions    Query parameters of GET /files/search, zero values don't filter
+type SearchFilesOptions struct {
+	BucketName string
+	Prefix     string
+	// Name is a glob matched against the whole object name, e.g. *.csv
+	Name           string
+	MinSize        *int64
+	MaxSize        *int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// ContentType is a media type or a type wildcard like image/*
+	ContentType string
+	Tags        map[string]string
+	Metadata    map[string]string
+	// Sort is name (default), size or lastModified
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+// UploadFileOptions    User metadata, tags and expiration of an uploaded object
+type UploadFileOptions struct {
+	Metadata map[string]string
+	Tags     map[string]string
+	// ExpiresAfter days the object expires, sent as X-Expires-After, 0 keeps it
+	ExpiresAfter int
+}
+
+type Client struct {
+	// BaseURL is the API base url, e.g. http://localhost:8080/api/v1
+	BaseURL string
+	// APIKey is sent as bearer token, leave empty when authentication is disabled
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// Error    Response with a non 2xx status code, Message is the body returned by the server
+type Error struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the wait asked by a 429 Too Many Requests or 503 Service Unavailable response, 0 when not given
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), strings.TrimSpace(e.Message))
+}
+
+func New(baseURL string, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// FileReader    Content of an object, starting at Offset of an object of Size bytes
+type FileReader struct {
+	io.ReadCloser
+	Offset      int64
+	Size        int64
+	ContentType string
+	ETag        string
+	// Checksums verified on upload, hex encoded except the base64 MD5, empty when not provided by the uploader
+	MD5    string
+	SHA256 string
+	CRC32C string
+}
+
+// ListFiles method    GET /files, an empty bucketName lists the configured bucket
+func (c *Client) ListFiles(ctx context.Context, bucketName string, prefix string) ([]FileInfo, error) {
+	var files []FileInfo
+	query := url.Values{}
+	if bucketName != "" {
+		query.Set("bucketName", bucketName)
+	}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	err := c.doJSON(ctx, http.MethodGet, withQuery("/files", query), nil, "", &files)
+	return files, err
+}
+
+// ListFilesByTags method    GET /files?tag=key:value, the objects under prefix having all the tags
+func (c *Client) ListFilesByTags(ctx context.Context, bucketName string, prefix string, tags map[string]string) ([]FileInfo, error) {
+	var files []FileInfo
+	query := bucketQuery(bucketName)
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	addFilters(query, "tag", tags)
+	err := c.doJSON(ctx, http.MethodGet, withQuery("/files", query), nil, "", &files)
+	return files, err
+}
+
+// SearchFiles method    GET /files/search, a page of the objects of the metadata index matching opts
+func (c *Client) SearchFiles(ctx context.Context, opts SearchFilesOptions) (SearchFilesResponse, error) {
+	var found SearchFilesResponse
+	query := bucketQuery(opts.BucketName)
+	for key, value := range map[string]string{"prefix": opts.Prefix, "name": opts.Name, "contentType": opts.ContentType, "sort": opts.Sort} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	for key, size := range map[string]*int64{"minSize": opts.MinSize, "maxSize": opts.MaxSize} {
+		if size != nil {
+			query.Set(key, strconv.FormatInt(*size, 10))
+		}
+	}
+	for key, date := range map[string]time.Time{"modifiedAfter": opts.ModifiedAfter, "modifiedBefore": opts.ModifiedBefore} {
+		if !date.IsZero() {
+			query.Set(key, date.Format(time.RFC3339))
+		}
+	}
+	addFilters(query, "tag", opts.Tags)
+	addFilters(query, "meta", opts.Metadata)
+	if opts.Descending {
+		query.Set("order", "desc")
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	err := c.doJSON(ctx, http.MethodGet, withQuery("/files/search", query), nil, "", &found)
+	return found, err
+}
+
+// GetFile method    GET /files/{name}, the content is read from offset (0 for the whole object) to resume a download.
+// An *Error with status 416 is returned when offset is at the end of the object.
+func (c *Client) GetFile(ctx context.Context, bucketName string, name string, offset int64) (*FileReader, error) {
+	return c.getFile(ctx, name, bucketQuery(bucketName), offset, "")
+}
+
+// ResumeFile method    GET /files/{name} from offset with If-Range: etag, the whole object (Offset 0) is returned when it changed since
+func (c *Client) ResumeFile(ctx context.Context, bucketName string, name string, offset int64, etag string) (*FileReader, error) {
+	return c.getFile(ctx, name, bucketQuery(bucketName), offset, etag)
+}
+
+// GetFileVariant method    GET /files/{name}?variant=, a derived object of the processors like thumb-256
+func (c *Client) GetFileVariant(ctx context.Context, bucketName string, name string, variant string) (*FileReader, error) {
+	query := bucketQuery(bucketName)
+	query.Set("variant", variant)
+	return c.getFile(ctx, name, query, 0, "")
+}
+
+func (c *Client) getFile(ctx context.Context, name string, query url.Values, offset int64, ifRange string) (*FileReader, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, withQuery(FilePath(name), query), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if ifRange != "" {
+			req.Header.Set("If-Range", ifRange)
+		}
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &FileReader{
+		ReadCloser:  resp.Body,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+		MD5:         resp.Header.Get("X-Checksum-Md5"),
+		SHA256:      resp.Header.Get("X-Checksum-Sha256"),
+		CRC32C:      resp.Header.Get("X-Checksum-Crc32c"),
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		var start, end int64
+		_, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &file.Size)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("invalid Content-Range %q: %w", resp.Header.Get("Content-Range"), err)
+		}
+		file.Offset = start
+	}
+	return file, nil
+}
+
+// HeadFile method    HEAD /files/{name}, the object properties are read from the response headers
+func (c *Client) HeadFile(ctx context.Context, bucketName string, name string) (FileMetadata, error) {
+	resp, err := c.do(ctx, http.MethodHead, withQuery(FilePath(name), bucketQuery(bucketName)), nil, "")
+	if err != nil {
+		return FileMetadata{}, err
+	}
+	resp.Body.Close()
+
+	header := resp.Header
+	metadata := FileMetadata{
+		Name:            name,
+		BucketName:      bucketName,
+		Size:            resp.ContentLength,
+		ContentType:     header.Get("Content-Type"),
+		ETag:            strings.Trim(header.Get("ETag"), `"`),
+		VersionID:       header.Get("X-Version-Id"),
+		Encryption:      header.Get("X-Encryption"),
+		EncryptionKeyID: header.Get("X-Encryption-Key-Id"),
+		RetentionMode:   header.Get("X-Retention-Mode"),
+		LegalHold:       header.Get("X-Legal-Hold"),
+		Checksums: FileChecksums{
+			MD5:    header.Get("X-Checksum-Md5"),
+			SHA256: header.Get("X-Checksum-Sha256"),
+			CRC32C: header.Get("X-Checksum-Crc32c"),
+		},
+		UserMetadata: map[string]string{},
+	}
+	metadata.LastModified, _ = http.ParseTime(header.Get("Last-Modified"))
+	if retainUntil, err := time.Parse(time.RFC3339, header.Get("X-Retain-Until")); err == nil {
+		metadata.RetainUntil = &retainUntil
+	}
+	for k := range header {
+		if strings.HasPrefix(k, "X-Meta-") {
+			metadata.UserMetadata[strings.TrimPrefix(k, "X-Meta-")] = header.Get(k)
+		}
+	}
+	return metadata, nil
+}
+
+// GetFileMetadata method    GET /files/{name}/metadata
+func (c *Client) GetFileMetadata(ctx context.Context, bucketName string, name string) (FileMetadata, error) {
+	var metadata FileMetadata
+	err := c.doJSON(ctx, http.MethodGet, withQuery(FilePath(name)+"/metadata", bucketQuery(bucketName)), nil, "", &metadata)
+	return metadata, err
+}
+
+// ImportArchive method    POST /files:import, the archive is expanded into objects or stored as one object
+func (c *Client) ImportArchive(ctx context.Context, opts ImportArchiveOptions, body io.Reader) (ImportArchiveResponse, error) {
+	var report ImportArchiveResponse
+	query := bucketQuery(opts.BucketName)
+	for key, value := range map[string]string{"format": opts.Format, "mode": opts.Mode, "prefix": opts.Prefix, "objectName": opts.ObjectName} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, withQuery("/files:import", query), body, "application/octet-stream")
+	if err != nil {
+		return report, err
+	}
+	setMetadataHeaders(req.Header, opts.Metadata, opts.Tags)
+	setExpiresAfterHeader(req.Header, opts.ExpiresAfter)
+
+	resp, err := c.send(req)
+	if err != nil {
+		return report, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&report)
+	return report, err
+}
+
+// ExportArchive method    GET /files:archive, a zip (or tar.gz with format) of the objects under prefix.
+// The archive is streamed by the server, read it from the returned body and close it.
+func (c *Client) ExportArchive(ctx context.Context, bucketName string, prefix string, format string) (io.ReadCloser, error) {
+	query := bucketQuery(bucketName)
+	query.Set("prefix", prefix)
+	if format != "" {
+		query.Set("format", format)
+	}
+	resp, err := c.do(ctx, http.MethodGet, withQuery("/files:archive", query), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ExportArchiveKeys method    POST /files:archive, an archive of an explicit list of objects
+func (c *Client) ExportArchiveKeys(ctx context.Context, reqBody ExportArchiveRequest) (io.ReadCloser, error) {
+	js, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/files:archive", bytes.NewReader(js), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DeleteFile method    DELETE /files/{name}
+func (c *Client) DeleteFile(ctx context.Context, bucketName string, name string) error {
+	resp, err := c.do(ctx, http.MethodDelete, withQuery(FilePath(name), bucketQuery(bucketName)), nil, "")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// PresignFile method    GET /files/{name}:presign
+func (c *Client) PresignFile(ctx context.Context, bucketName string, name string, expires time.Duration) (PresignFileResponse, error) {
+	var presigned PresignFileResponse
+	query := bucketQuery(bucketName)
+	if expires > 0 {
+		query.Set("expires", strconv.Itoa(int(expires.Seconds())))
+	}
+	err := c.doJSON(ctx, http.MethodGet, withQuery(FilePath(name)+":presign", query), nil, "", &presigned)
+	return presigned, err
+}
+
+// GetFileJobs method    GET /files/{name}/jobs
+func (c *Client) GetFileJobs(ctx context.Context, bucketName string, name string) ([]ProcessingJob, error) {
+	var response FileJobsResponse
+	err := c.doJSON(ctx, http.MethodGet, withQuery(FilePath(name)+"/jobs", bucketQuery(bucketName)), nil, "", &response)
+	return response.Jobs, err
+}
+
+// GetFileTags method    GET /files/{name}:tags
+func (c *Client) GetFileTags(ctx context.Context, bucketName string, name string) (map[string]string, error) {
+	var objectTags ObjectTags
+	err := c.doJSON(ctx, http.MethodGet, withQuery(FilePath(name)+":tags", bucketQuery(bucketName)), nil, "", &objectTags)
+	return objectTags.Tags, err
+}
+
+// PutFileTags method    PUT /files/{name}:tags, the tags replace the current ones
+func (c *Client) PutFileTags(ctx context.Context, bucketName string, name string, tags map[string]string) error {
+	js, err := json.Marshal(ObjectTags{Tags: tags})
+	if err != nil {
+		return err
+	}
+
+	var objectTags ObjectTags
+	return c.doJSON(ctx, http.MethodPut, withQuery(FilePath(name)+":tags", bucketQuery(bucketName)), bytes.NewReader(js), "application/json", &objectTags)
+}
+
+// DeleteFileTags method    DELETE /files/{name}:tags
+func (c *Client) DeleteFileTags(ctx context.Context, bucketName string, name string) error {
+	resp, err := c.do(ctx, http.MethodDelete, withQuery(FilePath(name)+":tags", bucketQuery(bucketName)), nil, "")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// CopyFile method    POST /files/{name}:copy, server-side copy of an object of bucketName
+func (c *Client) CopyFile(ctx context.Context, bucketName string, name string, request CopyFileRequest) (UploadFileResponse, error) {
+	var copied UploadFileResponse
+	err := c.copyFile(ctx, bucketName, FilePath(name)+":copy", request, &copied)
+	return copied, err
+}
+
+// MoveFile method    POST /files/{name}:move, server-side copy then removal of an object of bucketName, SourceRemoved is false when the copy is done but the source is kept
+func (c *Client) MoveFile(ctx context.Context, bucketName string, name string, request CopyFileRequest) (MoveFileResponse, error) {
+	var moved MoveFileResponse
+	err := c.copyFile(ctx, bucketName, FilePath(name)+":move", request, &moved)
+	return moved, err
+}
+
+func (c *Client) copyFile(ctx context.Context, bucketName string, path string, request CopyFileRequest, out interface{}) error {
+	js, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return c.doJSON(ctx, http.MethodPost, withQuery(path, bucketQuery(bucketName)), bytes.NewReader(js), "application/json", out)
+}
+
+// ComposeFile method    POST /files/{name}:compose, concatenate the sources into the object name of bucketName
+func (c *Client) ComposeFile(ctx context.Context, bucketName string, name string, request ComposeFileRequest) (UploadFileResponse, error) {
+	var composed UploadFileResponse
+	js, err := json.Marshal(request)
+	if err != nil {
+		return composed, err
+	}
+
+	err = c.doJSON(ctx, http.MethodPost, withQuery(FilePath(name)+":compose", bucketQuery(bucketName)), bytes.NewReader(js), "application/json", &composed)
+	return composed, err
+}
+
+// CreateShare method    POST /files/{name}/shares, the token and url of the link are only returned here
+func (c *Client) CreateShare(ctx context.Context, bucketName string, name string, request CreateShareRequest) (ShareInfo, error) {
+	var share ShareInfo
+	js, err := json.Marshal(request)
+	if err != nil {
+		return share, err
+	}
+
+	err = c.doJSON(ctx, http.MethodPost, withQuery(FilePath(name)+"/shares", bucketQuery(bucketName)), bytes.NewReader(js), "application/json", &share)
+	return share, err
+}
+
+// ListShares method    GET /files/{name}/shares
+func (c *Client) ListShares(ctx context.Context, bucketName string, name string) ([]ShareInfo, error) {
+	var response ListSharesResponse
+	err := c.doJSON(ctx, http.MethodGet, withQuery(FilePath(name)+"/shares", bucketQuery(bucketName)), nil, "", &response)
+	return response.Shares, err
+}
+
+// RevokeShare method    DELETE /files/{name}/shares/{id}
+func (c *Client) RevokeShare(ctx context.Context, bucketName string, name string, id string) error {
+	resp, err := c.do(ctx, http.MethodDelete, withQuery(FilePath(name)+"/shares/"+url.PathEscape(id), bucketQuery(bucketName)), nil, "")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// GetShareAccesses method    GET /files/{name}/shares/{id}/accesses
+func (c *Client) GetShareAccesses(ctx context.Context, bucketName string, name string, id string) ([]ShareAccess, error) {
+	var response ShareAccessesResponse
+	err := c.doJSON(ctx, http.MethodGet, withQuery(FilePath(name)+"/shares/"+url.PathEscape(id)+"/accesses", bucketQuery(bucketName)), nil, "", &response)
+	return response.Accesses, err
+}
+
+// DownloadShare method    GET /shares/{token}, password is sent as X-Share-Password when set. No API key is needed,
+// the url of the link can be downloaded with any HTTP client too.
+func (c *Client) DownloadShare(ctx context.Context, token string, password string) (*FileReader, error) {
+	resp, err := c.sendShare(ctx, http.MethodGet, token, password)
+	if err != nil {
+		return nil, err
+	}
+	return &FileReader{
+		ReadCloser:  resp.Body,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+	}, nil
+}
+
+// HeadShare method    HEAD /shares/{token}, nil when the link can be downloaded. The download is not counted.
+func (c *Client) HeadShare(ctx context.Context, token string, password string) error {
+	resp, err := c.sendShare(ctx, http.MethodHead, token, password)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) sendShare(ctx context.Context, method string, token string, password string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+"/shares/"+url.PathEscape(token), nil)
+	if err != nil {
+		return nil, err
+	}
+	if password != "" {
+		req.Header.Set(dto.HeaderSharePassword, password)
+	}
+	return c.send(req)
+}
+
+// ListBuckets method    GET /buckets, admin only
+func (c *Client) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	var response ListBucketsResponse
+	err := c.doJSON(ctx, http.MethodGet, "/buckets", nil, "", &response)
+	return response.Buckets, err
+}
+
+// CreateBucket method    POST /buckets, admin only
+func (c *Client) CreateBucket(ctx context.Context, request CreateBucketRequest) (BucketInfo, error) {
+	var bucket BucketInfo
+	js, err := json.Marshal(request)
+	if err != nil {
+		return bucket, err
+	}
+
+	err = c.doJSON(ctx, http.MethodPost, "/buckets", bytes.NewReader(js), "application/json", &bucket)
+	return bucket, err
+}
+
+// GetBucket method    GET /buckets/{name}, admin only
+func (c *Client) GetBucket(ctx context.Context, name string) (BucketInfo, error) {
+	var bucket BucketInfo
+	err := c.doJSON(ctx, http.MethodGet, "/buckets/"+url.PathEscape(name), nil, "", &bucket)
+	return bucket, err
+}
+
+// DeleteBucket method    DELETE /buckets/{name}, admin only
+func (c *Client) DeleteBucket(ctx context.Context, name string) error {
+	resp, err := c.do(ctx, http.MethodDelete, "/buckets/"+url.PathEscape(name), nil, "")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// GetBucketLifecycle method    GET /buckets/{name}/lifecycle, admin only
+func (c *Client) GetBucketLifecycle(ctx context.Context, name string) ([]LifecycleRule, error) {
+	var lifecycle BucketLifecycle
+	err := c.doJSON(ctx, http.MethodGet, "/buckets/"+url.PathEscape(name)+"/lifecycle", nil, "", &lifecycle)
+	return lifecycle.Rules, err
+}
+
+// PutBucketLifecycle method    PUT /buckets/{name}/lifecycle, admin only
+func (c *Client) PutBucketLifecycle(ctx context.Context, name string, rules []LifecycleRule) ([]LifecycleRule, error) {
+	js, err := json.Marshal(BucketLifecycle{Rules: rules})
+	if err != nil {
+		return nil, err
+	}
+
+	var lifecycle BucketLifecycle
+	err = c.doJSON(ctx, http.MethodPut, "/buckets/"+url.PathEscape(name)+"/lifecycle", bytes.NewReader(js), "application/json", &lifecycle)
+	return lifecycle.Rules, err
+}
+
+// DeleteBucketLifecycle method    DELETE /buckets/{name}/lifecycle, admin only
+func (c *Client) DeleteBucketLifecycle(ctx context.Context, name string) error {
+	resp, err := c.do(ctx, http.MethodDelete, "/buckets/"+url.PathEscape(name)+"/lifecycle", nil, "")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// ListWebhookDeliveries method    GET /webhooks/deliveries, status is pending or dead (default)
+func (c *Client) ListWebhookDeliveries(ctx context.Context, status string) ([]WebhookDelivery, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var response WebhookDeliveriesResponse
+	err := c.doJSON(ctx, http.MethodGet, withQuery("/webhooks/deliveries", query), nil, "", &response)
+	return response.Deliveries, err
+}
+
+// ReplayWebhookDelivery method    POST /webhooks/deliveries/{id}:replay
+func (c *Client) ReplayWebhookDelivery(ctx context.Context, id string) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := c.doJSON(ctx, http.MethodPost, "/webhooks/deliveries/"+url.PathEscape(id)+":replay", nil, "", &delivery)
+	return delivery, err
+}
+
+// ReplayWebhookDeliveries method    POST /webhooks/deliveries:replay, replay all the dead deliveries
+func (c *Client) ReplayWebhookDeliveries(ctx context.Context) ([]WebhookDelivery, error) {
+	var response WebhookDeliveriesResponse
+	err := c.doJSON(ctx, http.MethodPost, "/webhooks/deliveries:replay", nil, "", &response)
+	return response.Deliveries, err
+}
+
+// UploadFile method    POST /files as multipart/form-data, the content is streamed from body
+func (c *Client) UploadFile(ctx context.Context, bucketName string, objectName string, fileName string, body io.Reader) (UploadFileResponse, error) {
+	return c.UploadFileWithOptions(ctx, bucketName, objectName, fileName, body, UploadFileOptions{})
+}
+
+// UploadFileWithOptions method    UploadFile with user metadata and tags, sent as x-meta-* and tags form fields, and X-Expires-After
+func (c *Client) UploadFileWithOptions(ctx context.Context, bucketName string, objectName string, fileName string, body io.Reader, opts UploadFileOptions) (UploadFileResponse, error) {
+	var uploaded UploadFileResponse
+
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeUploadForm(form, bucketName, objectName, fileName, body, opts))
+	}()
+
+	defer pr.Close()
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/files", pr, form.FormDataContentType())
+	if err != nil {
+		return uploaded, err
+	}
+	setExpiresAfterHeader(req.Header, opts.ExpiresAfter)
+
+	resp, err := c.send(req)
+	if err != nil {
+		return uploaded, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&uploaded)
+	return uploaded, err
+}
+
+// DownloadFile method    GET /files/{name} with a JSON body (legacy), the object is written into a path on the server
+func (c *Client) DownloadFile(ctx context.Context, name string, reqBody DownloadFileRequest) (string, error) {
+	js, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, FilePath(name), bytes.NewReader(js), "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	msg, err := io.ReadAll(resp.Body)
+	return string(msg), err
+}
+
+// FilePath function    Path of an object, slashes in the name are kept while other characters are escaped
+func FilePath(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/files/" + strings.Join(segments, "/")
+}
+
+func bucketQuery(bucketName string) url.Values {
+	query := url.Values{}
+	if bucketName != "" {
+		query.Set("bucketName", bucketName)
+	}
+	return query
+}
+
+// addFilters function    Add one key:value parameter per filter, sorted by key
+func addFilters(query url.Values, param string, filters map[string]string) {
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		query.Add(param, k+":"+filters[k])
+	}
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// setMetadataHeaders function    X-Meta-* and X-Tags headers of the user metadata and tags
+func setMetadataHeaders(h http.Header, metadata map[string]string, tags map[string]string) {
+	for k, v := range metadata {
+		h.Set(dto.HeaderMetadataPrefix+k, v)
+	}
+	if len(tags) > 0 {
+		h.Set(dto.HeaderTags, encodeTags(tags))
+	}
+}
+
+func setExpiresAfterHeader(h http.Header, days int) {
+	if days > 0 {
+		h.Set(dto.HeaderExpiresAfter, strconv.Itoa(days))
+	}
+}
+
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+func writeUploadForm(form *multipart.Writer, bucketName string, objectName string, fileName string, body io.Reader, opts UploadFileOptions) error {
+	if bucketName != "" {
+		if err := form.WriteField("bucketName", bucketName); err != nil {
+			return err
+		}
+	}
+	if objectName != "" {
+		if err := form.WriteField("objectName", objectName); err != nil {
+			return err
+		}
+	}
+	for k, v := range opts.Metadata {
+		if err := form.WriteField(dto.FormMetadataPrefix+k, v); err != nil {
+			return err
+		}
+	}
+	if len(opts.Tags) > 0 {
+		if err := form.WriteField(dto.FormTags, encodeTags(opts.Tags)); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, body); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+func (c *Client) doJSON(ctx context.Context, method string, path string, body io.Reader, contentType string, out interface{}) error {
+	resp, err := c.do(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do method    Send the request, responses with a non 2xx status are returned as *Error
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(req)
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, path string, body io.Reader, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	return req, nil
+}
+
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: string(msg)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
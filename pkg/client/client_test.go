@@ -0,0 +1,1779 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pavva91/file-upload/config"
+	"github.com/pavva91/file-upload/internal/admission"
+	"github.com/pavva91/file-upload/internal/archive"
+	"github.com/pavva91/file-upload/internal/handlers"
+	"github.com/pavva91/file-upload/internal/index"
+	"github.com/pavva91/file-upload/internal/metrics"
+	"github.com/pavva91/file-upload/internal/middleware"
+	"github.com/pavva91/file-upload/internal/openapi"
+	"github.com/pavva91/file-upload/internal/services"
+	"github.com/pavva91/file-upload/internal/storage"
+	"github.com/pavva91/file-upload/internal/testutil/fakeclamd"
+	"github.com/pavva91/file-upload/internal/testutil/fakes3"
+	"github.com/pavva91/file-upload/internal/webhooks"
+)
+
+const (
+	testBucket = "testbucket"
+	testAPIKey = "test-api-key"
+)
+
+// newTestServer function    Serve the real handlers under /api/v1 backed by an in-memory S3
+func newTestServer(t *testing.T) *httptest.Server {
+	s3 := fakes3.New()
+	t.Cleanup(s3.Close)
+
+	minioClient, err := s3.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MinioClient = minioClient
+	err = minioClient.MakeBucket(context.Background(), testBucket, minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.ServerConfigValues.Minio.Bucket = testBucket
+	config.ServerConfigValues.Auth.ApiKeys = []config.ApiKey{{Key: testAPIKey, Principal: "tester"}}
+	t.Cleanup(func() {
+		config.ServerConfigValues.Auth.ApiKeys = nil
+	})
+
+	rateLimiter := middleware.NewRateLimiter()
+	filesHandler := middleware.Auth(rateLimiter.Handler(http.StripPrefix("/api/v1", &handlers.FilesHandler{})))
+	mux := http.NewServeMux()
+	for _, filesPath := range handlers.FilesPaths {
+		mux.Handle("/api/v1"+filesPath, filesHandler)
+	}
+	webhooksHandler := middleware.Auth(rateLimiter.Handler(http.StripPrefix("/api/v1", &handlers.WebhooksHandler{})))
+	for _, webhooksPath := range handlers.WebhooksPaths {
+		mux.Handle("/api/v1"+webhooksPath, webhooksHandler)
+	}
+	bucketsHandler := middleware.Auth(rateLimiter.Handler(http.StripPrefix("/api/v1", &handlers.BucketsHandler{})))
+	for _, bucketsPath := range handlers.BucketsPaths {
+		mux.Handle("/api/v1"+bucketsPath, bucketsHandler)
+	}
+	sharesHandler := rateLimiter.Handler(http.StripPrefix("/api/v1", &handlers.SharesHandler{}))
+	for _, sharesPath := range handlers.SharesPaths {
+		mux.Handle("/api/v1"+sharesPath, sharesHandler)
+	}
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestClient(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	uploaded, err := c.UploadFile(ctx, testBucket, "dir/hello.txt", "source.txt", strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.ObjectName != "dir/hello.txt" || uploaded.Size != 11 {
+		t.Errorf("got %+v, want object dir/hello.txt of size 11", uploaded)
+	}
+
+	files, err := c.ListFiles(ctx, "", "dir/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "dir/hello.txt" {
+		t.Errorf("got %+v, want dir/hello.txt", files)
+	}
+
+	downloadPath := filepath.Join(t.TempDir(), "download.txt")
+	_, err = c.DownloadFile(ctx, "dir/hello.txt", DownloadFileRequest{BucketName: testBucket, DownloadPath: downloadPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := os.ReadFile(downloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(downloaded) != "hello world" {
+		t.Errorf("got %s, want hello world", downloaded)
+	}
+
+	_, err = c.UploadFile(ctx, testBucket, "", "", strings.NewReader("hello world"))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || !strings.Contains(apiErr.Message, "Insert valid object name") {
+		t.Errorf("got %v, want 400 Insert valid object name", err)
+	}
+
+	_, err = New(ts.URL+"/api/v1", "wrong-key").ListFiles(ctx, "", "")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got %v, want 401", err)
+	}
+}
+
+func TestClientStreaming(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	content := strings.Repeat("0123456789", 100)
+	uploaded, err := c.UploadFile(ctx, testBucket, "stream/data.bin", "data.bin", strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Size != int64(len(content)) {
+		t.Errorf("got size %d, want %d", uploaded.Size, len(content))
+	}
+
+	file, err := c.GetFile(ctx, testBucket, "stream/data.bin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole, _ := io.ReadAll(file)
+	file.Close()
+	if string(whole) != content {
+		t.Errorf("got %d bytes, want %d", len(whole), len(content))
+	}
+
+	file, err = c.GetFile(ctx, testBucket, "stream/data.bin", 990)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := io.ReadAll(file)
+	file.Close()
+	if file.Offset != 990 || file.Size != 1000 || string(rest) != "0123456789" {
+		t.Errorf("got offset %d size %d content %q, want offset 990 size 1000 content 0123456789", file.Offset, file.Size, rest)
+	}
+
+	_, err = c.GetFile(ctx, testBucket, "stream/data.bin", 1000)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("got %v, want 416", err)
+	}
+
+	presigned, err := c.PresignFile(ctx, testBucket, "stream/data.bin", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(presigned.URL, "/"+testBucket+"/stream/data.bin") {
+		t.Errorf("got %s, want presigned url of %s/stream/data.bin", presigned.URL, testBucket)
+	}
+
+	err = c.DeleteFile(ctx, testBucket, "stream/data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetFile(ctx, testBucket, "stream/data.bin", 0)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want 404", err)
+	}
+}
+
+func TestClientImportArchive(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	for _, file := range [][2]string{{"dist/", ""}, {"dist/index.html", "<html></html>"}, {"dist/js/app.js", "app()"}} {
+		name, content := file[0], file[1]
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(name, "/") {
+			header.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+
+	report, err := c.ImportArchive(ctx, ImportArchiveOptions{
+		BucketName: testBucket,
+		Format:     "tar",
+		Prefix:     "site/",
+		Metadata:   map[string]string{"Release": "1.0"},
+		Tags:       map[string]string{"site": "docs"},
+	}, bytes.NewReader(tarball.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Uploaded != 2 || report.Skipped != 1 {
+		t.Errorf("got %+v, want 2 uploaded and 1 skipped", report)
+	}
+
+	files, err := c.ListFiles(ctx, testBucket, "site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"site/dist/index.html", "site/dist/js/app.js"}) {
+		t.Errorf("got %v, want site/dist/index.html and site/dist/js/app.js", names)
+	}
+
+	file, err := c.GetFile(ctx, testBucket, "site/dist/index.html", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if file.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("got content type %s, want text/html; charset=utf-8", file.ContentType)
+	}
+
+	head, err := c.HeadFile(ctx, testBucket, "site/dist/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := c.GetFileMetadata(ctx, testBucket, "site/dist/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []FileMetadata{head, metadata} {
+		if m.Size != 13 || m.ContentType != "text/html; charset=utf-8" || m.Encryption != "aws:kms" || m.UserMetadata["Mtime"] == "" || m.UserMetadata["Release"] != "1.0" || m.ETag != files[0].ETag {
+			t.Errorf("got %+v, want the metadata of site/dist/index.html", m)
+		}
+	}
+	if tags, _ := c.GetFileTags(ctx, testBucket, "site/dist/js/app.js"); tags["site"] != "docs" {
+		t.Errorf("got tags %v, want the tags of the import", tags)
+	}
+	_, err = c.HeadFile(ctx, testBucket, "site/missing")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want 404", err)
+	}
+
+	exported, err := c.ExportArchive(ctx, testBucket, "site/", "zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readArchive(t, exported, "zip")
+	if entries["dist/index.html"] != "<html></html>" || entries["dist/js/app.js"] != "app()" || len(entries) != 2 {
+		t.Errorf("got %v, want dist/index.html and dist/js/app.js", entries)
+	}
+
+	exported, err = c.ExportArchiveKeys(ctx, ExportArchiveRequest{BucketName: testBucket, Keys: []string{"site/dist/js/app.js"}, Format: "tar.gz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = readArchive(t, exported, "tar.gz")
+	if entries["site/dist/js/app.js"] != "app()" || len(entries) != 1 {
+		t.Errorf("got %v, want site/dist/js/app.js", entries)
+	}
+
+	_, err = c.ExportArchiveKeys(ctx, ExportArchiveRequest{BucketName: testBucket, Keys: []string{"site/missing"}})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for a missing key", err)
+	}
+
+	_, err = c.ImportArchive(ctx, ImportArchiveOptions{BucketName: testBucket, Format: "tar"}, bytes.NewReader(tarball.Bytes()[:600]))
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for a truncated archive", err)
+	}
+}
+
+func TestClientChecksums(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		header http.Header
+		status int
+	}{
+		"sha256 and crc32c": {
+			header: http.Header{"X-Checksum-Sha256": {"b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"}, "X-Checksum-Crc32c": {"yZRlqg=="}},
+			status: http.StatusOK,
+		},
+		"md5 mismatch": {
+			header: http.Header{"Content-Md5": {"AAAAAAAAAAAAAAAAAAAAAA=="}},
+			status: http.StatusBadRequest,
+		},
+		"malformed sha256": {
+			header: http.Header{"X-Checksum-Sha256": {"abc"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			objectName := "checksums/" + strings.ReplaceAll(name, " ", "-")
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			err := writeUploadForm(form, testBucket, objectName, "hello.txt", strings.NewReader("hello world"), UploadFileOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := c.newRequest(ctx, http.MethodPost, "/files", &body, form.FormDataContentType())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+			resp, err := c.HTTPClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != test.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.status)
+			}
+
+			file, err := c.GetFile(ctx, testBucket, objectName, 0)
+			if test.status != http.StatusOK {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+					t.Errorf("got %v, want the rejected upload to be missing", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+			if file.SHA256 != test.header.Get("X-Checksum-Sha256") || file.CRC32C != "c99465aa" {
+				t.Errorf("got checksums %s %s, want the verified ones", file.SHA256, file.CRC32C)
+			}
+		})
+	}
+}
+
+func TestClientMetadataAndTags(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	upload := func(objectName string, opts UploadFileOptions) {
+		_, err := c.UploadFileWithOptions(ctx, testBucket, objectName, "data.csv", strings.NewReader("a,b\n1,2\n"), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	upload("catalog/raw.csv", UploadFileOptions{Metadata: map[string]string{"project-id": "42"}, Tags: map[string]string{"stage": "raw", "team": "data"}})
+	upload("catalog/clean.csv", UploadFileOptions{Tags: map[string]string{"stage": "clean", "team": "data"}})
+
+	// Deduplicated uploads keep their tags on the reference record
+	config.ServerConfigValues.Minio.EnableDedup = true
+	upload("catalog/copy.csv", UploadFileOptions{Tags: map[string]string{"stage": "raw"}})
+	config.ServerConfigValues.Minio.EnableDedup = false
+
+	metadata, err := c.GetFileMetadata(ctx, testBucket, "catalog/raw.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.UserMetadata["Project-Id"] != "42" {
+		t.Errorf("got user metadata %v, want Project-Id 42", metadata.UserMetadata)
+	}
+
+	tags, err := c.GetFileTags(ctx, testBucket, "catalog/raw.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"stage": "raw", "team": "data"}) {
+		t.Errorf("got tags %v, want stage raw and team data", tags)
+	}
+
+	listTagged := func(tags map[string]string) []string {
+		files, err := c.ListFilesByTags(ctx, testBucket, "catalog/", tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, file := range files {
+			if file.Tags["stage"] != tags["stage"] || file.Size != 8 {
+				t.Errorf("got %+v, want the tags and size of %s", file, file.Name)
+			}
+			names = append(names, file.Name)
+		}
+		return names
+	}
+	if names := listTagged(map[string]string{"stage": "raw"}); !reflect.DeepEqual(names, []string{"catalog/copy.csv", "catalog/raw.csv"}) {
+		t.Errorf("got %v, want catalog/copy.csv and catalog/raw.csv", names)
+	}
+	if names := listTagged(map[string]string{"stage": "raw", "team": "data"}); !reflect.DeepEqual(names, []string{"catalog/raw.csv"}) {
+		t.Errorf("got %v, want catalog/raw.csv", names)
+	}
+
+	err = c.PutFileTags(ctx, testBucket, "catalog/clean.csv", map[string]string{"stage": "raw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.DeleteFileTags(ctx, testBucket, "catalog/copy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := listTagged(map[string]string{"stage": "raw"}); !reflect.DeepEqual(names, []string{"catalog/clean.csv", "catalog/raw.csv"}) {
+		t.Errorf("got %v after updating the tags, want catalog/clean.csv and catalog/raw.csv", names)
+	}
+
+	_, err = c.UploadFileWithOptions(ctx, testBucket, "catalog/reserved.csv", "reserved.csv", strings.NewReader(""), UploadFileOptions{
+		Metadata: map[string]string{"checksum-md5": "forged"},
+	})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for a reserved metadata key", err)
+	}
+
+	tooMany := map[string]string{}
+	for _, k := range strings.Split("a b c d e f g h i j k", " ") {
+		tooMany[k] = "v"
+	}
+	_, err = c.UploadFileWithOptions(ctx, testBucket, "catalog/many.csv", "data.csv", strings.NewReader(""), UploadFileOptions{Tags: tooMany})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for 11 tags", err)
+	}
+
+	_, err = c.ListFilesByTags(ctx, testBucket, "", map[string]string{"": "raw"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for a tag filter without key", err)
+	}
+
+	err = c.PutFileTags(ctx, testBucket, "catalog/missing.csv", map[string]string{"stage": "raw"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want 404 for a missing object", err)
+	}
+}
+
+func TestClientSearch(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	_, err := c.SearchFiles(ctx, SearchFilesOptions{BucketName: testBucket})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want 503 with the index disabled", err)
+	}
+
+	metadataIndex, err := index.Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MetadataIndex = metadataIndex
+	t.Cleanup(func() {
+		storage.MetadataIndex = nil
+		metadataIndex.Close()
+	})
+
+	for name, opts := range map[string]UploadFileOptions{
+		"reports/2024.csv":  {Tags: map[string]string{"stage": "raw"}},
+		"reports/2025.csv":  {Tags: map[string]string{"stage": "clean"}, Metadata: map[string]string{"owner": "finance"}},
+		"reports/large.bin": {},
+		"tmp/scratch.csv":   {},
+	} {
+		content := "a,b\n"
+		if strings.HasSuffix(name, ".bin") {
+			content = strings.Repeat("x", 4096)
+		}
+		_, err := c.UploadFileWithOptions(ctx, testBucket, name, "data", strings.NewReader(content), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = c.DeleteFile(ctx, testBucket, "tmp/scratch.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.PutFileTags(ctx, testBucket, "reports/2025.csv", map[string]string{"stage": "raw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Written behind the service, indexed by the full scan
+	_, err = storage.MinioClient.PutObject(ctx, testBucket, "reports/direct.csv", strings.NewReader("a,b\n"), 4, minio.PutObjectOptions{ContentType: "text/csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(opts SearchFilesOptions) []string {
+		opts.BucketName = testBucket
+		found, err := c.SearchFiles(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, file := range found.Files {
+			names = append(names, file.Name)
+		}
+		return names
+	}
+	minSize := int64(1024)
+
+	tests := map[string]struct {
+		opts  SearchFilesOptions
+		names []string
+	}{
+		"all":                 {opts: SearchFilesOptions{}, names: []string{"reports/2024.csv", "reports/2025.csv", "reports/large.bin"}},
+		"glob":                {opts: SearchFilesOptions{Name: "*.csv"}, names: []string{"reports/2024.csv", "reports/2025.csv"}},
+		"size":                {opts: SearchFilesOptions{MinSize: &minSize}, names: []string{"reports/large.bin"}},
+		"updated tags":        {opts: SearchFilesOptions{Tags: map[string]string{"stage": "raw"}}, names: []string{"reports/2024.csv", "reports/2025.csv"}},
+		"metadata":            {opts: SearchFilesOptions{Metadata: map[string]string{"owner": "finance"}}, names: []string{"reports/2025.csv"}},
+		"sort by size":        {opts: SearchFilesOptions{Sort: "size", Descending: true, Limit: 1}, names: []string{"reports/large.bin"}},
+		"modified in future":  {opts: SearchFilesOptions{ModifiedAfter: time.Now().Add(time.Hour)}, names: []string{}},
+		"content type filter": {opts: SearchFilesOptions{ContentType: "text/csv"}, names: []string{"reports/2024.csv", "reports/2025.csv"}},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			if names := search(test.opts); !reflect.DeepEqual(names, test.names) {
+				t.Errorf("got %v, want %v", names, test.names)
+			}
+		})
+	}
+
+	err = services.ReconcileIndex(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := search(SearchFilesOptions{ContentType: "text/csv"}); !reflect.DeepEqual(names, []string{"reports/2024.csv", "reports/2025.csv", "reports/direct.csv"}) {
+		t.Errorf("got %v after the full scan, want reports/direct.csv too", names)
+	}
+	if names := search(SearchFilesOptions{Tags: map[string]string{"stage": "raw"}, Metadata: map[string]string{"owner": "finance"}}); len(names) != 1 {
+		t.Errorf("got %v after the full scan, want the tags and metadata kept", names)
+	}
+
+	_, err = c.SearchFiles(ctx, SearchFilesOptions{BucketName: testBucket, Sort: "color"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for an invalid sort", err)
+	}
+}
+
+func TestClientWebhooks(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	admin := New(ts.URL+"/api/v1", "admin-key")
+	config.ServerConfigValues.Auth.ApiKeys = append(config.ServerConfigValues.Auth.ApiKeys, config.ApiKey{Key: "admin-key", Principal: "ops", Admin: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := admin.ListWebhookDeliveries(ctx, "")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want 503 without webhooks", err)
+	}
+
+	events := make(chan WebhookEvent, 10)
+	var failing atomic.Bool
+	failing.Store(true)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event WebhookEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if r.URL.Path == "/archive" && failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		events <- event
+	}))
+	defer receiver.Close()
+
+	config.ServerConfigValues.Webhooks.QueueDir = t.TempDir()
+	config.ServerConfigValues.Webhooks.MaxAttempts = 1
+	config.ServerConfigValues.Webhooks.Endpoints = []config.Webhook{
+		{Name: "uploads", URL: receiver.URL + "/uploads", Events: []string{webhooks.EventFileUploaded}, Prefix: "in/"},
+		{Name: "archive", URL: receiver.URL + "/archive", Events: []string{webhooks.EventFileDeleted}},
+	}
+	services.Webhooks, err = services.NewWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		services.Webhooks = nil
+		config.ServerConfigValues.Webhooks.Endpoints = nil
+	})
+	go services.Webhooks.Run(ctx)
+
+	_, err = c.UploadFile(ctx, "", "out/skipped.txt", "skipped.txt", strings.NewReader("skipped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.UploadFile(ctx, "", "in/hello.txt", "hello.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != webhooks.EventFileUploaded || event.Name != "in/hello.txt" || event.Bucket != testBucket || event.Size != 5 || event.Principal != "tester" {
+			t.Errorf("got %+v, want the upload of in/hello.txt by tester", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("got no event, want the upload of in/hello.txt")
+	}
+
+	err = c.DeleteFile(ctx, "", "in/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.ListWebhookDeliveries(ctx, "")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("got %v, want 403 for a non admin key", err)
+	}
+
+	var dead []WebhookDelivery
+	for start := time.Now(); len(dead) == 0 && time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		dead, err = admin.ListWebhookDeliveries(ctx, "dead")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(dead) != 1 || dead[0].Webhook != "archive" || dead[0].Event.Type != webhooks.EventFileDeleted {
+		t.Fatalf("got %+v, want the delete dead-lettered", dead)
+	}
+
+	_, err = admin.ReplayWebhookDelivery(ctx, "0123")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want 404 for an unknown delivery", err)
+	}
+
+	failing.Store(false)
+	replayed, err := admin.ReplayWebhookDeliveries(ctx)
+	if err != nil || len(replayed) != 1 || replayed[0].ID != dead[0].ID {
+		t.Errorf("got %+v (%v), want the dead delivery replayed", replayed, err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != webhooks.EventFileDeleted || event.Name != "in/hello.txt" || event.ID != dead[0].Event.ID {
+			t.Errorf("got %+v, want the replayed delete of in/hello.txt", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("got no event, want the replayed delete")
+	}
+}
+
+func TestClientProcessing(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := c.GetFileJobs(ctx, "", "hello.txt")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want 503 with processing disabled", err)
+	}
+
+	config.ServerConfigValues.Processing.Path = filepath.Join(t.TempDir(), "jobs.db")
+	config.ServerConfigValues.Processing.Processors = []config.Processor{{Name: "digest"}, {Name: "thumbnail", Options: map[string]string{"sizes": "32"}}}
+	services.Processing, err = services.NewProcessing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		services.Processing.Jobs.Close()
+		services.Processing = nil
+		config.ServerConfigValues.Processing.Processors = nil
+	})
+	go services.Processing.Run(ctx)
+
+	_, err = c.UploadFile(ctx, "", "hello.txt", "hello.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []ProcessingJob
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		jobs, err = c.GetFileJobs(ctx, "", "hello.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) == 1 && jobs[0].Status == "succeeded" {
+			break
+		}
+	}
+	sha256 := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if len(jobs) != 1 || jobs[0].Processor != "digest" || jobs[0].Status != "succeeded" || jobs[0].Result == nil || jobs[0].Result.Metadata["sha256"] != sha256 {
+		t.Errorf("got %+v, want the digest job succeeded with the sha256 of hello", jobs)
+	}
+
+	jobs, err = c.GetFileJobs(ctx, "", "missing.txt")
+	if err != nil || len(jobs) != 0 {
+		t.Errorf("got %+v (%v), want no jobs", jobs, err)
+	}
+
+	// Imported with the image/png content type detected from its content
+	img := image.NewRGBA(image.Rect(0, 0, 64, 16))
+	var tarball, pngContent bytes.Buffer
+	err = png.Encode(&pngContent, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(&tarball)
+	tw.WriteHeader(&tar.Header{Name: "photo.png", Mode: 0o644, Size: int64(pngContent.Len()), Typeflag: tar.TypeReg})
+	tw.Write(pngContent.Bytes())
+	tw.Close()
+	_, err = c.ImportArchive(ctx, ImportArchiveOptions{Format: "tar"}, &tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		jobs, err = c.GetFileJobs(ctx, "", "photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) == 2 && jobs[0].Status == "succeeded" && jobs[1].Status == "succeeded" {
+			break
+		}
+	}
+	thumb, err := c.GetFileVariant(ctx, "", "photo.png", "thumb-32")
+	if err != nil {
+		t.Fatalf("got %v (jobs %+v), want the thumb-32 variant", err, jobs)
+	}
+	cfg, err := png.DecodeConfig(thumb)
+	thumb.Close()
+	if err != nil || thumb.ContentType != "image/png" || cfg.Width != 32 || cfg.Height != 8 {
+		t.Errorf("got %s of %dx%d (%v), want image/png of 32x8", thumb.ContentType, cfg.Width, cfg.Height, err)
+	}
+
+	_, err = c.GetFileVariant(ctx, "", "photo.png", "Thumb_32")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for an invalid variant", err)
+	}
+
+	// Thumbnails go with their original
+	err = c.DeleteFile(ctx, "", "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetFileVariant(ctx, "", "photo.png", "thumb-32")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want 404 once the original is deleted", err)
+	}
+}
+
+func TestClientContentTypes(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	var pngContent bytes.Buffer
+	err := png.Encode(&pngContent, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ServerConfigValues.ContentTypes.Deny = []string{"application/x-executable"}
+	config.ServerConfigValues.ContentTypes.Buckets = []config.ContentTypePolicy{{Bucket: testBucket, Allow: []string{"image/*", "text/*"}}}
+	t.Cleanup(func() {
+		config.ServerConfigValues.ContentTypes.Deny = nil
+		config.ServerConfigValues.ContentTypes.Buckets = nil
+	})
+
+	tests := map[string]struct {
+		name    string
+		content string
+		want    string
+		status  int
+	}{
+		"detected over the extension": {name: "renamed.txt", content: pngContent.String(), want: "image/png"},
+		"refined by the extension":    {name: "data.csv", content: "a,b\n1,2\n", want: "text/csv; charset=utf-8"},
+		"html":                        {name: "page", content: "<html><body>hi</body></html>", want: "text/html; charset=utf-8"},
+		"not allowed in the bucket":   {name: "doc.pdf", content: "%PDF-1.7\n", status: http.StatusUnsupportedMediaType},
+		"denied everywhere":           {name: "tool.png", content: "\x7fELF\x02\x01\x01\x00", status: http.StatusUnsupportedMediaType},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			uploaded, err := c.UploadFile(ctx, testBucket, test.name, test.name, strings.NewReader(test.content))
+			if test.status != 0 {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+					t.Errorf("got %+v (%v), want %d", uploaded, err, test.status)
+				}
+				return
+			}
+			if err != nil || uploaded.ContentType != test.want {
+				t.Fatalf("got %+v (%v), want %s", uploaded, err, test.want)
+			}
+			metadata, err := c.GetFileMetadata(ctx, testBucket, test.name)
+			if err != nil || metadata.ContentType != test.want {
+				t.Errorf("got %s (%v) stored, want %s", metadata.ContentType, err, test.want)
+			}
+		})
+	}
+
+	// Refused before anything is uploaded
+	_, err = c.ImportArchive(ctx, ImportArchiveOptions{Mode: "single", ObjectName: "single.pdf"}, strings.NewReader("%PDF-1.7\n"))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("got %v, want 415", err)
+	}
+	if _, err := c.HeadFile(ctx, testBucket, "single.pdf"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want single.pdf not uploaded", err)
+	}
+}
+
+func TestClientScan(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	clamd, err := fakeclamd.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clamd.Close()
+	err = storage.MinioClient.MakeBucket(ctx, "quarantine", minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ServerConfigValues.Scan.Address = clamd.Addr
+	config.ServerConfigValues.Scan.QuarantineBucket = "quarantine"
+	services.Scanner, err = services.NewScanner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		services.Scanner = nil
+		config.ServerConfigValues.Scan.Address = ""
+		config.ServerConfigValues.Scan.QuarantineBucket = ""
+		config.ServerConfigValues.Scan.FailOpen = false
+	})
+
+	var apiErr *Error
+	_, err = c.UploadFile(ctx, "", "clean.txt", "clean.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := c.GetFileMetadata(ctx, "", "clean.txt")
+	if err != nil || metadata.UserMetadata["Scan-Status"] != "clean" || metadata.UserMetadata["Scan-Time"] == "" {
+		t.Errorf("got %+v (%v), want the clean verdict", metadata.UserMetadata, err)
+	}
+
+	// Refused and quarantined, whatever the way in
+	_, err = c.UploadFile(ctx, "", "in/eicar.txt", "eicar.txt", strings.NewReader(fakeclamd.EICAR))
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(apiErr.Message, fakeclamd.Signature) {
+		t.Errorf("got %v, want 422 with the signature", err)
+	}
+	_, err = c.ImportArchive(ctx, ImportArchiveOptions{Mode: "single", ObjectName: "in/single.txt"}, strings.NewReader(fakeclamd.EICAR))
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("got %v, want 422 for the single import", err)
+	}
+	for _, name := range []string{"in/eicar.txt", "in/single.txt"} {
+		if _, err := c.HeadFile(ctx, testBucket, name); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			t.Errorf("got %v, want %s not stored", err, name)
+		}
+		info, err := storage.MinioClient.StatObject(ctx, "quarantine", testBucket+"/"+name, minio.StatObjectOptions{})
+		if err != nil || info.UserMetadata["Scan-Status"] != "infected" || info.UserMetadata["Scan-Signature"] != fakeclamd.Signature || info.UserMetadata["Quarantine-Name"] != name {
+			t.Errorf("got %+v (%v), want %s quarantined", info.UserMetadata, err, name)
+		}
+	}
+	if clamd.Scans() != 3 {
+		t.Errorf("got %d scans, want 3", clamd.Scans())
+	}
+
+	_, err = c.UploadFileWithOptions(ctx, "", "forged.txt", "forged.txt", strings.NewReader("hello"), UploadFileOptions{Metadata: map[string]string{"scan-status": "clean"}})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for a forged verdict", err)
+	}
+
+	// Scanner down
+	clamd.Close()
+	_, err = c.UploadFile(ctx, "", "closed.txt", "closed.txt", strings.NewReader("hello"))
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want 503 failing closed", err)
+	}
+	config.ServerConfigValues.Scan.FailOpen = true
+	_, err = c.UploadFile(ctx, "", "open.txt", "open.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err = c.GetFileMetadata(ctx, "", "open.txt")
+	if err != nil || metadata.UserMetadata["Scan-Status"] != "unscanned" {
+		t.Errorf("got %+v (%v), want the unscanned verdict failing open", metadata.UserMetadata, err)
+	}
+}
+
+func TestClientLimits(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	err := storage.MinioClient.MakeBucket(ctx, "large", minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ServerConfigValues.Limits.MaxObjectSize = 1
+	config.ServerConfigValues.Limits.MaxFormParts = 4
+	config.ServerConfigValues.Limits.Buckets = []config.BucketLimits{{Bucket: "large", MaxObjectSize: 2}}
+	t.Cleanup(func() {
+		config.ServerConfigValues.Limits.MaxObjectSize = 0
+		config.ServerConfigValues.Limits.MaxFormParts = 0
+		config.ServerConfigValues.Limits.Buckets = nil
+	})
+	const MiB = 1024 * 1024
+
+	tests := map[string]struct {
+		bucket string
+		name   string
+		size   int
+		opts   UploadFileOptions
+		status int
+	}{
+		"at the limit":           {bucket: testBucket, name: "limit.bin", size: MiB},
+		"above the limit":        {bucket: testBucket, name: "above.bin", size: MiB + 1, status: http.StatusRequestEntityTooLarge},
+		"limit of the bucket":    {bucket: "large", name: "large.bin", size: MiB + MiB/2},
+		"above every limit":      {bucket: "large", name: "huge.bin", size: 4 * MiB, status: http.StatusRequestEntityTooLarge},
+		"too many parts":         {bucket: testBucket, name: "parts.txt", size: 1, opts: UploadFileOptions{Metadata: map[string]string{"a": "1", "b": "2"}}, status: http.StatusRequestEntityTooLarge},
+		"parent segment":         {bucket: testBucket, name: "../etc/passwd", size: 1, status: http.StatusBadRequest},
+		"current segment":        {bucket: testBucket, name: "a/./b", size: 1, status: http.StatusBadRequest},
+		"absolute":               {bucket: testBucket, name: "/etc/passwd", size: 1, status: http.StatusBadRequest},
+		"control character":      {bucket: testBucket, name: "a\nb", size: 1, status: http.StatusBadRequest},
+		"backslash":              {bucket: testBucket, name: "..\\a", size: 1, status: http.StatusBadRequest},
+		"name too long":          {bucket: testBucket, name: strings.Repeat("a", 1025), size: 1, status: http.StatusBadRequest},
+		"dots in names are fine": {bucket: testBucket, name: "v1..2/.hidden/a.txt", size: 1},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			_, err := c.UploadFileWithOptions(ctx, test.bucket, test.name, "data", strings.NewReader(strings.Repeat("x", test.size)), test.opts)
+			if test.status == 0 {
+				if err != nil {
+					t.Errorf("got %v, want uploaded", err)
+				}
+				return
+			}
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+				t.Errorf("got %v, want %d", err, test.status)
+			}
+		})
+	}
+
+	// The Content-Length is checked before reading
+	_, err = c.ImportArchive(ctx, ImportArchiveOptions{Mode: "single", ObjectName: "single.bin"}, strings.NewReader(strings.Repeat("x", MiB+1)))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %v, want 413 for the single import", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, FilePath("limit.bin")+":tags?bucketName="+testBucket, strings.NewReader(`{"tags":{"a":"`+strings.Repeat("x", 17*MiB)+`"}}`), "application/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.send(req)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %v, want 413 for a JSON body above the limit", err)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	config.ServerConfigValues.RateLimit.Requests = 1
+	config.ServerConfigValues.RateLimit.Burst = 2
+	config.ServerConfigValues.RateLimit.Principals = []config.PrincipalRateLimit{{Principal: "bulk", Requests: -1, Bandwidth: 1024 * 1024}}
+	t.Cleanup(func() {
+		config.ServerConfigValues.RateLimit.Requests = 0
+		config.ServerConfigValues.RateLimit.Burst = 0
+		config.ServerConfigValues.RateLimit.Principals = nil
+	})
+	ts := newTestServer(t)
+	config.ServerConfigValues.Auth.ApiKeys = append(config.ServerConfigValues.Auth.ApiKeys, config.ApiKey{Key: "bulk-api-key", Principal: "bulk"})
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	bulk := New(ts.URL+"/api/v1", "bulk-api-key")
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := c.ListFiles(ctx, testBucket, "")
+		if err != nil {
+			t.Fatalf("request %d of the burst: %v", i+1, err)
+		}
+	}
+	_, err := c.ListFiles(ctx, testBucket, "")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != time.Second {
+		t.Errorf("got %v, want 429 with Retry-After: 1", err)
+	}
+
+	// The other principals have their own limits
+	for i := 0; i < 5; i++ {
+		_, err := bulk.ListFiles(ctx, testBucket, "")
+		if err != nil {
+			t.Fatalf("request %d of the principal without request limit: %v", i+1, err)
+		}
+	}
+
+	// One second of burst, then 1 MiB per second
+	start := time.Now()
+	_, err = bulk.UploadFile(ctx, testBucket, "bulk.bin", "bulk.bin", bytes.NewReader(make([]byte, 3*1024*1024/2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("uploaded in %v, want about 500ms", elapsed)
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{
+		`file_upload_rate_limit_requests{principal="default"} 1`,
+		`file_upload_rate_limit_requests{principal="bulk"} 0`,
+		`file_upload_rate_limit_bandwidth_bytes{principal="bulk"} 1.048576e+06`,
+		`file_upload_rate_limited_requests_total{principal="tester"}`,
+		`file_upload_throttled_seconds_total{principal="bulk",direction="upload"}`,
+	} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("missing metric %s", line)
+		}
+	}
+}
+
+func TestClientUploadQueue(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	services.Admission = &admission.Controller{MaxConcurrent: 1, QueueSize: 1}
+	config.ServerConfigValues.Uploads.QueueTimeout = 1
+	t.Cleanup(func() {
+		services.Admission = nil
+		config.ServerConfigValues.Uploads.QueueTimeout = 0
+	})
+
+	// An upload in flight
+	err := services.Admission.Acquire(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next one waits in the queue until it times out
+	start := time.Now()
+	_, err = c.UploadFile(ctx, testBucket, "queued.txt", "queued.txt", strings.NewReader("queued"))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.RetryAfter != 5*time.Second {
+		t.Errorf("got %v, want 503 with Retry-After: 5", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("refused after %v, want after the queue timeout", elapsed)
+	}
+
+	// With the queue full the upload is refused right away
+	queued := make(chan error)
+	go func() {
+		_, err := c.UploadFile(ctx, testBucket, "queued.txt", "queued.txt", strings.NewReader("queued"))
+		queued <- err
+	}()
+	for _, _, waiting := services.Admission.Stats(); waiting == 0; _, _, waiting = services.Admission.Stats() {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = c.UploadFile(ctx, testBucket, "refused.txt", "refused.txt", strings.NewReader("refused"))
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || !strings.Contains(apiErr.Message, "upload queue full") {
+		t.Errorf("got %v, want 503 upload queue full", err)
+	}
+
+	// The queued upload goes once the one in flight is done
+	services.Admission.Release(0)
+	err = <-queued
+	if err != nil {
+		t.Errorf("got %v, want the queued upload stored", err)
+	}
+	inFlight, _, _ := services.Admission.Stats()
+	if inFlight != 0 {
+		t.Errorf("got %d uploads in flight, want none", inFlight)
+	}
+}
+
+func TestClientCopyMove(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	for _, bucket := range []string{"archive", "locked"} {
+		err := storage.MinioClient.MakeBucket(ctx, bucket, minio.MakeBucketOptions{ObjectLocking: bucket == "locked"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := c.UploadFileWithOptions(ctx, testBucket, "report.txt", "report.txt", strings.NewReader("report"), UploadFileOptions{
+		Metadata: map[string]string{"Owner": "alice"},
+		Tags:     map[string]string{"team": "data"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = storage.MinioClient.PutObject(ctx, testBucket, "held.txt", strings.NewReader("held"), 4, minio.PutObjectOptions{ContentType: "text/plain; charset=utf-8", LegalHold: minio.LegalHoldEnabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		move     bool
+		name     string
+		request  CopyFileRequest
+		metadata map[string]string
+		tags     map[string]string
+		// legalHold of the copy
+		legalHold string
+		status    int
+	}{
+		"copy keeps metadata and tags": {
+			name:     "report.txt",
+			request:  CopyFileRequest{DestinationName: "copies/report.txt"},
+			metadata: map[string]string{"Owner": "alice"},
+			tags:     map[string]string{"team": "data"},
+		},
+		"copy replaces metadata and tags": {
+			name:     "report.txt",
+			request:  CopyFileRequest{DestinationName: "copies/replaced.txt", MetadataDirective: "REPLACE", Metadata: map[string]string{"owner": "bob"}, TaggingDirective: "REPLACE", Tags: map[string]string{"team": "ops"}},
+			metadata: map[string]string{"Owner": "bob"},
+			tags:     map[string]string{"team": "ops"},
+		},
+		"copy to another bucket": {
+			name:     "report.txt",
+			request:  CopyFileRequest{DestinationBucket: "archive", DestinationName: "report.txt"},
+			metadata: map[string]string{"Owner": "alice"},
+			tags:     map[string]string{"team": "data"},
+		},
+		"metadata without replace":   {name: "report.txt", request: CopyFileRequest{DestinationName: "x.txt", Metadata: map[string]string{"owner": "bob"}}, status: http.StatusBadRequest},
+		"unknown directive":          {name: "report.txt", request: CopyFileRequest{DestinationName: "x.txt", TaggingDirective: "MERGE"}, status: http.StatusBadRequest},
+		"invalid destination":        {name: "report.txt", request: CopyFileRequest{DestinationName: "../x.txt"}, status: http.StatusBadRequest},
+		"missing destination bucket": {name: "report.txt", request: CopyFileRequest{DestinationBucket: "missing", DestinationName: "x.txt"}, status: http.StatusBadRequest},
+		"missing source":             {name: "missing.txt", request: CopyFileRequest{DestinationName: "x.txt"}, status: http.StatusNotFound},
+		"move onto itself":           {move: true, name: "report.txt", request: CopyFileRequest{DestinationName: "report.txt"}, status: http.StatusBadRequest},
+		"move under legal hold":      {move: true, name: "held.txt", request: CopyFileRequest{DestinationName: "moved.txt"}, status: http.StatusConflict},
+		"legal hold without lock":    {name: "held.txt", request: CopyFileRequest{DestinationBucket: "archive", DestinationName: "held.txt"}, status: http.StatusConflict},
+		"legal hold with lock":       {name: "held.txt", request: CopyFileRequest{DestinationBucket: "locked", DestinationName: "held.txt"}, legalHold: "ON"},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			copyFile := c.CopyFile
+			if test.move {
+				copyFile = func(ctx context.Context, bucketName string, name string, request CopyFileRequest) (UploadFileResponse, error) {
+					moved, err := c.MoveFile(ctx, bucketName, name, request)
+					return moved.UploadFileResponse, err
+				}
+			}
+			copied, err := copyFile(ctx, testBucket, test.name, test.request)
+			if test.status != 0 {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+					t.Errorf("got %v, want %d", err, test.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bucket := test.request.DestinationBucket
+			if bucket == "" {
+				bucket = testBucket
+			}
+			metadata, err := c.GetFileMetadata(ctx, bucket, copied.ObjectName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata.ContentType != "text/plain; charset=utf-8" {
+				t.Errorf("got content type %q, want the one of the source", metadata.ContentType)
+			}
+			if metadata.LegalHold != test.legalHold {
+				t.Errorf("got legal hold %q, want %q", metadata.LegalHold, test.legalHold)
+			}
+			for k, v := range test.metadata {
+				if metadata.UserMetadata[k] != v {
+					t.Errorf("got metadata %v, want %s=%s", metadata.UserMetadata, k, v)
+				}
+			}
+			tags, err := c.GetFileTags(ctx, bucket, copied.ObjectName)
+			if err != nil || test.tags != nil && !reflect.DeepEqual(tags, test.tags) {
+				t.Errorf("got tags %v (%v), want %v", tags, err, test.tags)
+			}
+		})
+	}
+
+	moved, err := c.MoveFile(ctx, testBucket, "report.txt", CopyFileRequest{DestinationBucket: "archive", DestinationName: "moved.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ObjectName != "moved.txt" || !moved.SourceRemoved {
+		t.Errorf("got %+v, want moved.txt", moved)
+	}
+	_, err = c.GetFileMetadata(ctx, testBucket, "report.txt")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want the source removed", err)
+	}
+}
+
+func TestClientCompose(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	ctx := context.Background()
+
+	err := storage.MinioClient.MakeBucket(ctx, "shards", minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const MiB = 1024 * 1024
+	shards := map[string]string{
+		"part-0": strings.Repeat("a", 5*MiB),
+		"part-1": strings.Repeat("b", 6*MiB),
+		"part-2": "tail",
+	}
+	for name, content := range shards {
+		_, err := storage.MinioClient.PutObject(ctx, "shards", name, strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{ContentType: "text/csv"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		sources []ComposeSource
+		content string
+		status  int
+	}{
+		"whole sources": {
+			sources: []ComposeSource{{BucketName: "shards", Name: "part-0"}, {BucketName: "shards", Name: "part-2"}},
+			content: shards["part-0"] + "tail",
+		},
+		"byte ranges": {
+			sources: []ComposeSource{{BucketName: "shards", Name: "part-1", Offset: MiB}, {BucketName: "shards", Name: "part-0", Offset: 10, Length: 3}},
+			content: shards["part-1"][MiB:] + "aaa",
+		},
+		"small last source": {
+			sources: []ComposeSource{{BucketName: "shards", Name: "part-2"}},
+			content: "tail",
+		},
+		"small source first": {
+			sources: []ComposeSource{{BucketName: "shards", Name: "part-2"}, {BucketName: "shards", Name: "part-0"}},
+			status:  http.StatusBadRequest,
+		},
+		"range below the minimum": {
+			sources: []ComposeSource{{BucketName: "shards", Name: "part-1", Offset: 2 * MiB}, {BucketName: "shards", Name: "part-2"}},
+			status:  http.StatusBadRequest,
+		},
+		"range out of the source": {
+			sources: []ComposeSource{{BucketName: "shards", Name: "part-2", Offset: 2, Length: 3}},
+			status:  http.StatusBadRequest,
+		},
+		"negative offset": {
+			sources: []ComposeSource{{BucketName: "shards", Name: "part-2", Offset: -1}},
+			status:  http.StatusBadRequest,
+		},
+		"no sources":     {status: http.StatusBadRequest},
+		"missing source": {sources: []ComposeSource{{Name: "missing"}}, status: http.StatusNotFound},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			objectName := "composed/" + strings.ReplaceAll(name, " ", "-")
+			composed, err := c.ComposeFile(ctx, testBucket, objectName, ComposeFileRequest{Sources: test.sources, Tags: map[string]string{"stage": "merged"}})
+			if test.status != 0 {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+					t.Errorf("got %v, want %d", err, test.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if composed.Size != int64(len(test.content)) || composed.ContentType != "text/csv" {
+				t.Errorf("got %+v, want %d bytes of text/csv", composed, len(test.content))
+			}
+
+			file, err := c.GetFile(ctx, testBucket, objectName, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			content, err := io.ReadAll(file)
+			if err != nil || string(content) != test.content {
+				t.Errorf("got %d bytes (%v), want the %d bytes of the sources", len(content), err, len(test.content))
+			}
+			tags, err := c.GetFileTags(ctx, testBucket, objectName)
+			if err != nil || tags["stage"] != "merged" {
+				t.Errorf("got tags %v (%v), want stage=merged", tags, err)
+			}
+		})
+	}
+}
+
+func TestClientBuckets(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	admin := New(ts.URL+"/api/v1", "admin-key")
+	config.ServerConfigValues.Auth.ApiKeys = append(config.ServerConfigValues.Auth.ApiKeys, config.ApiKey{Key: "admin-key", Principal: "ops", Admin: true})
+	ctx := context.Background()
+
+	_, err := c.ListBuckets(ctx)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("got %v, want 403 for a non admin key", err)
+	}
+
+	tests := map[string]struct {
+		request CreateBucketRequest
+		want    BucketInfo
+		status  int
+	}{
+		"defaults": {
+			request: CreateBucketRequest{Name: "plain"},
+			want:    BucketInfo{Name: "plain", Region: fakes3.Region, Encryption: "NONE"},
+		},
+		"every option": {
+			request: CreateBucketRequest{
+				Name:            "archive",
+				ObjectLocking:   true,
+				Versioning:      true,
+				Encryption:      "SSE-KMS",
+				EncryptionKeyID: "archive-key",
+				Tags:            map[string]string{"team": "data"},
+				Lifecycle:       []LifecycleRule{{ID: "tmp", Prefix: "tmp/", ExpirationDays: 7}},
+			},
+			want: BucketInfo{
+				Name:            "archive",
+				Region:          fakes3.Region,
+				ObjectLocking:   true,
+				Versioning:      true,
+				Encryption:      "SSE-KMS",
+				EncryptionKeyID: "archive-key",
+				Tags:            map[string]string{"team": "data"},
+				Lifecycle:       []LifecycleRule{{ID: "tmp", Prefix: "tmp/", ExpirationDays: 7}},
+			},
+		},
+		"versioning": {
+			request: CreateBucketRequest{Name: "versioned", Versioning: true, Encryption: "SSE-S3"},
+			want:    BucketInfo{Name: "versioned", Region: fakes3.Region, Versioning: true, Encryption: "SSE-S3"},
+		},
+		"existing bucket":         {request: CreateBucketRequest{Name: testBucket}, status: http.StatusConflict},
+		"invalid name":            {request: CreateBucketRequest{Name: "Not_A_Bucket"}, status: http.StatusBadRequest},
+		"unknown encryption":      {request: CreateBucketRequest{Name: "other", Encryption: "ROT13"}, status: http.StatusBadRequest},
+		"key without kms":         {request: CreateBucketRequest{Name: "other", EncryptionKeyID: "key"}, status: http.StatusBadRequest},
+		"rule without expiration": {request: CreateBucketRequest{Name: "other", Lifecycle: []LifecycleRule{{ID: "tmp"}}}, status: http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			bucket, err := admin.CreateBucket(ctx, test.request)
+			if test.status != 0 {
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+					t.Errorf("got %v, want %d", err, test.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(bucket, test.want) {
+				t.Errorf("got %+v, want %+v", bucket, test.want)
+			}
+			bucket, err = admin.GetBucket(ctx, test.request.Name)
+			if err != nil || !reflect.DeepEqual(bucket, test.want) {
+				t.Errorf("got %+v (%v), want %+v", bucket, err, test.want)
+			}
+		})
+	}
+
+	buckets, err := admin.ListBuckets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		names = append(names, bucket.Name)
+	}
+	if want := []string{"archive", "plain", testBucket, "versioned"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	_, err = c.UploadFile(ctx, "plain", "a.txt", "a.txt", strings.NewReader("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, status := range map[string]int{"plain": http.StatusConflict, testBucket: http.StatusConflict, "missing": http.StatusNotFound} {
+		err = admin.DeleteBucket(ctx, name)
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+			t.Errorf("got %v, want %d removing %s", err, status, name)
+		}
+	}
+	err = admin.DeleteBucket(ctx, "versioned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = admin.GetBucket(ctx, "versioned")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want the bucket removed", err)
+	}
+}
+
+func TestClientLifecycle(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	admin := New(ts.URL+"/api/v1", "admin-key")
+	config.ServerConfigValues.Auth.ApiKeys = append(config.ServerConfigValues.Auth.ApiKeys, config.ApiKey{Key: "admin-key", Principal: "ops", Admin: true})
+	config.ServerConfigValues.Lifecycle.ExpiresAfter = []int{30, 7}
+	t.Cleanup(func() {
+		config.ServerConfigValues.Lifecycle.ExpiresAfter = nil
+	})
+	ctx := context.Background()
+
+	expiresAfter := []LifecycleRule{
+		{ID: "expires-after-7", Tags: map[string]string{"expires-after": "7"}, ExpirationDays: 7},
+		{ID: "expires-after-30", Tags: map[string]string{"expires-after": "30"}, ExpirationDays: 30},
+	}
+
+	_, err := admin.CreateBucket(ctx, CreateBucketRequest{Name: "logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := admin.GetBucketLifecycle(ctx, "logs")
+	if err != nil || !reflect.DeepEqual(rules, expiresAfter) {
+		t.Errorf("got %+v (%v), want the expires-after rules on a new bucket", rules, err)
+	}
+
+	tests := map[string]struct {
+		rules  []LifecycleRule
+		status int
+	}{
+		"expiration and transition": {
+			rules: []LifecycleRule{
+				{ID: "tmp", Prefix: "tmp/", ExpirationDays: 1, AbortIncompleteUploadDays: 1},
+				{ID: "cold", Prefix: "archive/", Tags: map[string]string{"tier": "cold"}, TransitionDays: 30, TransitionStorageClass: "GLACIER"},
+				{ID: "versions", NoncurrentExpirationDays: 90},
+			},
+		},
+		"no rules":               {},
+		"no action":              {rules: []LifecycleRule{{ID: "tmp", Prefix: "tmp/"}}, status: http.StatusBadRequest},
+		"duplicated id":          {rules: []LifecycleRule{{ID: "tmp", ExpirationDays: 1}, {ID: "tmp", ExpirationDays: 2}}, status: http.StatusBadRequest},
+		"negative days":          {rules: []LifecycleRule{{ID: "tmp", ExpirationDays: -1}}, status: http.StatusBadRequest},
+		"transition no class":    {rules: []LifecycleRule{{ID: "cold", TransitionDays: 30}}, status: http.StatusBadRequest},
+		"abort with tags":        {rules: []LifecycleRule{{ID: "tmp", Tags: map[string]string{"a": "b"}, AbortIncompleteUploadDays: 1}}, status: http.StatusBadRequest},
+		"reserved expires-after": {rules: []LifecycleRule{{ID: "expires-after-7", ExpirationDays: 1}}, status: http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			rules, err := admin.PutBucketLifecycle(ctx, "logs", test.rules)
+			if test.status != 0 {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+					t.Errorf("got %v, want %d", err, test.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := append(append([]LifecycleRule{}, test.rules...), expiresAfter...)
+			if !reflect.DeepEqual(rules, want) {
+				t.Errorf("got %+v, want %+v", rules, want)
+			}
+		})
+	}
+
+	err = admin.DeleteBucketLifecycle(ctx, "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err = admin.GetBucketLifecycle(ctx, "logs")
+	if err != nil || !reflect.DeepEqual(rules, expiresAfter) {
+		t.Errorf("got %+v (%v), want only the expires-after rules after delete", rules, err)
+	}
+
+	var apiErr *Error
+	_, err = admin.GetBucketLifecycle(ctx, "missing")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want 404 for a missing bucket", err)
+	}
+	_, err = c.GetBucketLifecycle(ctx, "logs")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("got %v, want 403 for a non admin key", err)
+	}
+
+	// The test bucket predates the config, the first X-Expires-After upload adds the rules
+	opts := UploadFileOptions{Tags: map[string]string{"project": "apollo"}, ExpiresAfter: 7}
+	_, err = c.UploadFileWithOptions(ctx, testBucket, "reports/daily.csv", "daily.csv", strings.NewReader("a,b\n"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := c.GetFileTags(ctx, testBucket, "reports/daily.csv")
+	if err != nil || !reflect.DeepEqual(tags, map[string]string{"project": "apollo", "expires-after": "7"}) {
+		t.Errorf("got tags %v (%v), want project and expires-after", tags, err)
+	}
+	rules, err = admin.GetBucketLifecycle(ctx, testBucket)
+	if err != nil || !reflect.DeepEqual(rules, expiresAfter) {
+		t.Errorf("got %+v (%v), want the expires-after rules on the test bucket", rules, err)
+	}
+
+	_, err = c.UploadFileWithOptions(ctx, testBucket, "reports/weekly.csv", "weekly.csv", strings.NewReader("a,b\n"), UploadFileOptions{ExpiresAfter: 3})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v, want 400 for days out of the config", err)
+	}
+}
+
+func TestClientShares(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL+"/api/v1", testAPIKey)
+	config.ServerConfigValues.Shares.BaseURL = ts.URL + "/api/v1"
+	t.Cleanup(func() {
+		config.ServerConfigValues.Shares.BaseURL = ""
+	})
+	ctx := context.Background()
+
+	_, err := c.UploadFile(ctx, testBucket, "reports/q1.csv", "q1.csv", strings.NewReader("a,b\n1,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.CreateShare(ctx, testBucket, "reports/q1.csv", CreateShareRequest{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want 503 with the index disabled", err)
+	}
+
+	metadataIndex, err := index.Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MetadataIndex = metadataIndex
+	t.Cleanup(func() {
+		storage.MetadataIndex = nil
+		metadataIndex.Close()
+	})
+
+	share, err := c.CreateShare(ctx, testBucket, "reports/q1.csv", CreateShareRequest{Password: "s3cret", MaxDownloads: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.Token == "" || share.URL != ts.URL+"/api/v1/shares/"+share.Token || !share.Protected || share.CreatedBy != "tester" {
+		t.Errorf("got %+v, want a protected link with its token and url", share)
+	}
+	if expires := share.ExpiresAt.Sub(share.CreatedAt); expires != 24*time.Hour {
+		t.Errorf("got expiry %s, want the 24h default", expires)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		head     bool
+		status   int
+	}{
+		{name: "no password", status: http.StatusUnauthorized},
+		{name: "wrong password", password: "guess", status: http.StatusUnauthorized},
+		{name: "head", password: "s3cret", head: true},
+		{name: "head again", password: "s3cret", head: true},
+		{name: "first download", password: "s3cret"},
+		{name: "second download", password: "s3cret"},
+		{name: "max downloads", password: "s3cret", status: http.StatusGone},
+		{name: "head of max downloads", password: "s3cret", head: true, status: http.StatusGone},
+	}
+	for _, test := range tests {
+		if test.head {
+			err := c.HeadShare(ctx, share.Token, test.password)
+			if test.status == 0 && err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			if test.status != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != test.status) {
+				t.Errorf("%s: got %v, want %d", test.name, err, test.status)
+			}
+			continue
+		}
+		file, err := c.DownloadShare(ctx, share.Token, test.password)
+		if test.status != 0 {
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+				t.Errorf("%s: got %v, want %d", test.name, err, test.status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		content, _ := io.ReadAll(file)
+		file.Close()
+		if string(content) != "a,b\n1,2\n" {
+			t.Errorf("%s: got %q", test.name, content)
+		}
+	}
+
+	// The downloads already counted can be resumed past the max downloads
+	req, _ := http.NewRequest(http.MethodGet, share.URL, nil)
+	req.Header.Set("X-Share-Password", "s3cret")
+	req.Header.Set("Range", "bytes=4-")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(content) != "1,2\n" {
+		t.Errorf("got %d %q, want the resumed range", resp.StatusCode, content)
+	}
+
+	// Browsers send the password with HTTP Basic authentication
+	open, err := c.CreateShare(ctx, testBucket, "reports/q1.csv", CreateShareRequest{Password: "s3cret", ExpiresIn: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, open.URL, nil)
+	req.SetBasicAuth("", "s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Disposition") != `attachment; filename=q1.csv` {
+		t.Errorf("got %d %q, want the attachment q1.csv", resp.StatusCode, resp.Header.Get("Content-Disposition"))
+	}
+
+	err = c.RevokeShare(ctx, testBucket, "reports/q1.csv", open.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.DownloadShare(ctx, open.Token, "s3cret")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusGone {
+		t.Errorf("got %v, want 410 for a revoked link", err)
+	}
+
+	shares, err := c.ListShares(ctx, testBucket, "reports/q1.csv")
+	if err != nil || len(shares) != 2 {
+		t.Fatalf("got %+v (%v), want 2 links", shares, err)
+	}
+	if shares[0].ID != share.ID || shares[0].Downloads != 2 || shares[0].Token != "" || shares[1].RevokedAt == nil {
+		t.Errorf("got %+v, want the downloads and the revocation without the tokens", shares)
+	}
+
+	accesses, err := c.GetShareAccesses(ctx, testBucket, "reports/q1.csv", share.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []string{}
+	for _, access := range accesses {
+		results = append(results, access.Result)
+	}
+	if want := []string{"wrong-password", "wrong-password", "checked", "checked", "downloaded", "downloaded", "exhausted", "exhausted", "resumed"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("got accesses %v, want %v", results, want)
+	}
+
+	errorTests := map[string]struct {
+		call   func() error
+		status int
+	}{
+		"unknown token": {
+			call:   func() error { _, err := c.DownloadShare(ctx, "unknown", ""); return err },
+			status: http.StatusNotFound,
+		},
+		"missing object": {
+			call: func() error {
+				_, err := c.CreateShare(ctx, testBucket, "reports/missing.csv", CreateShareRequest{})
+				return err
+			},
+			status: http.StatusNotFound,
+		},
+		"expiry above the max": {
+			call: func() error {
+				_, err := c.CreateShare(ctx, testBucket, "reports/q1.csv", CreateShareRequest{ExpiresIn: 8 * 24 * 60 * 60})
+				return err
+			},
+			status: http.StatusBadRequest,
+		},
+		"negative downloads": {
+			call: func() error {
+				_, err := c.CreateShare(ctx, testBucket, "reports/q1.csv", CreateShareRequest{MaxDownloads: -1})
+				return err
+			},
+			status: http.StatusBadRequest,
+		},
+		"share of another object": {
+			call:   func() error { return c.RevokeShare(ctx, testBucket, "reports/q2.csv", share.ID) },
+			status: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range errorTests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			err := test.call()
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+				t.Errorf("got %v, want %d", err, test.status)
+			}
+		})
+	}
+}
+
+// readArchive function    Contents of the archive entries by name
+func readArchive(t *testing.T, body io.ReadCloser, format string) map[string]string {
+	defer body.Close()
+
+	entries := map[string]string{}
+	_, err := archive.Walk(body, format, archive.Limits{}, func(entry archive.Entry, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		entries[entry.Name] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// TestClientCoversSpec function    Every operation of the OpenAPI document has a client method
+func TestClientCoversSpec(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	err := json.Unmarshal(openapi.Spec, &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientType := reflect.TypeOf(&Client{})
+	for path, operations := range spec.Paths {
+		for method, raw := range operations {
+			var operation struct {
+				OperationID string `json:"operationId"`
+			}
+			if method == "parameters" || json.Unmarshal(raw, &operation) != nil {
+				continue
+			}
+			name := strings.ToUpper(operation.OperationID[:1]) + operation.OperationID[1:]
+			if _, ok := clientType.MethodByName(name); !ok {
+				t.Errorf("%s %s: client method %s is missing", strings.ToUpper(method), path, name)
+			}
+		}
+	}
+}
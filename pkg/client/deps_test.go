@@ -0,0 +1,38 @@
+package client
+
+import (
+	"go/build"
+	"strings"
+	"testing"
+)
+
+// TestClientDependencies function    The client is imported by other programs, it must not pull the server storage (SQLite) in
+func TestClientDependencies(t *testing.T) {
+	const module = "github.com/pavva91/file-upload/"
+	forbidden := []string{"modernc.org/sqlite", module + "internal/index", module + "internal/processing", module + "internal/webhooks"}
+
+	seen := map[string]bool{}
+	var walk func(path string, from string)
+	walk = func(path string, from string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		for _, f := range forbidden {
+			if path == f {
+				t.Errorf("%s imports %s", from, path)
+			}
+		}
+		if !strings.HasPrefix(path, module) {
+			return
+		}
+		pkg, err := build.Import(path, "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imported := range pkg.Imports {
+			walk(imported, path)
+		}
+	}
+	walk(module+"pkg/client", "")
+}